@@ -2,16 +2,22 @@ package apic
 
 import (
 	"aci-chatbot/mocks"
+	"aci-chatbot/proxy"
+	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 // Type to define the APIC client configuration option
@@ -41,6 +47,73 @@ type EndpointInformation struct {
 	Epg      string
 }
 
+// Struct to store Interface information. See GetInterfaceInformation()
+type InterfaceInformation struct {
+	Node          string
+	Id            string
+	AdminSt       string
+	OperSt        string
+	Speed         string
+	LastLinkStChg string
+	CrcErrors     string
+	InputErrors   string
+	OutputErrors  string
+	InputUtil     string
+	OutputUtil    string
+}
+
+// Struct to store Node information. See GetNodeInformation()
+type NodeInformation struct {
+	Id          string
+	Name        string
+	Role        string
+	Pod         string
+	Model       string
+	Serial      string
+	Address     string
+	FabricSt    string
+	Firmware    string
+	Uptime      string
+	Health      string
+	Supervisors []map[string]string
+	Fans        []map[string]string
+	Psus        []map[string]string
+	Faults      []FaultInst
+}
+
+// Struct to store the health history of a single object. See GetHealthHistory()
+type HealthSeries struct {
+	Name    string
+	Samples []int // Average health per interval, oldest first
+	Min     int
+	Max     int
+	Avg     float64
+}
+
+// Struct to store the Fabric health history. See GetHealthHistory()
+// Pods, Tenants and Nodes are sorted by average health, the worst first
+type HealthHistory struct {
+	Granularity string
+	Fabric      HealthSeries
+	Pods        []HealthSeries
+	Tenants     []HealthSeries
+	Nodes       []HealthSeries
+}
+
+// Generic MO returned by a query. See Query()
+// Children are only set if they are requested with the rsp-subtree option
+type ApicMo struct {
+	Class      string
+	Attributes ApicMoAttributes
+	Children   []ApicMo
+}
+
+// Struct to store the result of a query. TotalCount is the number of MOs matching the query, across all the pages
+type QueryResult struct {
+	TotalCount int
+	Mos        []ApicMo
+}
+
 // Interface used to mock the HTTP Client
 type HttpClient interface {
 	Do(req *http.Request) (*http.Response, error)
@@ -48,17 +121,27 @@ type HttpClient interface {
 
 // Apic interface. Implemented by ApicClient and ApicClientMocks
 type ApicInterface interface {
-	Login() error
+	Login(ctx context.Context) error
 	GetIp() string
 	GetToken() string
-	GetProcEntity() ([]ApicMoAttributes, error)
-	SubscribeClassWebSocket(c string) (string, error)
-	RefreshSubscriptionWebSocket(id string) error
-	GetFabricInformation() (FabricInformation, error)
-	GetEndpointInformation(m string) ([]EndpointInformation, error)
-	GetFabricNeighbors(nd string) (map[string][]string, error)
-	GetLatestFaults(c string) ([]ApicMoAttributes, error)
-	GetLatestEvents(c string, usr ...string) ([]ApicMoAttributes, error)
+	GetDialer() *websocket.Dialer
+	GetProcEntity(ctx context.Context) ([]ProcEntity, error)
+	SubscribeClassWebSocket(ctx context.Context, c string) (string, error)
+	SubscribeMoWebSocket(ctx context.Context, dn string) (string, error)
+	RefreshSubscriptionWebSocket(ctx context.Context, id string) error
+	GetFabricInformation(ctx context.Context) (FabricInformation, error)
+	GetEndpointInformation(ctx context.Context, m string) ([]EndpointInformation, error)
+	GetFabricNeighbors(ctx context.Context, nd string) (map[string][]string, error)
+	GetNodeInformation(ctx context.Context, nd string) (NodeInformation, error)
+	GetHealthHistory(ctx context.Context, g string) (HealthHistory, error)
+	GetInterfaceInformation(ctx context.Context, nd string, iface string) ([]InterfaceInformation, error)
+	GetTopErrorInterfaces(ctx context.Context, c string) ([]InterfaceInformation, error)
+	GetLatestFaults(ctx context.Context, c string) ([]FaultInst, error)
+	PostMo(ctx context.Context, dn string, payload []byte) error
+	Query(ctx context.Context, q *Query) (QueryResult, error)
+	GetEndpointHistory(ctx context.Context, m string) ([]EventRecord, error)
+	GetLatestEvents(ctx context.Context, c string, usr ...string) ([]AaaModLR, error)
+	CheckAvailable() error
 }
 
 // Apic Client struct
@@ -68,6 +151,13 @@ type ApicClient struct {
 	pwd        string
 	tkn        string
 	baseURL    string
+	pageSize   int // MOs per page of the class queries. See reqApicMos()
+	pageFetch  int // Pages fetched in parallel
+	retries    int // Retries of the GET requests. See SetRetries()
+	backoff    time.Duration
+	breaker    *circuitBreaker
+	tlsConfig  *tls.Config // See SetTLSConfig()
+	proxy      proxy.Func  // See SetProxy()
 }
 
 // Package level variable to define which objects is used as http client (Mock or the standard)
@@ -94,6 +184,19 @@ func SetTimeout(t time.Duration) Option {
 	}
 }
 
+// The proxy of the requests to the APIC. The REST API and the WebSocket are sent through the proxy. See proxy.New()
+// Without a proxy the APIC is reached directly: the HTTP_PROXY, HTTPS_PROXY and NO_PROXY variables are ignored
+func SetProxy(p proxy.Func) Option {
+	return func(client *ApicClient) {
+		client.proxy = p
+		if hc, ok := client.httpClient.(*http.Client); ok {
+			if t, ok := hc.Transport.(*http.Transport); ok {
+				t.Proxy = p
+			}
+		}
+	}
+}
+
 // Create new APIC client
 func NewApicClient(url, usr, pwd string, options ...Option) (*ApicClient, error) {
 	client := ApicClient{
@@ -101,19 +204,39 @@ func NewApicClient(url, usr, pwd string, options ...Option) (*ApicClient, error)
 		pwd:        pwd,
 		httpClient: Client,
 		baseURL:    url,
+		pageSize:   defaultPageSize,
+		pageFetch:  defaultPageFetch,
+		retries:    defaultRetries,
+		backoff:    defaultBackoff,
+		breaker:    &circuitBreaker{threshold: defaultBreakerFailures, cooldown: defaultBreakerCooldown},
+	}
+	// Each client has its own transport, so that the TLS and proxy settings of an APIC do not apply to the other clients
+	if hc, ok := Client.(*http.Client); ok {
+		c := *hc
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.Proxy = nil
+		c.Transport = t
+		client.httpClient = &c
 	}
-	http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
 
 	for _, opt := range options {
 		opt(&client)
 	}
 
-	if err := client.Login(); err != nil {
+	if err := client.Login(context.Background()); err != nil {
 		return nil, err
 	}
 	return &client, nil
 }
 
+// Get a WebSocket dialer using the TLS and proxy settings of the client
+func (client *ApicClient) GetDialer() *websocket.Dialer {
+	d := *websocket.DefaultDialer
+	d.TLSClientConfig = client.tlsConfig
+	d.Proxy = client.proxy
+	return &d
+}
+
 // Get the client URL
 func (client *ApicClient) GetIp() string {
 	return client.baseURL
@@ -125,11 +248,11 @@ func (client *ApicClient) GetToken() string {
 }
 
 // Login to the APIC
-func (client *ApicClient) Login() error {
+func (client *ApicClient) Login(ctx context.Context) error {
 
 	var result map[string]interface{}
 	loginPayload := fmt.Sprintf(`{"aaaUser":{"attributes":{"name":"%s","pwd":"%s"}}}`, client.usr, client.pwd)
-	req, err := client.makeCall(http.MethodPost, "/api/aaaLogin.json", strings.NewReader(loginPayload))
+	req, err := client.makeCall(ctx, http.MethodPost, "/api/aaaLogin.json", strings.NewReader(loginPayload))
 	if err != nil {
 		return err
 	}
@@ -139,16 +262,22 @@ func (client *ApicClient) Login() error {
 		return err
 	}
 
-	r := getApicManagedObjects(result, "aaaLogin")
-	client.tkn = r[0]["token"]
+	var login []AaaLogin
+	if err = unmarshalResult(result, &login); err != nil {
+		return err
+	}
+	if len(login) == 0 || login[0].Token == "" {
+		return errors.New("unexpected APIC response. The login did not return a token")
+	}
+	client.tkn = login[0].Token
 
 	return nil
 }
 
 // Refresh subscription
-func (client *ApicClient) RefreshSubscriptionWebSocket(id string) error {
+func (client *ApicClient) RefreshSubscriptionWebSocket(ctx context.Context, id string) error {
 	var result map[string]interface{}
-	req, err := client.makeCall(http.MethodGet, fmt.Sprintf("/api/subscriptionRefresh.json?id=%s", id), nil)
+	req, err := client.makeCall(ctx, http.MethodGet, (&Query{path: "/api/subscriptionRefresh.json"}).Param("id", id).String(), nil)
 
 	if err != nil {
 		return err
@@ -161,9 +290,9 @@ func (client *ApicClient) RefreshSubscriptionWebSocket(id string) error {
 }
 
 // Subscribe to class events
-func (client *ApicClient) SubscribeClassWebSocket(c string) (string, error) {
+func (client *ApicClient) SubscribeClassWebSocket(ctx context.Context, c string) (string, error) {
 	var result map[string]interface{}
-	req, err := client.makeCall(http.MethodGet, fmt.Sprintf("/api/class/%s.json?subscription=yes&refresh-timeout=120?query-target=subtree", c), nil)
+	req, err := client.makeCall(ctx, http.MethodGet, (&Query{path: "/api/class/" + escapePath(c) + ".json"}).Subscribe(120).String(), nil)
 
 	if err != nil {
 		return "", err
@@ -172,21 +301,48 @@ func (client *ApicClient) SubscribeClassWebSocket(c string) (string, error) {
 		log.Println("Error: ", err)
 		return "", err
 	}
-	return result["subscriptionId"].(string), nil
+	id, ok := result["subscriptionId"].(string)
+	if !ok {
+		return "", errors.New("unexpected APIC response. subscriptionId not found")
+	}
+	return id, nil
+}
+
+// Subscribe to events of a MO and its subtree
+func (client *ApicClient) SubscribeMoWebSocket(ctx context.Context, dn string) (string, error) {
+	var result map[string]interface{}
+	req, err := client.makeCall(ctx, http.MethodGet, (&Query{path: "/api/mo/" + escapePath(dn) + ".json"}).Subscribe(120).Target("subtree").String(), nil)
+
+	if err != nil {
+		return "", err
+	}
+	if err = client.doCall(req, &result); err != nil {
+		log.Println("Error: ", err)
+		return "", err
+	}
+	id, ok := result["subscriptionId"].(string)
+	if !ok {
+		return "", errors.New("unexpected APIC response. subscriptionId not found")
+	}
+	return id, nil
 }
 
 // Get the latest fabric events.
 // Filtering based on username is optional
-func (client *ApicClient) GetLatestEvents(c string, usr ...string) ([]ApicMoAttributes, error) {
+func (client *ApicClient) GetLatestEvents(ctx context.Context, c string, usr ...string) ([]AaaModLR, error) {
 
-	q := fmt.Sprintf("order-by=aaaModLR.created|desc&page-size=%s", c)
+	q := ClassQuery("aaaModLR").OrderBy("aaaModLR.created", Desc).Param("page-size", c)
 
-	for _, u := range usr {
-		q += fmt.Sprintf("&query-target-filter=eq(aaaModLR.user,\"%s\")", u)
+	if len(usr) > 0 {
+		users := []Filter{}
+		for _, u := range usr {
+			users = append(users, Eq("aaaModLR.user", u))
+		}
+		q.Filter(Or(users...))
 	}
 
-	events, err := client.reqApicClass(http.MethodGet, "aaaModLR", q)
-	if err != nil {
+	var events []AaaModLR
+	if err := client.getClass(ctx, q, &events); err != nil {
 		return nil, err
 	}
 	return events, nil
@@ -194,121 +350,390 @@ func (client *ApicClient) GetLatestEvents(c string, usr ...string) ([]ApicMoAttr
 
 // Get the latest fabric fault.
 // Filtering based on username is optional
-func (client *ApicClient) GetLatestFaults(c string) ([]ApicMoAttributes, error) {
+func (client *ApicClient) GetLatestFaults(ctx context.Context, c string) ([]FaultInst, error) {
 
-	faults, err := client.reqApicClass(http.MethodGet, "faultInst", "order-by=faultInst.lastTransition|desc", fmt.Sprintf("page-size=%s", c))
-	if err != nil {
+	var faults []FaultInst
+	if err := client.getClass(ctx, ClassQuery("faultInst").OrderBy("faultInst.lastTransition", Desc).Param("page-size", c), &faults); err != nil {
 		return nil, err
 	}
 	return faults, nil
 }
 
+// Create or modify the MO of a DN and its children
+// The payload is the JSON representation of the MO. Children MOs may be posted to their parent DN
+func (client *ApicClient) PostMo(ctx context.Context, dn string, payload []byte) error {
+	var result map[string]interface{}
+	req, err := client.makeCall(ctx, http.MethodPost, DnQuery(dn).String(), bytes.NewReader(payload))
+
+	if err != nil {
+		return err
+	}
+	if err = client.doCall(req, &result); err != nil {
+		log.Println("Error: ", err)
+		return err
+	}
+	return nil
+}
+
 // Get the Fabric LLDP and CDP neigh
 // Filter based on node id optional
-func (client *ApicClient) GetFabricNeighbors(nd string) (map[string][]string, error) {
+func (client *ApicClient) GetFabricNeighbors(ctx context.Context, nd string) (map[string][]string, error) {
 
-	cdpN, err := client.reqApicClass(http.MethodGet, "cdpAdjEp")
-	if err != nil {
+	var cdpN []CdpAdjEp
+	if err := client.getClass(ctx, ClassQuery("cdpAdjEp"), &cdpN); err != nil {
 		return nil, err
 	}
-	lldpN, err := client.reqApicClass(http.MethodGet, "lldpAdjEp")
-	if err != nil {
+	var lldpN []LldpAdjEp
+	if err := client.getClass(ctx, ClassQuery("lldpAdjEp"), &lldpN); err != nil {
 		return nil, err
 	}
+	// DN and system name of the neighbors
+	neighs := [][2]string{}
+	for _, n := range cdpN {
+		neighs = append(neighs, [2]string{n.Dn, n.SysName})
+	}
+	for _, n := range lldpN {
+		neighs = append(neighs, [2]string{n.Dn, n.SysName})
+	}
 	neighMap := make(map[string][]string)
 
-	for _, n := range append(cdpN, lldpN...) {
-		node := GetRn(n["dn"], "node")
-		nodeIface := fmt.Sprintf("%s:%s", node, GetRn(n["dn"], "if"))
-		if !stringInSlice(nodeIface, neighMap[n["sysName"]]) && (nd == node || nd == "all") && n["sysName"] != "" {
-			neighMap[n["sysName"]] = append(neighMap[n["sysName"]], nodeIface)
+	for _, n := range neighs {
+		dn, sysName := n[0], n[1]
+		node := GetRn(dn, "node")
+		nodeIface := fmt.Sprintf("%s:%s", node, GetRn(dn, "if"))
+		if !stringInSlice(nodeIface, neighMap[sysName]) && (nd == node || nd == "all") && sysName != "" {
+			neighMap[sysName] = append(neighMap[sysName], nodeIface)
 		}
 	}
 	return neighMap, nil
 }
 
+// Get health, hardware status and active faults of a node
+func (client *ApicClient) GetNodeInformation(ctx context.Context, nd string) (NodeInformation, error) {
+
+	var info NodeInformation
+
+	var nodes []FabricNode
+	if err := client.getClass(ctx, ClassQuery("fabricNode").Filter(Eq("fabricNode.id", nd)), &nodes); err != nil {
+		return NodeInformation{}, err
+	}
+	// Unknown node. Return an empty struct
+	if len(nodes) == 0 {
+		return NodeInformation{}, nil
+	}
+	node := nodes[0]
+	dn := fmt.Sprintf("/node-%s/", nd)
+
+	var system []TopSystem
+	if err := client.getClass(ctx, ClassQuery("topSystem").Filter(Wcard("topSystem.dn", dn)), &system); err != nil {
+		return NodeInformation{}, err
+	}
+	var health []HealthInst
+	if err := client.getClass(ctx, ClassQuery("healthInst").Filter(Eq("healthInst.dn", node.Dn+"/sys/health")), &health); err != nil {
+		return NodeInformation{}, err
+	}
+	var sups []EqptSupC
+	if err := client.getClass(ctx, ClassQuery("eqptSupC").Filter(Wcard("eqptSupC.dn", dn)), &sups); err != nil {
+		return NodeInformation{}, err
+	}
+	var fans []EqptFan
+	if err := client.getClass(ctx, ClassQuery("eqptFan").Filter(Wcard("eqptFan.dn", dn)), &fans); err != nil {
+		return NodeInformation{}, err
+	}
+	var psus []EqptPsu
+	if err := client.getClass(ctx, ClassQuery("eqptPsu").Filter(Wcard("eqptPsu.dn", dn)), &psus); err != nil {
+		return NodeInformation{}, err
+	}
+	faults := []FaultInst{}
+	if err := client.getClass(ctx, ClassQuery("faultInst").Filter(And(Wcard("faultInst.dn", dn), Ne("faultInst.severity", "cleared"))).OrderBy("faultInst.lastTransition", Desc), &faults); err != nil {
+		return NodeInformation{}, err
+	}
+
+	//Parse result
+	info.Id = node.Id
+	info.Name = node.Name
+	info.Role = node.Role
+	info.Pod = GetRn(node.Dn, "pod")
+	info.Model = node.Model
+	info.Serial = node.Serial
+	info.Address = node.Address
+	info.FabricSt = node.FabricSt
+	info.Firmware = node.Version
+	if len(system) > 0 {
+		info.Uptime = system[0].SystemUpTime
+	}
+	if len(health) > 0 {
+		info.Health = health[0].Cur
+	}
+	info.Supervisors = make([]map[string]string, 0)
+	for _, item := range sups {
+		info.Supervisors = append(info.Supervisors, map[string]string{"id": GetRn(item.Dn, "supslot"), "model": item.Model, "status": item.OperSt})
+	}
+	info.Fans = make([]map[string]string, 0)
+	for _, item := range fans {
+		info.Fans = append(info.Fans, map[string]string{"id": fmt.Sprintf("%s/%s", GetRn(item.Dn, "ftslot"), item.Id), "status": item.OperSt})
+	}
+	info.Psus = make([]map[string]string, 0)
+	for _, item := range psus {
+		info.Psus = append(info.Psus, map[string]string{"id": GetRn(item.Dn, "psuslot"), "model": item.Model, "status": item.OperSt})
+	}
+	info.Faults = faults
+	return info, nil
+}
+
+// Get status and counters of the physical interfaces of a node
+// Filter based on interface id (eth1/x) optional
+func (client *ApicClient) GetInterfaceInformation(ctx context.Context, nd string, iface string) ([]InterfaceInformation, error) {
+
+	ifaces, err := client.getInterfaces(ctx, fmt.Sprintf("/node-%s/", nd))
+	if err != nil {
+		return nil, err
+	}
+	info := []InterfaceInformation{}
+	for _, i := range ifaces {
+		if i.Node == nd && (iface == "" || i.Id == iface) {
+			info = append(info, i)
+		}
+	}
+	return info, nil
+}
+
+// Get the fabric interfaces with the highest amount of CRC and input errors
+func (client *ApicClient) GetTopErrorInterfaces(ctx context.Context, c string) ([]InterfaceInformation, error) {
+
+	count, err := strconv.Atoi(c)
+	if err != nil {
+		return nil, err
+	}
+	ifaces, err := client.getInterfaces(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	// Only return interfaces with errors, the worst first
+	info := []InterfaceInformation{}
+	for _, i := range ifaces {
+		if interfaceErrors(i) > 0 {
+			info = append(info, i)
+		}
+	}
+	sort.SliceStable(info, func(i, j int) bool {
+		return interfaceErrors(info[i]) > interfaceErrors(info[j])
+	})
+	if len(info) > count {
+		info = info[:count]
+	}
+	return info, nil
+}
+
+// Get and merge the interface state, counters and utilization
+// Server-side filtering based on the DN is optional
+func (client *ApicClient) getInterfaces(ctx context.Context, dn string) ([]InterfaceInformation, error) {
+
+	// Server-side filtering of a class on the DN
+	query := func(c string) *Query {
+		q := ClassQuery(c)
+		if dn != "" {
+			q.Filter(Wcard(c+".dn", dn))
+		}
+		return q
+	}
+	var physIfs []L1PhysIf
+	if err := client.getClass(ctx, query("l1PhysIf"), &physIfs); err != nil {
+		return nil, err
+	}
+	var ethpmIfs []EthpmPhysIf
+	if err := client.getClass(ctx, query("ethpmPhysIf"), &ethpmIfs); err != nil {
+		return nil, err
+	}
+	var etherStats []RmonEtherStats
+	if err := client.getClass(ctx, query("rmonEtherStats"), &etherStats); err != nil {
+		return nil, err
+	}
+	var ifIn []RmonIfIn
+	if err := client.getClass(ctx, query("rmonIfIn"), &ifIn); err != nil {
+		return nil, err
+	}
+	var ifOut []RmonIfOut
+	if err := client.getClass(ctx, query("rmonIfOut"), &ifOut); err != nil {
+		return nil, err
+	}
+	var ingr []EqptIngrTotal5min
+	if err := client.getClass(ctx, query("eqptIngrTotal5min"), &ingr); err != nil {
+		return nil, err
+	}
+	var egr []EqptEgrTotal5min
+	if err := client.getClass(ctx, query("eqptEgrTotal5min"), &egr); err != nil {
+		return nil, err
+	}
+
+	ifaces := make(map[string]*InterfaceInformation)
+	keys := []string{}
+	for _, item := range physIfs {
+		k := interfaceKey(item.Dn)
+		ifaces[k] = &InterfaceInformation{Node: GetRn(item.Dn, "node"), Id: item.Id, AdminSt: item.AdminSt, Speed: item.Speed}
+		keys = append(keys, k)
+	}
+	for _, item := range ethpmIfs {
+		if i, ok := ifaces[interfaceKey(item.Dn)]; ok {
+			i.OperSt = item.OperSt
+			i.LastLinkStChg = item.LastLinkStChg
+			if item.OperSpeed != "" {
+				i.Speed = item.OperSpeed
+			}
+		}
+	}
+	for _, item := range etherStats {
+		if i, ok := ifaces[interfaceKey(item.Dn)]; ok {
+			i.CrcErrors = item.CRCAlignErrors
+		}
+	}
+	for _, item := range ifIn {
+		if i, ok := ifaces[interfaceKey(item.Dn)]; ok {
+			i.InputErrors = item.Errors
+		}
+	}
+	for _, item := range ifOut {
+		if i, ok := ifaces[interfaceKey(item.Dn)]; ok {
+			i.OutputErrors = item.Errors
+		}
+	}
+	for _, item := range ingr {
+		if i, ok := ifaces[interfaceKey(item.Dn)]; ok {
+			i.InputUtil = item.UtilAvg
+		}
+	}
+	for _, item := range egr {
+		if i, ok := ifaces[interfaceKey(item.Dn)]; ok {
+			i.OutputUtil = item.UtilAvg
+		}
+	}
+
+	info := []InterfaceInformation{}
+	for _, k := range keys {
+		info = append(info, *ifaces[k])
+	}
+	return info, nil
+}
+
 // Get information of the fabric
 // Number of switches, Pods, Health
-func (client *ApicClient) GetFabricInformation() (FabricInformation, error) {
+func (client *ApicClient) GetFabricInformation(ctx context.Context) (FabricInformation, error) {
 
 	var info FabricInformation
 
 	// Get the values from the APIC
-	banner, err := client.reqApicClass(http.MethodGet, "aaaPreLoginBanner")
-	if err != nil {
+	var banner []AaaPreLoginBanner
+	if err := client.getClass(ctx, ClassQuery("aaaPreLoginBanner"), &banner); err != nil {
 		return FabricInformation{}, err
 	}
-	pods, err := client.reqApicClass(http.MethodGet, "fabricPod")
-	if err != nil {
+	var pods []FabricPod
+	if err := client.getClass(ctx, ClassQuery("fabricPod"), &pods); err != nil {
 		return FabricInformation{}, err
 	}
-	nodes, err := client.reqApicClass(http.MethodGet, "fabricNode")
-	if err != nil {
+	var nodes []FabricNode
+	if err := client.getClass(ctx, ClassQuery("fabricNode"), &nodes); err != nil {
 		return FabricInformation{}, err
 	}
-	health, err := client.reqApicClass(http.MethodGet, "fabricOverallHealthHist5min", "query-target-filter=and(eq(fabricOverallHealthHist5min.dn,\"topology/HDfabricOverallHealth5min-0\"))")
-	if err != nil {
+	var health []HealthHist
+	if err := client.getRecords(ctx, ClassQuery("fabricOverallHealthHist5min").Filter(And(Eq("fabricOverallHealthHist5min.dn", "topology/HDfabricOverallHealth5min-0"))), &health); err != nil {
 		return FabricInformation{}, err
 	}
 	//Parse result
-	info.Name = banner[0]["guiTextMessage"]
+	if len(banner) > 0 {
+		info.Name = banner[0].GuiTextMessage
+	}
 	info.Pods = make([]map[string]string, 0)
 
 	for _, item := range pods {
-		info.Pods = append(info.Pods, map[string]string{"id": item["id"], "type": item["podType"]})
+		info.Pods = append(info.Pods, map[string]string{"id": item.Id, "type": item.PodType})
 	}
 	info.Spines = make([]map[string]string, 0)
 	info.Leafs = make([]map[string]string, 0)
 	info.Apics = make([]map[string]string, 0)
 	for _, item := range nodes {
-		switch item["role"] {
+		switch item.Role {
 		case "controller":
-			info.Apics = append(info.Apics, map[string]string{"name": item["name"], "version": item["version"]})
+			info.Apics = append(info.Apics, map[string]string{"name": item.Name, "version": item.Version})
 		case "leaf":
-			info.Leafs = append(info.Leafs, map[string]string{"name": item["name"], "version": item["version"]})
+			info.Leafs = append(info.Leafs, map[string]string{"name": item.Name, "version": item.Version})
 		case "spine":
-			info.Spines = append(info.Spines, map[string]string{"name": item["name"], "version": item["version"]})
+			info.Spines = append(info.Spines, map[string]string{"name": item.Name, "version": item.Version})
 		}
 	}
-	info.Health = health[0]["healthAvg"]
+	if len(health) > 0 {
+		info.Health = health[0].HealthAvg
+	}
 	info.Url = client.baseURL
 	return info, nil
 }
 
+// Get the health history of the fabric, pods, tenants and nodes
+// Granularity (g) must be one of 15min, 1h or 1d
+func (client *ApicClient) GetHealthHistory(ctx context.Context, g string) (HealthHistory, error) {
+
+	history := HealthHistory{Granularity: g}
+
+	var fabric, pods, tenants, nodes []HealthHist
+	if err := client.getRecords(ctx, ClassQuery("fabricOverallHealthHist"+g), &fabric); err != nil {
+		return HealthHistory{}, err
+	}
+	if err := client.getRecords(ctx, ClassQuery("fabricHealthTotalHist"+g), &pods); err != nil {
+		return HealthHistory{}, err
+	}
+	if err := client.getRecords(ctx, ClassQuery("fvOverallHealthHist"+g), &tenants); err != nil {
+		return HealthHistory{}, err
+	}
+	if err := client.getRecords(ctx, ClassQuery("fabricNodeHealthHist"+g), &nodes); err != nil {
+		return HealthHistory{}, err
+	}
+	//Parse result
+	if series := getHealthSeries(fabric, ""); len(series) > 0 {
+		history.Fabric = series[0]
+	}
+	history.Fabric.Name = "fabric"
+	history.Pods = getHealthSeries(pods, "pod")
+	history.Tenants = getHealthSeries(tenants, "tn")
+	history.Nodes = getHealthSeries(nodes, "node")
+	return history, nil
+}
+
 // Get information from an specific enpoint [MAC]
-func (client *ApicClient) GetEndpointInformation(m string) ([]EndpointInformation, error) {
+func (client *ApicClient) GetEndpointInformation(ctx context.Context, m string) ([]EndpointInformation, error) {
 	var info []EndpointInformation
-	ep, err := client.reqApicClass(http.MethodGet, "fvCEp", fmt.Sprintf("query-target-filter=eq(fvCEp.mac,\"%s\")", m))
-	if err != nil {
+	var eps []FvCEp
+	if err := client.getClass(ctx, ClassQuery("fvCEp").Filter(Eq("fvCEp.mac", m)), &eps); err != nil {
 		return []EndpointInformation{}, err
 	}
-	for _, itemEp := range ep {
+	for _, itemEp := range eps {
 		var ep EndpointInformation
-		ep.Mac = itemEp["mac"]
-		ep.Tenant = GetRn(itemEp["dn"], "tn")
-		ep.App = GetRn(itemEp["dn"], "ap")
-		ep.Epg = GetRn(itemEp["dn"], "epg")
+		ep.Mac = itemEp.Mac
+		ep.Tenant = GetRn(itemEp.Dn, "tn")
+		ep.App = GetRn(itemEp.Dn, "ap")
+		ep.Epg = GetRn(itemEp.Dn, "epg")
 		// Only return EPG Endpoints
 		if ep.Epg == "" {
 			continue
 		}
-		ips, err := client.getMoChildren("fvCEp", "fvIp", fmt.Sprintf("eq(fvCEp.dn,\"%s\")", itemEp["dn"]))
-		if err != nil {
+		var ips []FvCEp
+		if err := client.getClass(ctx, ClassQuery("fvCEp").Subtree("children").SubtreeClass("fvIp").Filter(Eq("fvCEp.dn", itemEp.Dn)), &ips); err != nil {
 			return []EndpointInformation{}, err
 		}
-		for _, itempIp := range ips {
-			ep.Ips = append(ep.Ips, itempIp["addr"])
+		for _, e := range ips {
+			for _, itemIp := range e.Ips {
+				ep.Ips = append(ep.Ips, itemIp.Addr)
+			}
 		}
-		paths, err := client.getMoChildren("fvCEp", "fvRsCEpToPathEp", fmt.Sprintf("eq(fvCEp.dn,\"%s\")", itemEp["dn"]))
-		if err != nil {
+		var paths []FvCEp
+		if err := client.getClass(ctx, ClassQuery("fvCEp").Subtree("children").SubtreeClass("fvRsCEpToPathEp").Filter(Eq("fvCEp.dn", itemEp.Dn)), &paths); err != nil {
 			return []EndpointInformation{}, err
 		}
-		for _, itempPath := range paths {
-			location := getPath(itempPath["tDn"])
-			if location != nil {
-				ep.Location = append(ep.Location, location)
+		for _, e := range paths {
+			for _, itemPath := range e.Paths {
+				location := getPath(itemPath.TDn)
+				if location != nil {
+					ep.Location = append(ep.Location, location)
+				}
 			}
-
 		}
 
 		info = append(info, ep)
@@ -316,61 +741,72 @@ func (client *ApicClient) GetEndpointInformation(m string) ([]EndpointInformatio
 	return info, nil
 }
 
-// Get procEntity class
-func (client *ApicClient) GetProcEntity() ([]ApicMoAttributes, error) {
-	proc, err := client.reqApicClass(http.MethodGet, "procEntity")
-	if err != nil {
+// Get the latest events recorded for an specific endpoint [MAC]
+func (client *ApicClient) GetEndpointHistory(ctx context.Context, m string) ([]EventRecord, error) {
+
+	var events []EventRecord
+	if err := client.getClass(ctx, ClassQuery("eventRecord").Filter(Wcard("eventRecord.affected", "cep-"+m)).OrderBy("eventRecord.created", Desc).PageSize(10), &events); err != nil {
 		return nil, err
 	}
-	return proc, nil
+	return events, nil
 }
 
-// Get children Objects from an specific class
-func (client *ApicClient) getMoChildren(parent, children, query string) ([]ApicMoAttributes, error) {
-	var result map[string]interface{}
-	url := fmt.Sprintf("/api/node/class/%s.json?rsp-subtree=children&rsp-subtree-class=%s&query-target-filter=%s", parent, children, query)
-	req, err := client.makeCall(http.MethodGet, url, nil)
-
-	if err != nil {
+// Get procEntity class
+func (client *ApicClient) GetProcEntity(ctx context.Context) ([]ProcEntity, error) {
+	var proc []ProcEntity
+	if err := client.getClass(ctx, ClassQuery("procEntity"), &proc); err != nil {
 		return nil, err
 	}
+	return proc, nil
+}
 
-	if err = client.doCall(req, &result); err != nil {
-		log.Println("Error: ", err)
-		return nil, err
+// Run any query. The MOs of the result may be of any class
+func (client *ApicClient) Query(ctx context.Context, q *Query) (QueryResult, error) {
+	p, err := client.getPage(ctx, q)
+	if err != nil {
+		return QueryResult{}, err
 	}
-	return getApicManagedObjectsChildren(result, parent, children), nil
+	return getQueryResult(p)
 }
 
-// Generic request for an APIC Class/MO. Only works for the /apic/node/class URI
-// Server-side filtering is optional
-func (client *ApicClient) reqApicClass(m, c string, filter ...string) ([]ApicMoAttributes, error) {
-	var result map[string]interface{}
-	url := fmt.Sprintf("/api/node/class/%s.json", c)
-
-	// TODO: How to improve this
-	if len(filter) > 0 {
-		url += "?"
+// Get the MOs of all the pages of a class query
+func (client *ApicClient) reqApicMos(ctx context.Context, q *Query) ([]ApicMo, error) {
+	pages, err := client.getPages(ctx, q)
+	if err != nil {
+		return nil, err
 	}
-	for _, f := range filter {
-		url += "&" + f
+	mos := []ApicMo{}
+	for _, p := range pages {
+		r, err := getQueryResult(p)
+		if err != nil {
+			return nil, err
+		}
+		mos = append(mos, r.Mos...)
 	}
-	req, err := client.makeCall(m, url, nil)
+	return mos, nil
+}
 
+// Get the instances of a class as typed MOs, e.g. *[]FabricNode. See UnmarshalMos()
+func (client *ApicClient) getClass(ctx context.Context, q *Query, out interface{}) error {
+	mos, err := client.reqApicMos(ctx, q)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	return UnmarshalMos(mos, out)
+}
 
-	if err = client.doCall(req, &result); err != nil {
-		log.Println("Error: ", err)
-		return nil, err
+// Get the instances of the class of the query as records shared by several classes, e.g. *[]HealthHist
+func (client *ApicClient) getRecords(ctx context.Context, q *Query, out interface{}) error {
+	mos, err := client.reqApicMos(ctx, q)
+	if err != nil {
+		return err
 	}
-	return getApicManagedObjects(result, c), nil
+	return unmarshalRecords(mos, q.Class(), out)
 }
 
 // Create HTTP Request
-func (client *ApicClient) makeCall(m, url string, p io.Reader) (*http.Request, error) {
-	req, err := http.NewRequest(m, client.baseURL+url, p)
+func (client *ApicClient) makeCall(ctx context.Context, m, url string, p io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, m, client.baseURL+url, p)
 	if err != nil {
 		return nil, errors.New("unable to create a new HTTP request")
 	}
@@ -385,19 +821,19 @@ func (client *ApicClient) makeCall(m, url string, p io.Reader) (*http.Request, e
 
 // Execute HTTP Request
 func (client *ApicClient) doCall(req *http.Request, res interface{}) error {
-	resp, err := client.httpClient.Do(req)
-	if err != nil {
-		return err
+	start := time.Now()
+	status, body, err := client.send(req)
+	class := requestClass(req.URL)
+	requestDuration.Observe(time.Since(start).Seconds(), class)
+	if err != nil || status != http.StatusOK {
+		requestErrors.Inc(class)
+		recordFailure(req.Context())
 	}
-
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-
 	if err != nil {
 		return err
 	}
 
-	if resp.StatusCode != http.StatusOK {
+	if status != http.StatusOK {
 		return fmt.Errorf("error processing this request %s\n API message %s", req.URL, body)
 	}
 
@@ -3,13 +3,27 @@
 
 package apic
 
+import (
+	"context"
+
+	"github.com/gorilla/websocket"
+)
+
 type ApicClientMocks struct {
-	GetProcEntityF          func() ([]ApicMoAttributes, error)
-	GetFabricInformationF   func() (FabricInformation, error)
-	GetEndpointInformationF func(m string) ([]EndpointInformation, error)
-	GetFabricNeighborsF     func(nd string) (map[string][]string, error)
-	GetLatestFaultsF        func(c string) ([]ApicMoAttributes, error)
-	GetLatestEventsF        func(c string, usr ...string) ([]ApicMoAttributes, error)
+	GetProcEntityF           func(ctx context.Context) ([]ProcEntity, error)
+	GetFabricInformationF    func(ctx context.Context) (FabricInformation, error)
+	GetEndpointInformationF  func(ctx context.Context, m string) ([]EndpointInformation, error)
+	GetFabricNeighborsF      func(ctx context.Context, nd string) (map[string][]string, error)
+	GetNodeInformationF      func(ctx context.Context, nd string) (NodeInformation, error)
+	GetHealthHistoryF        func(ctx context.Context, g string) (HealthHistory, error)
+	GetInterfaceInformationF func(ctx context.Context, nd string, iface string) ([]InterfaceInformation, error)
+	GetTopErrorInterfacesF   func(ctx context.Context, c string) ([]InterfaceInformation, error)
+	GetLatestFaultsF         func(ctx context.Context, c string) ([]FaultInst, error)
+	GetLatestEventsF         func(ctx context.Context, c string, usr ...string) ([]AaaModLR, error)
+	PostMoF                  func(ctx context.Context, dn string, payload []byte) error
+	QueryF                   func(ctx context.Context, q *Query) (QueryResult, error)
+	GetEndpointHistoryF      func(ctx context.Context, m string) ([]EventRecord, error)
+	CheckAvailableF          func() error
 }
 
 var (
@@ -18,14 +32,14 @@ var (
 
 // Mock functions default values
 func (ac *ApicClientMocks) SetDefaultFunctions() {
-	ac.GetProcEntityF = func() ([]ApicMoAttributes, error) {
-		procs := []ApicMoAttributes{}
-		procs = append(procs, ApicMoAttributes{"dn": "topology/pod-1/node-1/sys/proc", "cpuPct": "50", "memFree": "4000", "maxMemAlloc": "6000"})
-		procs = append(procs, ApicMoAttributes{"dn": "topology/pod-1/node-2/sys/proc", "cpuPct": "40", "memFree": "60", "maxMemAlloc": "100"})
+	ac.GetProcEntityF = func(ctx context.Context) ([]ProcEntity, error) {
+		procs := []ProcEntity{}
+		procs = append(procs, ProcEntity{Dn: "topology/pod-1/node-1/sys/proc", CpuPct: "50", MemFree: "4000", MaxMemAlloc: "6000"})
+		procs = append(procs, ProcEntity{Dn: "topology/pod-1/node-2/sys/proc", CpuPct: "40", MemFree: "60", MaxMemAlloc: "100"})
 		return procs, nil
 	}
 
-	ac.GetFabricInformationF = func() (FabricInformation, error) {
+	ac.GetFabricInformationF = func(ctx context.Context) (FabricInformation, error) {
 		return FabricInformation{
 			Name:   "Test Fabric",
 			Url:    "https://test-apic.com",
@@ -36,7 +50,7 @@ func (ac *ApicClientMocks) SetDefaultFunctions() {
 			Health: "95"}, nil
 	}
 
-	ac.GetEndpointInformationF = func(m string) ([]EndpointInformation, error) {
+	ac.GetEndpointInformationF = func(ctx context.Context, m string) ([]EndpointInformation, error) {
 		return []EndpointInformation{{
 			Mac:      "AA:AA:AA:BB:BB:CC",
 			Ips:      []string{"192.168.1.1"},
@@ -47,59 +61,178 @@ func (ac *ApicClientMocks) SetDefaultFunctions() {
 		}}, nil
 	}
 
-	ac.GetFabricNeighborsF = func(nd string) (map[string][]string, error) {
+	ac.GetFabricNeighborsF = func(ctx context.Context, nd string) (map[string][]string, error) {
 		return map[string][]string{"SW1": {"101:[eth1/1]", "102:[eth1/2]"}, "SW2": {"101:[eth1/3]", "103:[eth1/4]"}, "SW3": {"102:[eth1/5]", "103:[eth1/6]"}}, nil
 	}
 
-	ac.GetLatestFaultsF = func(c string) ([]ApicMoAttributes, error) {
-		return []ApicMoAttributes{
-			{"code": "F1451",
-				"dn":       "topology/pod-1/node-202/sys/ch/psuslot-1/psu/fault-F1451",
-				"descr":    "Power supply shutdown. (serial number ABCDEF)",
-				"severity": "minor",
-				"lc":       "raised",
-				"type":     "environmental",
-				"created":  "2021-09-07T13:20:13.645+01:00",
+	ac.GetNodeInformationF = func(ctx context.Context, nd string) (NodeInformation, error) {
+		return NodeInformation{
+			Id:          "101",
+			Name:        "LEAF1",
+			Role:        "leaf",
+			Pod:         "1",
+			Model:       "N9K-C93180YC-EX",
+			Serial:      "FDO12345678",
+			Address:     "10.0.72.64",
+			FabricSt:    "active",
+			Firmware:    "n9000-15.2(3e)",
+			Uptime:      "10:02:30:15.000",
+			Health:      "98",
+			Supervisors: []map[string]string{{"id": "1", "model": "N9K-C93180YC-EX", "status": "online"}},
+			Fans:        []map[string]string{{"id": "1/1", "status": "operable"}, {"id": "2/1", "status": "inoperable"}},
+			Psus:        []map[string]string{{"id": "1", "model": "NXA-PAC-650W-PE", "status": "shut"}},
+			Faults: []FaultInst{{
+				Code:     "F1451",
+				Dn:       "topology/pod-1/node-101/sys/ch/psuslot-1/psu/fault-F1451",
+				Descr:    "Power supply shutdown. (serial number ABCDEF)",
+				Severity: "minor",
+			}},
+		}, nil
+	}
+
+	ac.GetHealthHistoryF = func(ctx context.Context, g string) (HealthHistory, error) {
+		return HealthHistory{
+			Granularity: g,
+			Fabric:      HealthSeries{Name: "fabric", Samples: []int{90, 92, 95, 100}, Min: 88, Max: 100, Avg: 94.25},
+			Pods:        []HealthSeries{{Name: "1", Samples: []int{90, 95}, Min: 90, Max: 95, Avg: 92.5}},
+			Tenants: []HealthSeries{
+				{Name: "myTenant", Samples: []int{50, 60, 70}, Min: 45, Max: 75, Avg: 60},
+				{Name: "common", Samples: []int{100, 100, 100}, Min: 100, Max: 100, Avg: 100},
+			},
+			Nodes: []HealthSeries{{Name: "101", Samples: []int{80, 100}, Min: 80, Max: 100, Avg: 90}},
+		}, nil
+	}
+
+	ac.GetInterfaceInformationF = func(ctx context.Context, nd string, iface string) ([]InterfaceInformation, error) {
+		return []InterfaceInformation{
+			{Node: "101", Id: "eth1/1", AdminSt: "up", OperSt: "up", Speed: "10G", LastLinkStChg: "2021-09-07T13:20:13.645+01:00",
+				CrcErrors: "0", InputErrors: "0", OutputErrors: "0", InputUtil: "1", OutputUtil: "2"},
+			{Node: "101", Id: "eth1/2", AdminSt: "up", OperSt: "down", Speed: "inherit", LastLinkStChg: "2021-09-08T10:00:00.000+01:00",
+				CrcErrors: "12", InputErrors: "3", OutputErrors: "0", InputUtil: "0", OutputUtil: "0"},
+		}, nil
+	}
+
+	ac.GetTopErrorInterfacesF = func(ctx context.Context, c string) ([]InterfaceInformation, error) {
+		return []InterfaceInformation{
+			{Node: "102", Id: "eth1/10", AdminSt: "up", OperSt: "up", Speed: "25G", CrcErrors: "1500", InputErrors: "20", OutputErrors: "0", InputUtil: "40", OutputUtil: "35"},
+			{Node: "101", Id: "eth1/2", AdminSt: "up", OperSt: "down", Speed: "inherit", CrcErrors: "12", InputErrors: "3", OutputErrors: "0", InputUtil: "0", OutputUtil: "0"},
+		}, nil
+	}
+
+	ac.GetLatestFaultsF = func(ctx context.Context, c string) ([]FaultInst, error) {
+		return []FaultInst{
+			{Code: "F1451",
+				Dn:       "topology/pod-1/node-202/sys/ch/psuslot-1/psu/fault-F1451",
+				Descr:    "Power supply shutdown. (serial number ABCDEF)",
+				Severity: "minor",
+				Lc:       "raised",
+				Type:     "environmental",
+				Created:  "2021-09-07T13:20:13.645+01:00",
+			}}, nil
+	}
+
+	ac.PostMoF = func(ctx context.Context, dn string, payload []byte) error {
+		return nil
+	}
+
+	ac.QueryF = func(ctx context.Context, q *Query) (QueryResult, error) {
+		if c := q.Class(); c != "" {
+			return QueryResult{TotalCount: 2, Mos: []ApicMo{
+				{Class: c, Attributes: ApicMoAttributes{"dn": "uni/tn-myTenant/BD-myBD", "name": "myBD", "unicastRoute": "yes"}},
+				{Class: c, Attributes: ApicMoAttributes{"dn": "uni/tn-common/BD-default", "name": "default", "unicastRoute": "no"}},
+			}}, nil
+		}
+		dn := q.Dn()
+		return QueryResult{TotalCount: 1, Mos: []ApicMo{
+			{Class: "fvTenant", Attributes: ApicMoAttributes{"dn": dn, "name": "myTenant"}, Children: []ApicMo{
+				{Class: "fvAp", Attributes: ApicMoAttributes{"dn": dn + "/ap-myApp", "name": "myApp"}},
+			}},
+		}}, nil
+	}
+
+	ac.CheckAvailableF = func() error {
+		return nil
+	}
+
+	ac.GetEndpointHistoryF = func(ctx context.Context, m string) ([]EventRecord, error) {
+		return []EventRecord{
+			{Code: "E4209236",
+				Affected: "uni/tn-myTenant/ap-myApp/epg-myEPG/cep-AA:AA:AA:BB:BB:CC",
+				Descr:    "ip 192.168.1.1 created",
+				Cause:    "transition",
+				Created:  "2021-09-07T13:20:13.645+01:00",
 			}}, nil
 	}
 
-	ac.GetLatestEventsF = func(c string, usr ...string) ([]ApicMoAttributes, error) {
-		return []ApicMoAttributes{
-			{"code": "E4218210",
-				"affected": "uni/uipageusage/pagecount-AllTenants",
-				"descr":    "PageCount AllTenants modified",
-				"user":     "user1",
-				"ind":      "modification",
-				"created":  "2021-09-07T13:20:13.645+01:00",
+	ac.GetLatestEventsF = func(ctx context.Context, c string, usr ...string) ([]AaaModLR, error) {
+		return []AaaModLR{
+			{Code: "E4218210",
+				Affected: "uni/uipageusage/pagecount-AllTenants",
+				Descr:    "PageCount AllTenants modified",
+				User:     "user1",
+				Ind:      "modification",
+				Created:  "2021-09-07T13:20:13.645+01:00",
 			}}, nil
 	}
 }
 
-func (ac *ApicClientMocks) GetProcEntity() ([]ApicMoAttributes, error) {
-	return ac.GetProcEntityF()
+func (ac *ApicClientMocks) GetProcEntity(ctx context.Context) ([]ProcEntity, error) {
+	return ac.GetProcEntityF(ctx)
 }
 
-func (ac *ApicClientMocks) GetFabricInformation() (FabricInformation, error) {
-	return ac.GetFabricInformationF()
+func (ac *ApicClientMocks) GetFabricInformation(ctx context.Context) (FabricInformation, error) {
+	return ac.GetFabricInformationF(ctx)
 }
 
-func (ac *ApicClientMocks) GetEndpointInformation(m string) ([]EndpointInformation, error) {
-	return ac.GetEndpointInformationF(m)
+func (ac *ApicClientMocks) GetEndpointInformation(ctx context.Context, m string) ([]EndpointInformation, error) {
+	return ac.GetEndpointInformationF(ctx, m)
 }
 
-func (ac *ApicClientMocks) GetFabricNeighbors(nd string) (map[string][]string, error) {
-	return ac.GetFabricNeighborsF(nd)
+func (ac *ApicClientMocks) GetFabricNeighbors(ctx context.Context, nd string) (map[string][]string, error) {
+	return ac.GetFabricNeighborsF(ctx, nd)
 }
 
-func (ac *ApicClientMocks) GetLatestFaults(c string) ([]ApicMoAttributes, error) {
-	return ac.GetLatestFaultsF(c)
+func (ac *ApicClientMocks) GetNodeInformation(ctx context.Context, nd string) (NodeInformation, error) {
+	return ac.GetNodeInformationF(ctx, nd)
 }
 
-func (ac *ApicClientMocks) SubscribeClassWebSocket(c string) (string, error) {
+func (ac *ApicClientMocks) GetHealthHistory(ctx context.Context, g string) (HealthHistory, error) {
+	return ac.GetHealthHistoryF(ctx, g)
+}
+
+func (ac *ApicClientMocks) GetInterfaceInformation(ctx context.Context, nd string, iface string) ([]InterfaceInformation, error) {
+	return ac.GetInterfaceInformationF(ctx, nd, iface)
+}
+
+func (ac *ApicClientMocks) GetTopErrorInterfaces(ctx context.Context, c string) ([]InterfaceInformation, error) {
+	return ac.GetTopErrorInterfacesF(ctx, c)
+}
+
+func (ac *ApicClientMocks) GetLatestFaults(ctx context.Context, c string) ([]FaultInst, error) {
+	return ac.GetLatestFaultsF(ctx, c)
+}
+
+func (ac *ApicClientMocks) PostMo(ctx context.Context, dn string, payload []byte) error {
+	return ac.PostMoF(ctx, dn, payload)
+}
+
+func (ac *ApicClientMocks) Query(ctx context.Context, q *Query) (QueryResult, error) {
+	return ac.QueryF(ctx, q)
+}
+
+func (ac *ApicClientMocks) GetEndpointHistory(ctx context.Context, m string) ([]EventRecord, error) {
+	return ac.GetEndpointHistoryF(ctx, m)
+}
+
+func (ac *ApicClientMocks) SubscribeMoWebSocket(ctx context.Context, dn string) (string, error) {
+	return "", nil
+}
+
+func (ac *ApicClientMocks) SubscribeClassWebSocket(ctx context.Context, c string) (string, error) {
 	return "", nil
 }
 
-func (ac *ApicClientMocks) RefreshSubscriptionWebSocket(id string) error {
+func (ac *ApicClientMocks) RefreshSubscriptionWebSocket(ctx context.Context, id string) error {
 	return nil
 }
 
@@ -111,10 +244,18 @@ func (ac *ApicClientMocks) GetToken() string {
 	return "aRanDoMtokEn"
 }
 
-func (ac *ApicClientMocks) Login() error {
+func (ac *ApicClientMocks) GetDialer() *websocket.Dialer {
+	return websocket.DefaultDialer
+}
+
+func (ac *ApicClientMocks) Login(ctx context.Context) error {
 	return nil
 }
 
-func (ac *ApicClientMocks) GetLatestEvents(c string, usr ...string) ([]ApicMoAttributes, error) {
-	return ac.GetLatestEventsF(c)
+func (ac *ApicClientMocks) GetLatestEvents(ctx context.Context, c string, usr ...string) ([]AaaModLR, error) {
+	return ac.GetLatestEventsF(ctx, c)
+}
+
+func (ac *ApicClientMocks) CheckAvailable() error {
+	return ac.CheckAvailableF()
 }
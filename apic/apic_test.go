@@ -2,11 +2,15 @@ package apic
 
 import (
 	"aci-chatbot/mocks"
+	"aci-chatbot/proxy"
 	"bytes"
+	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"path/filepath"
 	"reflect"
 	"runtime"
@@ -97,6 +101,13 @@ func TestApicClientCreation(t *testing.T) {
 		_, err := NewApicClient("http://mocking.com", "admin", "admin", SetTimeout(8))
 		notOk(t, err)
 	})
+	t.Run("Create Client with unexpected login response", func(t *testing.T) {
+		mocks.GetDoFunc = func(*http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(`{"totalCount": "0", "imdata": []}`)))}, nil
+		}
+		_, err := NewApicClient("http://mocking.com", "admin", "admin", SetTimeout(8))
+		notOk(t, err)
+	})
 }
 
 func TestGetProcEntity(t *testing.T) {
@@ -151,16 +162,16 @@ func TestGetProcEntity(t *testing.T) {
 			return nil, nil
 		}
 		clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetTimeout(8))
-		procs, _ := clt.GetProcEntity()
-		equals(t, procs[0]["dn"], "topology/pod-1/node-1/sys/proc")
-		equals(t, procs[0]["cpuPct"], "26")
-		equals(t, procs[0]["maxMemAlloc"], "131427900")
-		equals(t, procs[0]["memFree"], "76078044")
+		procs, _ := clt.GetProcEntity(context.Background())
+		equals(t, procs[0].Dn, "topology/pod-1/node-1/sys/proc")
+		equals(t, procs[0].CpuPct, "26")
+		equals(t, procs[0].MaxMemAlloc, "131427900")
+		equals(t, procs[0].MemFree, "76078044")
 
-		equals(t, procs[1]["dn"], "topology/pod-1/node-2/sys/proc")
-		equals(t, procs[1]["cpuPct"], "11")
-		equals(t, procs[1]["maxMemAlloc"], "196438552")
-		equals(t, procs[1]["memFree"], "162262988")
+		equals(t, procs[1].Dn, "topology/pod-1/node-2/sys/proc")
+		equals(t, procs[1].CpuPct, "11")
+		equals(t, procs[1].MaxMemAlloc, "196438552")
+		equals(t, procs[1].MemFree, "162262988")
 
 	})
 
@@ -174,10 +185,30 @@ func TestGetProcEntity(t *testing.T) {
 			}
 		}
 		clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetTimeout(8))
-		_, err := clt.GetProcEntity()
+		_, err := clt.GetProcEntity(context.Background())
 		notOk(t, err)
 		equals(t, err, errors.New("Generic HTTP Error"))
 	})
+
+	t.Run("Cancelled Context", func(t *testing.T) {
+		mocks.GetDoFunc = func(req *http.Request) (*http.Response, error) {
+			loginR := ioutil.NopCloser(bytes.NewReader([]byte(login)))
+			if strings.Contains(req.URL.Path, "aaaLogin") {
+				return &http.Response{StatusCode: 200, Body: loginR}, nil
+			}
+			// The context of the caller is propagated to the HTTP request
+			if err := req.Context().Err(); err != nil {
+				return nil, err
+			}
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(proc)))}, nil
+		}
+		clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetTimeout(8))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := clt.GetProcEntity(ctx)
+		notOk(t, err)
+		equals(t, err, context.Canceled)
+	})
 }
 
 func TestGetFabricInformation(t *testing.T) {
@@ -281,7 +312,7 @@ func TestGetFabricInformation(t *testing.T) {
 			return nil, nil
 		}
 		clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetTimeout(8))
-		info, err := clt.GetFabricInformation()
+		info, err := clt.GetFabricInformation(context.Background())
 		ok(t, err)
 		equals(t, info.Name, "CX Fabric")
 		equals(t, info.Health, "95")
@@ -301,6 +332,113 @@ func TestGetFabricInformation(t *testing.T) {
 	})
 }
 
+func TestGetHealthHistory(t *testing.T) {
+	Client = &mocks.MockClient{}
+	login := `{
+		"totalCount": "1",
+		"imdata": [
+			{
+				"aaaLogin": {
+					"attributes": {
+						"token": "eyJhbGciOiJSUzI1NiIsImtpZCI6InJqcmRjazBuNW"
+					}
+				}
+			}
+		]
+	}`
+	fabric := `{
+		"totalCount": "2",
+		"imdata": [
+			{
+				"fabricOverallHealthHist1h": {
+					"attributes": {
+						"dn": "topology/HDfabricOverallHealth1h-0",
+						"healthAvg": "96",
+						"healthMax": "100",
+						"healthMin": "90",
+						"index": "0"
+					}
+				}
+			},
+			{
+				"fabricOverallHealthHist1h": {
+					"attributes": {
+						"dn": "topology/HDfabricOverallHealth1h-1",
+						"healthAvg": "80",
+						"healthMax": "85",
+						"healthMin": "70",
+						"index": "1"
+					}
+				}
+			}
+		]
+	}`
+	tenants := `{
+		"totalCount": "3",
+		"imdata": [
+			{
+				"fvOverallHealthHist1h": {
+					"attributes": {
+						"dn": "uni/tn-common/HDfvOverallHealth1h-0",
+						"healthAvg": "100",
+						"healthMax": "100",
+						"healthMin": "100",
+						"index": "0"
+					}
+				}
+			},
+			{
+				"fvOverallHealthHist1h": {
+					"attributes": {
+						"dn": "uni/tn-myTenant/HDfvOverallHealth1h-0",
+						"healthAvg": "60",
+						"healthMax": "70",
+						"healthMin": "50",
+						"index": "0"
+					}
+				}
+			},
+			{
+				"fvOverallHealthHist1h": {
+					"attributes": {
+						"dn": "uni/tn-myTenant/HDfvOverallHealth1h-1",
+						"healthAvg": "40",
+						"healthMax": "45",
+						"healthMin": "35",
+						"index": "1"
+					}
+				}
+			}
+		]
+	}`
+	empty := `{
+		"totalCount": "0",
+		"imdata": []
+	}`
+	mocks.GetDoFunc = func(req *http.Request) (*http.Response, error) {
+		if strings.Contains(req.URL.Path, "aaaLogin") {
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(login)))}, nil
+		} else if strings.Contains(req.URL.Path, "/api/node/class/fabricOverallHealthHist1h.json") {
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(fabric)))}, nil
+		} else if strings.Contains(req.URL.Path, "/api/node/class/fvOverallHealthHist1h.json") {
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(tenants)))}, nil
+		}
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(empty)))}, nil
+	}
+	clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetTimeout(8))
+	t.Run("Hourly history", func(t *testing.T) {
+		history, err := clt.GetHealthHistory(context.Background(), "1h")
+		ok(t, err)
+		equals(t, history.Granularity, "1h")
+		equals(t, history.Fabric, HealthSeries{Name: "fabric", Samples: []int{80, 96}, Min: 70, Max: 100, Avg: 88})
+		equals(t, len(history.Pods), 0)
+		equals(t, len(history.Nodes), 0)
+		equals(t, len(history.Tenants), 2)
+		equals(t, history.Tenants[0], HealthSeries{Name: "myTenant", Samples: []int{40, 60}, Min: 35, Max: 70, Avg: 50})
+		equals(t, history.Tenants[1].Name, "common")
+	})
+}
+
 func TestGetEndpointInformation(t *testing.T) {
 
 	Client = &mocks.MockClient{}
@@ -405,17 +543,17 @@ func TestGetEndpointInformation(t *testing.T) {
 		mocks.GetDoFunc = func(req *http.Request) (*http.Response, error) {
 			if strings.Contains(req.URL.Path, "aaaLogin") {
 				return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(login)))}, nil
-			} else if strings.Contains(req.URL.Path, "/api/node/class/fvCEp.json") && strings.Contains(req.URL.RawQuery, "query-target-filter=eq(fvCEp.mac") {
+			} else if strings.Contains(req.URL.Path, "/api/node/class/fvCEp.json") && strings.HasPrefix(req.URL.Query().Get("query-target-filter"), "eq(fvCEp.mac") {
 				return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(ep)))}, nil
-			} else if strings.Contains(req.URL.Path, "/api/node/class/fvCEp.json") && strings.Contains(req.URL.RawQuery, "rsp-subtree-class=fvIp") {
+			} else if strings.Contains(req.URL.Path, "/api/node/class/fvCEp.json") && req.URL.Query().Get("rsp-subtree-class") == "fvIp" {
 				return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(ips)))}, nil
-			} else if strings.Contains(req.URL.Path, "/api/node/class/fvCEp.json") && strings.Contains(req.URL.RawQuery, "rsp-subtree-class=fvRsCEpToPathEp") {
+			} else if strings.Contains(req.URL.Path, "/api/node/class/fvCEp.json") && req.URL.Query().Get("rsp-subtree-class") == "fvRsCEpToPathEp" {
 				return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(paths)))}, nil
 			}
 			return nil, nil
 		}
 		clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetTimeout(8))
-		info, err := clt.GetEndpointInformation("00:50:56:96:A0:D3")
+		info, err := clt.GetEndpointInformation(context.Background(), "00:50:56:96:A0:D3")
 		ok(t, err)
 		equals(t, info[0].Mac, "00:50:56:96:A0:D3")
 		equals(t, info[0].Tenant, "test_tenant")
@@ -506,7 +644,7 @@ func TestGetFabricNeighbors(t *testing.T) {
 	}
 	clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetTimeout(8))
 	t.Run("All neighbors", func(t *testing.T) {
-		neigh, err := clt.GetFabricNeighbors("all")
+		neigh, err := clt.GetFabricNeighbors(context.Background(), "all")
 		ok(t, err)
 		equals(t, len(neigh), 3)
 		equals(t, neigh["SW-1"], []string{"101:[eth1/1]"})
@@ -515,7 +653,7 @@ func TestGetFabricNeighbors(t *testing.T) {
 	})
 
 	t.Run("Node 101", func(t *testing.T) {
-		neigh, err := clt.GetFabricNeighbors("101")
+		neigh, err := clt.GetFabricNeighbors(context.Background(), "101")
 		ok(t, err)
 		equals(t, len(neigh), 2)
 		equals(t, neigh["SW-1"], []string{"101:[eth1/1]"})
@@ -523,6 +661,392 @@ func TestGetFabricNeighbors(t *testing.T) {
 	})
 }
 
+func TestGetNodeInformation(t *testing.T) {
+	Client = &mocks.MockClient{}
+	login := `{
+		"totalCount": "1",
+		"imdata": [
+			{
+				"aaaLogin": {
+					"attributes": {
+						"token": "eyJhbGciOiJSUzI1NiIsImtpZCI6InJqcmRjazBuNW"
+					}
+				}
+			}
+		]
+	}`
+	node := `{
+		"totalCount": "1",
+		"imdata": [
+			{
+				"fabricNode": {
+					"attributes": {
+						"address": "10.0.72.64",
+						"dn": "topology/pod-1/node-101",
+						"fabricSt": "active",
+						"id": "101",
+						"model": "N9K-C93180YC-EX",
+						"name": "Leaf-101",
+						"role": "leaf",
+						"serial": "FDO12345678",
+						"version": "n9000-15.2(2f)"
+					}
+				}
+			}
+		]
+	}`
+	system := `{
+		"totalCount": "1",
+		"imdata": [
+			{
+				"topSystem": {
+					"attributes": {
+						"dn": "topology/pod-1/node-101/sys",
+						"systemUpTime": "10:02:30:15.000"
+					}
+				}
+			}
+		]
+	}`
+	health := `{
+		"totalCount": "1",
+		"imdata": [
+			{
+				"healthInst": {
+					"attributes": {
+						"cur": "98",
+						"dn": "topology/pod-1/node-101/sys/health"
+					}
+				}
+			}
+		]
+	}`
+	sups := `{
+		"totalCount": "1",
+		"imdata": [
+			{
+				"eqptSupC": {
+					"attributes": {
+						"dn": "topology/pod-1/node-101/sys/ch/supslot-1/sup",
+						"model": "N9K-C93180YC-EX",
+						"operSt": "online"
+					}
+				}
+			}
+		]
+	}`
+	fans := `{
+		"totalCount": "2",
+		"imdata": [
+			{
+				"eqptFan": {
+					"attributes": {
+						"dn": "topology/pod-1/node-101/sys/ch/ftslot-1/ft/fan-1",
+						"id": "1",
+						"operSt": "operable"
+					}
+				}
+			},
+			{
+				"eqptFan": {
+					"attributes": {
+						"dn": "topology/pod-1/node-101/sys/ch/ftslot-2/ft/fan-1",
+						"id": "1",
+						"operSt": "inoperable"
+					}
+				}
+			}
+		]
+	}`
+	psus := `{
+		"totalCount": "1",
+		"imdata": [
+			{
+				"eqptPsu": {
+					"attributes": {
+						"dn": "topology/pod-1/node-101/sys/ch/psuslot-1/psu",
+						"model": "NXA-PAC-650W-PE",
+						"operSt": "shut"
+					}
+				}
+			}
+		]
+	}`
+	faults := `{
+		"totalCount": "1",
+		"imdata": [
+			{
+				"faultInst": {
+					"attributes": {
+						"code": "F1451",
+						"descr": "Power supply shutdown. (serial number ABCDEF)",
+						"dn": "topology/pod-1/node-101/sys/ch/psuslot-1/psu/fault-F1451",
+						"severity": "minor"
+					}
+				}
+			}
+		]
+	}`
+	empty := `{
+		"totalCount": "0",
+		"imdata": []
+	}`
+	replies := map[string]string{
+		"topSystem":  system,
+		"healthInst": health,
+		"eqptSupC":   sups,
+		"eqptFan":    fans,
+		"eqptPsu":    psus,
+		"faultInst":  faults,
+	}
+	mocks.GetDoFunc = func(req *http.Request) (*http.Response, error) {
+		if strings.Contains(req.URL.Path, "aaaLogin") {
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(login)))}, nil
+		} else if strings.Contains(req.URL.Path, "/api/node/class/fabricNode.json") && strings.Contains(req.URL.Query().Get("query-target-filter"), "\"101\"") {
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(node)))}, nil
+		} else if strings.Contains(req.URL.Path, "/api/node/class/fabricNode.json") {
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(empty)))}, nil
+		}
+		for c, r := range replies {
+			if strings.Contains(req.URL.Path, fmt.Sprintf("/api/node/class/%s.json", c)) {
+				return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(r)))}, nil
+			}
+		}
+		return nil, nil
+	}
+	clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetTimeout(8))
+	t.Run("Existing node", func(t *testing.T) {
+		info, err := clt.GetNodeInformation(context.Background(), "101")
+		ok(t, err)
+		equals(t, info.Id, "101")
+		equals(t, info.Name, "Leaf-101")
+		equals(t, info.Pod, "1")
+		equals(t, info.Serial, "FDO12345678")
+		equals(t, info.Address, "10.0.72.64")
+		equals(t, info.Firmware, "n9000-15.2(2f)")
+		equals(t, info.Uptime, "10:02:30:15.000")
+		equals(t, info.Health, "98")
+		equals(t, info.Supervisors, []map[string]string{{"id": "1", "model": "N9K-C93180YC-EX", "status": "online"}})
+		equals(t, info.Fans, []map[string]string{{"id": "1/1", "status": "operable"}, {"id": "2/1", "status": "inoperable"}})
+		equals(t, info.Psus, []map[string]string{{"id": "1", "model": "NXA-PAC-650W-PE", "status": "shut"}})
+		equals(t, len(info.Faults), 1)
+		equals(t, info.Faults[0].Code, "F1451")
+	})
+	t.Run("Unknown node", func(t *testing.T) {
+		info, err := clt.GetNodeInformation(context.Background(), "999")
+		ok(t, err)
+		equals(t, info.Id, "")
+	})
+}
+
+func TestGetInterfaceInformation(t *testing.T) {
+	Client = &mocks.MockClient{}
+	login := `{
+		"totalCount": "1",
+		"imdata": [
+			{
+				"aaaLogin": {
+					"attributes": {
+						"token": "eyJhbGciOiJSUzI1NiIsImtpZCI6InJqcmRjazBuNW"
+					}
+				}
+			}
+		]
+	}`
+	l1PhysIf := `{
+		"totalCount": "3",
+		"imdata": [
+			{
+				"l1PhysIf": {
+					"attributes": {
+						"adminSt": "up",
+						"dn": "topology/pod-1/node-101/sys/phys-[eth1/1]",
+						"id": "eth1/1",
+						"speed": "inherit"
+					}
+				}
+			},
+			{
+				"l1PhysIf": {
+					"attributes": {
+						"adminSt": "down",
+						"dn": "topology/pod-1/node-101/sys/phys-[eth1/2]",
+						"id": "eth1/2",
+						"speed": "inherit"
+					}
+				}
+			},
+			{
+				"l1PhysIf": {
+					"attributes": {
+						"adminSt": "up",
+						"dn": "topology/pod-1/node-102/sys/phys-[eth1/1]",
+						"id": "eth1/1",
+						"speed": "inherit"
+					}
+				}
+			}
+		]
+	}`
+	ethpmPhysIf := `{
+		"totalCount": "2",
+		"imdata": [
+			{
+				"ethpmPhysIf": {
+					"attributes": {
+						"dn": "topology/pod-1/node-101/sys/phys-[eth1/1]/phys",
+						"lastLinkStChg": "2021-10-31T20:15:05.000+01:00",
+						"operSpeed": "10G",
+						"operSt": "up"
+					}
+				}
+			},
+			{
+				"ethpmPhysIf": {
+					"attributes": {
+						"dn": "topology/pod-1/node-101/sys/phys-[eth1/2]/phys",
+						"lastLinkStChg": "2021-10-30T10:00:00.000+01:00",
+						"operSpeed": "inherit",
+						"operSt": "down"
+					}
+				}
+			}
+		]
+	}`
+	rmonEtherStats := `{
+		"totalCount": "3",
+		"imdata": [
+			{
+				"rmonEtherStats": {
+					"attributes": {
+						"cRCAlignErrors": "5",
+						"dn": "topology/pod-1/node-101/sys/phys-[eth1/1]/dbgEtherStats"
+					}
+				}
+			},
+			{
+				"rmonEtherStats": {
+					"attributes": {
+						"cRCAlignErrors": "0",
+						"dn": "topology/pod-1/node-101/sys/phys-[eth1/2]/dbgEtherStats"
+					}
+				}
+			},
+			{
+				"rmonEtherStats": {
+					"attributes": {
+						"cRCAlignErrors": "120",
+						"dn": "topology/pod-1/node-102/sys/phys-[eth1/1]/dbgEtherStats"
+					}
+				}
+			}
+		]
+	}`
+	rmonIfIn := `{
+		"totalCount": "1",
+		"imdata": [
+			{
+				"rmonIfIn": {
+					"attributes": {
+						"dn": "topology/pod-1/node-101/sys/phys-[eth1/1]/dbgIfIn",
+						"errors": "2"
+					}
+				}
+			}
+		]
+	}`
+	rmonIfOut := `{
+		"totalCount": "1",
+		"imdata": [
+			{
+				"rmonIfOut": {
+					"attributes": {
+						"dn": "topology/pod-1/node-101/sys/phys-[eth1/1]/dbgIfOut",
+						"errors": "1"
+					}
+				}
+			}
+		]
+	}`
+	ingr := `{
+		"totalCount": "1",
+		"imdata": [
+			{
+				"eqptIngrTotal5min": {
+					"attributes": {
+						"dn": "topology/pod-1/node-101/sys/phys-[eth1/1]/CDeqptIngrTotal5min",
+						"utilAvg": "12"
+					}
+				}
+			}
+		]
+	}`
+	egr := `{
+		"totalCount": "1",
+		"imdata": [
+			{
+				"eqptEgrTotal5min": {
+					"attributes": {
+						"dn": "topology/pod-1/node-101/sys/phys-[eth1/1]/CDeqptEgrTotal5min",
+						"utilAvg": "7"
+					}
+				}
+			}
+		]
+	}`
+	replies := map[string]string{
+		"l1PhysIf":          l1PhysIf,
+		"ethpmPhysIf":       ethpmPhysIf,
+		"rmonEtherStats":    rmonEtherStats,
+		"rmonIfIn":          rmonIfIn,
+		"rmonIfOut":         rmonIfOut,
+		"eqptIngrTotal5min": ingr,
+		"eqptEgrTotal5min":  egr,
+	}
+	mocks.GetDoFunc = func(req *http.Request) (*http.Response, error) {
+		if strings.Contains(req.URL.Path, "aaaLogin") {
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(login)))}, nil
+		}
+		for c, r := range replies {
+			if strings.Contains(req.URL.Path, fmt.Sprintf("/api/node/class/%s.json", c)) {
+				return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(r)))}, nil
+			}
+		}
+		return nil, nil
+	}
+	clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetTimeout(8))
+	t.Run("All node interfaces", func(t *testing.T) {
+		ifaces, err := clt.GetInterfaceInformation(context.Background(), "101", "")
+		ok(t, err)
+		equals(t, len(ifaces), 2)
+		equals(t, ifaces[0], InterfaceInformation{Node: "101", Id: "eth1/1", AdminSt: "up", OperSt: "up", Speed: "10G",
+			LastLinkStChg: "2021-10-31T20:15:05.000+01:00", CrcErrors: "5", InputErrors: "2", OutputErrors: "1", InputUtil: "12", OutputUtil: "7"})
+		equals(t, ifaces[1].AdminSt, "down")
+		equals(t, ifaces[1].OperSt, "down")
+	})
+	t.Run("Single interface", func(t *testing.T) {
+		ifaces, err := clt.GetInterfaceInformation(context.Background(), "101", "eth1/2")
+		ok(t, err)
+		equals(t, len(ifaces), 1)
+		equals(t, ifaces[0].Id, "eth1/2")
+	})
+	t.Run("Top error interfaces", func(t *testing.T) {
+		ifaces, err := clt.GetTopErrorInterfaces(context.Background(), "5")
+		ok(t, err)
+		equals(t, len(ifaces), 2)
+		equals(t, ifaces[0].Node, "102")
+		equals(t, ifaces[0].CrcErrors, "120")
+		equals(t, ifaces[1].Node, "101")
+		equals(t, ifaces[1].Id, "eth1/1")
+	})
+	t.Run("Top error interfaces - Limited", func(t *testing.T) {
+		ifaces, err := clt.GetTopErrorInterfaces(context.Background(), "1")
+		ok(t, err)
+		equals(t, len(ifaces), 1)
+		equals(t, ifaces[0].Node, "102")
+	})
+}
+
 func TestGetLatestFaults(t *testing.T) {
 	Client = &mocks.MockClient{}
 	login := `{
@@ -596,11 +1120,11 @@ func TestGetLatestFaults(t *testing.T) {
 		}
 		clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetTimeout(8))
 
-		fault, err := clt.GetLatestFaults("all")
+		fault, err := clt.GetLatestFaults(context.Background(), "all")
 		ok(t, err)
 		equals(t, len(fault), 1)
-		equals(t, fault[0]["dn"], "uni/tn-tenant/cif-CON_IFACE/rsif/fault-F1123")
-		equals(t, fault[0]["severity"], "warning")
+		equals(t, fault[0].Dn, "uni/tn-tenant/cif-CON_IFACE/rsif/fault-F1123")
+		equals(t, fault[0].Severity, "warning")
 	})
 	t.Run("Unavailable Server", func(t *testing.T) {
 		mocks.GetDoFunc = func(req *http.Request) (*http.Response, error) {
@@ -613,11 +1137,179 @@ func TestGetLatestFaults(t *testing.T) {
 		}
 		clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetTimeout(8))
 
-		_, err := clt.GetLatestFaults("all")
+		_, err := clt.GetLatestFaults(context.Background(), "all")
 		notOk(t, err)
 	})
 
 }
+func TestPostMo(t *testing.T) {
+	Client = &mocks.MockClient{}
+	login := `{
+		"totalCount": "1",
+		"imdata": [
+			{
+				"aaaLogin": {
+					"attributes": {
+						"token": "eyJhbGciOiJSUzI1NiIsImtpZCI6InJqcmRjazBuNW"
+					}
+				}
+			}
+		]
+	}`
+	var method, payload string
+	mocks.GetDoFunc = func(req *http.Request) (*http.Response, error) {
+		if strings.Contains(req.URL.Path, "aaaLogin") {
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(login)))}, nil
+		} else if req.URL.Path == "/api/node/mo/uni.json" {
+			body, _ := ioutil.ReadAll(req.Body)
+			method, payload = req.Method, string(body)
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(`{"totalCount": "0", "imdata": []}`)))}, nil
+		}
+		return &http.Response{StatusCode: 400, Body: ioutil.NopCloser(bytes.NewReader([]byte(`{"totalCount": "1", "imdata": [{"error": {"attributes": {"code": "103", "text": "Invalid DN"}}}]}`)))}, nil
+	}
+	clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetTimeout(8))
+	t.Run("Create tenant", func(t *testing.T) {
+		err := clt.PostMo(context.Background(), "uni", []byte(`{"fvTenant":{"attributes":{"name":"myTenant","status":"created"}}}`))
+		ok(t, err)
+		equals(t, method, http.MethodPost)
+		equals(t, payload, `{"fvTenant":{"attributes":{"name":"myTenant","status":"created"}}}`)
+	})
+	t.Run("Invalid DN", func(t *testing.T) {
+		err := clt.PostMo(context.Background(), "uni/bad", []byte(`{}`))
+		notOk(t, err)
+		equals(t, strings.Contains(err.Error(), "Invalid DN"), true)
+	})
+}
+
+func TestQuery(t *testing.T) {
+	Client = &mocks.MockClient{}
+	login := `{
+		"totalCount": "1",
+		"imdata": [
+			{
+				"aaaLogin": {
+					"attributes": {
+						"token": "eyJhbGciOiJSUzI1NiIsImtpZCI6InJqcmRjazBuNW"
+					}
+				}
+			}
+		]
+	}`
+	bds := `{
+		"totalCount": "42",
+		"imdata": [
+			{
+				"fvBD": {
+					"attributes": {
+						"dn": "uni/tn-myTenant/BD-myBD",
+						"name": "myBD"
+					}
+				}
+			}
+		]
+	}`
+	tenant := `{
+		"totalCount": "1",
+		"imdata": [
+			{
+				"fvTenant": {
+					"attributes": {
+						"dn": "uni/tn-myTenant",
+						"name": "myTenant"
+					},
+					"children": [
+						{
+							"fvAp": {
+								"attributes": {
+									"name": "myApp"
+								}
+							}
+						}
+					]
+				}
+			}
+		]
+	}`
+	var query string
+	mocks.GetDoFunc = func(req *http.Request) (*http.Response, error) {
+		query = req.URL.RawQuery
+		if strings.Contains(req.URL.Path, "aaaLogin") {
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(login)))}, nil
+		} else if req.URL.Path == "/api/node/class/fvBD.json" {
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(bds)))}, nil
+		} else if req.URL.Path == "/api/node/mo/uni/tn-myTenant.json" {
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(tenant)))}, nil
+		}
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(`{"imdata": {}}`)))}, nil
+	}
+	clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetTimeout(8))
+	t.Run("Query class", func(t *testing.T) {
+		r, err := clt.Query(context.Background(), ClassQuery("fvBD").PageSize(1).OrderBy("fvBD.name", Desc))
+		ok(t, err)
+		equals(t, query, "page-size=1&order-by=fvBD.name%7Cdesc")
+		equals(t, r, QueryResult{TotalCount: 42, Mos: []ApicMo{{Class: "fvBD", Attributes: ApicMoAttributes{"dn": "uni/tn-myTenant/BD-myBD", "name": "myBD"}}}})
+	})
+	t.Run("Query DN with children", func(t *testing.T) {
+		r, err := clt.Query(context.Background(), DnQuery("uni/tn-myTenant").Subtree("children"))
+		ok(t, err)
+		equals(t, r.Mos[0].Class, "fvTenant")
+		equals(t, r.Mos[0].Children, []ApicMo{{Class: "fvAp", Attributes: ApicMoAttributes{"name": "myApp"}}})
+	})
+	t.Run("Unexpected response", func(t *testing.T) {
+		_, err := clt.Query(context.Background(), DnQuery("uni/tn-other"))
+		notOk(t, err)
+	})
+}
+
+func TestGetEndpointHistory(t *testing.T) {
+	Client = &mocks.MockClient{}
+	login := `{
+		"totalCount": "1",
+		"imdata": [
+			{
+				"aaaLogin": {
+					"attributes": {
+						"token": "eyJhbGciOiJSUzI1NiIsImtpZCI6InJqcmRjazBuNW"
+					}
+				}
+			}
+		]
+	}`
+	events := `{
+		"totalCount": "1",
+		"imdata": [
+			{
+				"eventRecord": {
+					"attributes": {
+						"affected": "uni/tn-test_tenant/ap-Test-AP/epg-test_epg/cep-00:50:56:96:A0:D3",
+						"code": "E4209236",
+						"created": "2021-10-28T07:32:34.846+01:00",
+						"descr": "ip 172.20.206.132 created"
+					}
+				}
+			}
+		]
+	}`
+	var filter string
+	mocks.GetDoFunc = func(req *http.Request) (*http.Response, error) {
+		if strings.Contains(req.URL.Path, "aaaLogin") {
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(login)))}, nil
+		} else if strings.Contains(req.URL.Path, "/api/node/class/eventRecord.json") {
+			filter = req.URL.Query().Get("query-target-filter")
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(events)))}, nil
+		}
+		return nil, nil
+	}
+	clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetTimeout(8))
+	t.Run("Endpoint history", func(t *testing.T) {
+		history, err := clt.GetEndpointHistory(context.Background(), "00:50:56:96:A0:D3")
+		ok(t, err)
+		equals(t, filter, `wcard(eventRecord.affected,"cep-00:50:56:96:A0:D3")`)
+		equals(t, len(history), 1)
+		equals(t, history[0].Descr, "ip 172.20.206.132 created")
+	})
+}
+
 func TestGetLatestEvents(t *testing.T) {
 	Client = &mocks.MockClient{}
 	login := `{
@@ -671,18 +1363,18 @@ func TestGetLatestEvents(t *testing.T) {
 	}
 	clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetTimeout(8))
 	t.Run("Get All Events", func(t *testing.T) {
-		fault, err := clt.GetLatestEvents("1")
+		fault, err := clt.GetLatestEvents(context.Background(), "1")
 		ok(t, err)
 		equals(t, len(fault), 1)
-		equals(t, fault[0]["dn"], "subj-[uni/tn-myTenant/ap-AP1/epg-EP1]/mod-4295233655")
-		equals(t, fault[0]["user"], "user1")
+		equals(t, fault[0].Dn, "subj-[uni/tn-myTenant/ap-AP1/epg-EP1]/mod-4295233655")
+		equals(t, fault[0].User, "user1")
 	})
 	t.Run("Get Events from User", func(t *testing.T) {
-		fault, err := clt.GetLatestEvents("1", "user1")
+		fault, err := clt.GetLatestEvents(context.Background(), "1", "user1")
 		ok(t, err)
 		equals(t, len(fault), 1)
-		equals(t, fault[0]["dn"], "subj-[uni/tn-myTenant/ap-AP1/epg-EP1]/mod-4295233655")
-		equals(t, fault[0]["user"], "user1")
+		equals(t, fault[0].Dn, "subj-[uni/tn-myTenant/ap-AP1/epg-EP1]/mod-4295233655")
+		equals(t, fault[0].User, "user1")
 	})
 }
 
@@ -732,12 +1424,19 @@ func TestSubscribeClassWebSocket(t *testing.T) {
 			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(login)))}, nil
 		} else if strings.Contains(req.URL.Path, "/api/class/fvTenant") {
 			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(subscription)))}, nil
+		} else if strings.Contains(req.URL.Path, "/api/mo/uni/tn-myTenant") && req.URL.Query().Get("query-target") == "subtree" {
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(subscription)))}, nil
 		}
 		return nil, nil
 	}
 	clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetTimeout(8))
 	t.Run("Subscribe to class", func(t *testing.T) {
-		subId, err := clt.SubscribeClassWebSocket("fvTenant")
+		subId, err := clt.SubscribeClassWebSocket(context.Background(), "fvTenant")
+		ok(t, err)
+		equals(t, subId, "72079567111979009")
+	})
+	t.Run("Subscribe to MO", func(t *testing.T) {
+		subId, err := clt.SubscribeMoWebSocket(context.Background(), "uni/tn-myTenant")
 		ok(t, err)
 		equals(t, subId, "72079567111979009")
 	})
@@ -772,7 +1471,51 @@ func TestRefreshSubscriptionWebSocket(t *testing.T) {
 	}
 	clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetTimeout(8))
 	t.Run("Refresh Subscription", func(t *testing.T) {
-		err := clt.RefreshSubscriptionWebSocket("fvTenant")
+		err := clt.RefreshSubscriptionWebSocket(context.Background(), "fvTenant")
+		ok(t, err)
+	})
+}
+
+func TestProxy(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Authorization") != "Basic "+base64.StdEncoding.EncodeToString([]byte("user:password")) {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		requests = append(requests, r.URL.String())
+		w.Write([]byte(`{"totalCount": "1", "imdata": [{"aaaLogin": {"attributes": {"token": "eyJhbGciOiJSUzI1NiIsImtpZCI6InJqcmRjazBuNW"}}}]}`))
+	}))
+	defer srv.Close()
+	defer func(c HttpClient) { Client = c }(Client)
+	Client = &http.Client{}
+
+	p, err := proxy.New(fmt.Sprintf("http://user:password@%s", srv.Listener.Addr()), "direct.example.com")
+	ok(t, err)
+	t.Run("Requests sent through the proxy", func(t *testing.T) {
+		clt, err := NewApicClient("http://apic.example.com", "admin", "admin", SetProxy(p))
+		ok(t, err)
+		equals(t, requests, []string{"http://apic.example.com/api/aaaLogin.json"})
+		req, _ := http.NewRequest(http.MethodGet, "https://apic.example.com/socket", nil)
+		u, err := clt.GetDialer().Proxy(req)
+		ok(t, err)
+		equals(t, u.Host, srv.Listener.Addr().String())
+	})
+	t.Run("Hosts excluded from the proxy", func(t *testing.T) {
+		requests = nil
+		_, err := NewApicClient("http://direct.example.com", "admin", "admin", SetProxy(p), SetRetries(0, 0))
+		notOk(t, err)
+		equals(t, len(requests), 0)
+	})
+	t.Run("Direct without a proxy", func(t *testing.T) {
+		apicSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"totalCount": "1", "imdata": [{"aaaLogin": {"attributes": {"token": "eyJhbGciOiJSUzI1NiIsImtpZCI6InJqcmRjazBuNW"}}}]}`))
+		}))
+		defer apicSrv.Close()
+		clt, err := NewApicClient(apicSrv.URL, "admin", "admin", SetProxy(nil))
 		ok(t, err)
+		// The HTTP_PROXY, HTTPS_PROXY and NO_PROXY variables are not used
+		equals(t, clt.httpClient.(*http.Client).Transport.(*http.Transport).Proxy == nil, true)
+		equals(t, clt.GetDialer().Proxy == nil, true)
 	})
 }
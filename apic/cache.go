@@ -0,0 +1,289 @@
+package apic
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIC client caching the results of the read methods for a TTL
+// Errors are not cached. Concurrent calls for the same result share a single request to the APIC
+// The cached results are shared by the callers and must not be modified
+// Writes are sent to the APIC and flush the cache. Other methods are passed through to the wrapped client
+type CachedClient struct {
+	ApicInterface
+	ttl      time.Duration
+	mu       sync.Mutex
+	entries  map[string]*cacheEntry
+	inflight map[string]*cacheCall
+	hits     uint64
+	misses   uint64
+}
+
+// Cached result and the classes it was built from
+type cacheEntry struct {
+	value   interface{}
+	classes []string
+	dn      string // DN of a DN query. The result is also invalidated by the events of its subtree
+	expires time.Time
+}
+
+// Request to the APIC shared by concurrent calls
+type cacheCall struct {
+	done  chan struct{} // Closed when the request is done
+	value interface{}
+	err   error
+}
+
+// Cache statistics. See Stats()
+type CacheStats struct {
+	Entries int
+	Hits    uint64
+	Misses  uint64
+}
+
+// Create a cache in front of an APIC client
+func NewCachedClient(c ApicInterface, ttl time.Duration) *CachedClient {
+	return &CachedClient{
+		ApicInterface: c,
+		ttl:           ttl,
+		entries:       make(map[string]*cacheEntry),
+		inflight:      make(map[string]*cacheCall),
+	}
+}
+
+// Get a result from the cache, or from the APIC on misses
+// The request to the APIC does not depend on the context of any caller, as it is shared by the concurrent calls
+// Each caller stops waiting for it when its own context is done
+func (cc *CachedClient) get(ctx context.Context, key string, classes []string, dn string, fetch func(context.Context) (interface{}, error)) (interface{}, error) {
+	// Results are cached per fabric
+	key = cc.GetIp() + " " + key
+	cc.mu.Lock()
+	if e, ok := cc.entries[key]; ok && time.Now().Before(e.expires) {
+		cc.hits++
+		cc.mu.Unlock()
+		return e.value, nil
+	}
+	cc.misses++
+	call, ok := cc.inflight[key]
+	if !ok {
+		call = &cacheCall{done: make(chan struct{})}
+		cc.inflight[key] = call
+		go cc.run(key, classes, dn, call, fetch)
+	}
+	cc.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		recordFailure(ctx)
+		return nil, ctx.Err()
+	}
+	if call.err != nil {
+		recordFailure(ctx)
+	}
+	return call.value, call.err
+}
+
+// Run a request shared by concurrent calls and cache its result
+func (cc *CachedClient) run(key string, classes []string, dn string, call *cacheCall, fetch func(context.Context) (interface{}, error)) {
+	call.value, call.err = fetch(context.Background())
+
+	cc.mu.Lock()
+	delete(cc.inflight, key)
+	if call.err == nil {
+		now := time.Now()
+		// Purge the expired results
+		for k, e := range cc.entries {
+			if !now.Before(e.expires) {
+				delete(cc.entries, k)
+			}
+		}
+		cc.entries[key] = &cacheEntry{value: call.value, classes: classes, dn: dn, expires: now.Add(cc.ttl)}
+	}
+	cc.mu.Unlock()
+	close(call.done)
+}
+
+// Drop all the results. Returns the number of results dropped
+func (cc *CachedClient) Flush() int {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	n := len(cc.entries)
+	cc.entries = make(map[string]*cacheEntry)
+	return n
+}
+
+// Drop the results built from a class, or from the subtree of a DN query including the DN of the event
+// Called for the events received over the APIC WebSocket. Returns the number of results dropped
+func (cc *CachedClient) Invalidate(class, dn string) int {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	n := 0
+	for k, e := range cc.entries {
+		if (e.dn != "" && strings.HasPrefix(dn, e.dn)) || stringInSlice(class, e.classes) {
+			delete(cc.entries, k)
+			n++
+		}
+	}
+	return n
+}
+
+// Get the number of cached results, hits and misses
+func (cc *CachedClient) Stats() CacheStats {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	n := 0
+	now := time.Now()
+	for _, e := range cc.entries {
+		if now.Before(e.expires) {
+			n++
+		}
+	}
+	return CacheStats{Entries: n, Hits: cc.hits, Misses: cc.misses}
+}
+
+// Classes read by the methods. A WebSocket event of any of them invalidates the result
+var (
+	fabricClasses    = []string{"aaaPreLoginBanner", "fabricPod", "fabricNode", "fabricOverallHealthHist5min"}
+	endpointClasses  = []string{"fvCEp", "fvIp", "fvRsCEpToPathEp"}
+	neighborClasses  = []string{"cdpAdjEp", "lldpAdjEp"}
+	nodeClasses      = []string{"fabricNode", "topSystem", "healthInst", "eqptSupC", "eqptFan", "eqptPsu", "faultInst"}
+	interfaceClasses = []string{"l1PhysIf", "ethpmPhysIf", "rmonEtherStats", "rmonIfIn", "rmonIfOut", "eqptIngrTotal5min", "eqptEgrTotal5min"}
+)
+
+func (cc *CachedClient) GetProcEntity(ctx context.Context) ([]ProcEntity, error) {
+	v, err := cc.get(ctx, "procEntity", []string{"procEntity"}, "", func(ctx context.Context) (interface{}, error) {
+		return cc.ApicInterface.GetProcEntity(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]ProcEntity), nil
+}
+
+func (cc *CachedClient) GetFabricInformation(ctx context.Context) (FabricInformation, error) {
+	v, err := cc.get(ctx, "fabric", fabricClasses, "", func(ctx context.Context) (interface{}, error) {
+		return cc.ApicInterface.GetFabricInformation(ctx)
+	})
+	if err != nil {
+		return FabricInformation{}, err
+	}
+	return v.(FabricInformation), nil
+}
+
+func (cc *CachedClient) GetEndpointInformation(ctx context.Context, m string) ([]EndpointInformation, error) {
+	v, err := cc.get(ctx, "endpoint "+m, endpointClasses, "", func(ctx context.Context) (interface{}, error) {
+		return cc.ApicInterface.GetEndpointInformation(ctx, m)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]EndpointInformation), nil
+}
+
+func (cc *CachedClient) GetEndpointHistory(ctx context.Context, m string) ([]EventRecord, error) {
+	v, err := cc.get(ctx, "endpointHistory "+m, []string{"eventRecord"}, "", func(ctx context.Context) (interface{}, error) {
+		return cc.ApicInterface.GetEndpointHistory(ctx, m)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]EventRecord), nil
+}
+
+func (cc *CachedClient) GetFabricNeighbors(ctx context.Context, nd string) (map[string][]string, error) {
+	v, err := cc.get(ctx, "neighbors "+nd, neighborClasses, "", func(ctx context.Context) (interface{}, error) {
+		return cc.ApicInterface.GetFabricNeighbors(ctx, nd)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(map[string][]string), nil
+}
+
+func (cc *CachedClient) GetNodeInformation(ctx context.Context, nd string) (NodeInformation, error) {
+	v, err := cc.get(ctx, "node "+nd, nodeClasses, "", func(ctx context.Context) (interface{}, error) {
+		return cc.ApicInterface.GetNodeInformation(ctx, nd)
+	})
+	if err != nil {
+		return NodeInformation{}, err
+	}
+	return v.(NodeInformation), nil
+}
+
+func (cc *CachedClient) GetInterfaceInformation(ctx context.Context, nd string, iface string) ([]InterfaceInformation, error) {
+	v, err := cc.get(ctx, fmt.Sprintf("interface %s %s", nd, iface), interfaceClasses, "", func(ctx context.Context) (interface{}, error) {
+		return cc.ApicInterface.GetInterfaceInformation(ctx, nd, iface)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]InterfaceInformation), nil
+}
+
+func (cc *CachedClient) GetTopErrorInterfaces(ctx context.Context, c string) ([]InterfaceInformation, error) {
+	v, err := cc.get(ctx, "topErrorInterfaces "+c, interfaceClasses, "", func(ctx context.Context) (interface{}, error) {
+		return cc.ApicInterface.GetTopErrorInterfaces(ctx, c)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]InterfaceInformation), nil
+}
+
+func (cc *CachedClient) GetHealthHistory(ctx context.Context, g string) (HealthHistory, error) {
+	classes := []string{"fabricOverallHealthHist" + g, "fabricHealthTotalHist" + g, "fvOverallHealthHist" + g, "fabricNodeHealthHist" + g}
+	v, err := cc.get(ctx, "healthHistory "+g, classes, "", func(ctx context.Context) (interface{}, error) {
+		return cc.ApicInterface.GetHealthHistory(ctx, g)
+	})
+	if err != nil {
+		return HealthHistory{}, err
+	}
+	return v.(HealthHistory), nil
+}
+
+func (cc *CachedClient) GetLatestFaults(ctx context.Context, c string) ([]FaultInst, error) {
+	v, err := cc.get(ctx, "faults "+c, []string{"faultInst"}, "", func(ctx context.Context) (interface{}, error) {
+		return cc.ApicInterface.GetLatestFaults(ctx, c)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]FaultInst), nil
+}
+
+func (cc *CachedClient) GetLatestEvents(ctx context.Context, c string, usr ...string) ([]AaaModLR, error) {
+	v, err := cc.get(ctx, fmt.Sprintf("events %s %s", c, strings.Join(usr, ",")), []string{"aaaModLR"}, "", func(ctx context.Context) (interface{}, error) {
+		return cc.ApicInterface.GetLatestEvents(ctx, c, usr...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]AaaModLR), nil
+}
+
+// Class queries are invalidated by the events of their class. DN queries by the events of their subtree
+func (cc *CachedClient) Query(ctx context.Context, q *Query) (QueryResult, error) {
+	var classes []string
+	if q.Class() != "" {
+		classes = []string{q.Class()}
+	}
+	v, err := cc.get(ctx, "query "+q.String(), classes, q.Dn(), func(ctx context.Context) (interface{}, error) {
+		return cc.ApicInterface.Query(ctx, q)
+	})
+	if err != nil {
+		return QueryResult{}, err
+	}
+	return v.(QueryResult), nil
+}
+
+func (cc *CachedClient) PostMo(ctx context.Context, dn string, payload []byte) error {
+	if err := cc.ApicInterface.PostMo(ctx, dn, payload); err != nil {
+		return err
+	}
+	cc.Flush()
+	return nil
+}
@@ -0,0 +1,125 @@
+package apic
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCachedClient(t *testing.T) {
+	mc := ApicClientMocks{}
+	mc.SetDefaultFunctions()
+	var calls int32
+	neighbors := mc.GetFabricNeighborsF
+	mc.GetFabricNeighborsF = func(ctx context.Context, nd string) (map[string][]string, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(5 * time.Millisecond)
+		return neighbors(ctx, nd)
+	}
+	query := mc.QueryF
+	mc.QueryF = func(ctx context.Context, q *Query) (QueryResult, error) {
+		atomic.AddInt32(&calls, 1)
+		return query(ctx, q)
+	}
+
+	t.Run("Hits and misses", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		cc := NewCachedClient(&mc, time.Minute)
+		first, err := cc.GetFabricNeighbors(context.Background(), "all")
+		ok(t, err)
+		second, err := cc.GetFabricNeighbors(context.Background(), "all")
+		ok(t, err)
+		equals(t, first, second)
+		_, err = cc.GetFabricNeighbors(context.Background(), "101")
+		ok(t, err)
+		equals(t, atomic.LoadInt32(&calls), int32(2))
+		equals(t, cc.Stats(), CacheStats{Entries: 2, Hits: 1, Misses: 2})
+	})
+	t.Run("Concurrent calls share a request", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		cc := NewCachedClient(&mc, time.Minute)
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				cc.GetFabricNeighbors(context.Background(), "all")
+			}()
+		}
+		wg.Wait()
+		equals(t, atomic.LoadInt32(&calls), int32(1))
+	})
+	t.Run("Expired results", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		cc := NewCachedClient(&mc, time.Millisecond)
+		cc.GetFabricNeighbors(context.Background(), "all")
+		time.Sleep(2 * time.Millisecond)
+		cc.GetFabricNeighbors(context.Background(), "all")
+		equals(t, atomic.LoadInt32(&calls), int32(2))
+		time.Sleep(2 * time.Millisecond)
+		equals(t, cc.Stats().Entries, 0)
+	})
+	t.Run("Expired results are purged", func(t *testing.T) {
+		cc := NewCachedClient(&mc, time.Millisecond)
+		cc.GetFabricNeighbors(context.Background(), "all")
+		time.Sleep(2 * time.Millisecond)
+		cc.GetFabricNeighbors(context.Background(), "101")
+		cc.mu.Lock()
+		defer cc.mu.Unlock()
+		equals(t, len(cc.entries), 1)
+	})
+	t.Run("Callers stop waiting on their context", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		cc := NewCachedClient(&mc, time.Minute)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := cc.GetFabricNeighbors(ctx, "all")
+		equals(t, err, context.Canceled)
+		// The shared request is not canceled with the first caller
+		neighbors, err := cc.GetFabricNeighbors(context.Background(), "all")
+		ok(t, err)
+		equals(t, len(neighbors) > 0, true)
+		equals(t, atomic.LoadInt32(&calls), int32(1))
+	})
+	t.Run("Errors are not cached", func(t *testing.T) {
+		failing := mc
+		failing.GetLatestFaultsF = func(ctx context.Context, c string) ([]FaultInst, error) {
+			atomic.AddInt32(&calls, 1)
+			return nil, errors.New("timeout")
+		}
+		atomic.StoreInt32(&calls, 0)
+		cc := NewCachedClient(&failing, time.Minute)
+		_, err := cc.GetLatestFaults(context.Background(), "10")
+		notOk(t, err)
+		_, err = cc.GetLatestFaults(context.Background(), "10")
+		notOk(t, err)
+		equals(t, atomic.LoadInt32(&calls), int32(2))
+	})
+	t.Run("Invalidated by events", func(t *testing.T) {
+		cc := NewCachedClient(&mc, time.Minute)
+		cc.GetFabricNeighbors(context.Background(), "all")
+		cc.Query(context.Background(), ClassQuery("fvBD"))
+		cc.Query(context.Background(), DnQuery("uni/tn-myTenant").Target("subtree"))
+		equals(t, cc.Invalidate("fvCtx", "uni/tn-other/ctx-myVrf"), 0)
+		equals(t, cc.Invalidate("lldpAdjEp", "topology/pod-1/node-101/sys/lldp/inst/if-[eth1/1]/adj-1"), 1)
+		equals(t, cc.Invalidate("fvBD", "uni/tn-other/BD-myBD"), 1)
+		equals(t, cc.Invalidate("fvAEPg", "uni/tn-myTenant/ap-myApp/epg-myEPG"), 1)
+		equals(t, cc.Stats().Entries, 0)
+	})
+	t.Run("Flushed by writes", func(t *testing.T) {
+		cc := NewCachedClient(&mc, time.Minute)
+		cc.GetFabricNeighbors(context.Background(), "all")
+		cc.GetFabricNeighbors(context.Background(), "101")
+		ok(t, cc.PostMo(context.Background(), "uni", []byte(`{}`)))
+		equals(t, cc.Stats().Entries, 0)
+		cc.GetFabricNeighbors(context.Background(), "all")
+		equals(t, cc.Flush(), 1)
+	})
+	t.Run("Other methods are passed through", func(t *testing.T) {
+		cc := NewCachedClient(&mc, time.Minute)
+		equals(t, cc.GetIp(), "1.2.3.4")
+	})
+}
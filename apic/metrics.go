@@ -0,0 +1,65 @@
+package apic
+
+import (
+	"aci-chatbot/metrics"
+	"context"
+	"net/url"
+	"strings"
+	"sync/atomic"
+)
+
+// Metrics of the calls to the APIC API. See metrics.Handler()
+var (
+	requestDuration = metrics.NewHistogram("aci_chatbot_apic_request_duration_seconds", "Duration of the APIC API calls, retries included, by class", metrics.DefBuckets, "class")
+	requestErrors   = metrics.NewCounter("aci_chatbot_apic_request_errors_total", "APIC API calls failed, by class", "class")
+)
+
+// Classes and calls used as metric labels. The classes queried with /query or subscribed to with /websocket are
+// labeled other, so that they do not create a series each
+var metricClasses = func() map[string]bool {
+	m := map[string]bool{"mo": true, "aaaLogin": true, "subscriptionRefresh": true, "aaaModLR": true, "eventRecord": true, "procEntity": true}
+	for _, classes := range [][]string{fabricClasses, endpointClasses, neighborClasses, nodeClasses, interfaceClasses} {
+		for _, c := range classes {
+			m[c] = true
+		}
+	}
+	for _, g := range []string{"15min", "1h", "1d"} {
+		for _, c := range []string{"fabricOverallHealthHist", "fabricHealthTotalHist", "fvOverallHealthHist", "fabricNodeHealthHist"} {
+			m[c+g] = true
+		}
+	}
+	return m
+}()
+
+// Class of a request used as metric label. MO queries are labeled mo, other calls by their name (e.g. aaaLogin)
+// Classes not queried by the methods of the client are labeled other. See metricClasses
+func requestClass(u *url.URL) string {
+	p := strings.TrimSuffix(strings.TrimPrefix(u.Path, "/api/"), ".json")
+	if strings.HasPrefix(p, "mo/") || strings.HasPrefix(p, "node/mo/") {
+		return "mo"
+	}
+	if c := p[strings.LastIndex(p, "/")+1:]; metricClasses[c] {
+		return c
+	}
+	return "other"
+}
+
+type failuresKey struct{}
+
+// Record the failed APIC calls made with the context. See Failed()
+// Used to tell the commands which could not get their data from the APIC
+func TrackFailures(ctx context.Context) context.Context {
+	return context.WithValue(ctx, failuresKey{}, new(int32))
+}
+
+// Whether an APIC call made with a context returned by TrackFailures() failed
+func Failed(ctx context.Context) bool {
+	f, ok := ctx.Value(failuresKey{}).(*int32)
+	return ok && atomic.LoadInt32(f) > 0
+}
+
+func recordFailure(ctx context.Context) {
+	if f, ok := ctx.Value(failuresKey{}).(*int32); ok {
+		atomic.AddInt32(f, 1)
+	}
+}
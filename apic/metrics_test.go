@@ -0,0 +1,48 @@
+package apic
+
+import (
+	"aci-chatbot/metrics"
+	"aci-chatbot/mocks"
+	"bytes"
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Run("Request classes", func(t *testing.T) {
+		for path, class := range map[string]string{
+			"/api/aaaLogin.json":                           "aaaLogin",
+			"/api/node/class/faultInst.json":               "faultInst",
+			"/api/node/class/topology/pod-1/l1PhysIf.json": "l1PhysIf",
+			"/api/class/fvBD.json":                         "other",
+			"/api/node/class/fabricNodeHealthHist1h.json":  "fabricNodeHealthHist1h",
+			"/api/node/mo/uni/tn-myTenant.json":            "mo",
+			"/api/mo/topology/pod-1/node-101/sys.json":     "mo",
+			"/api/subscriptionRefresh.json":                "subscriptionRefresh",
+		} {
+			equals(t, requestClass(&url.URL{Path: path}), class)
+		}
+	})
+	t.Run("Failed calls recorded", func(t *testing.T) {
+		Client = &mocks.MockClient{}
+		mockStatuses(200)
+		clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetRetries(0, time.Millisecond))
+		ctx := TrackFailures(context.Background())
+		_, err := clt.GetProcEntity(ctx)
+		ok(t, err)
+		equals(t, Failed(ctx), false)
+		mockStatuses(400)
+		_, err = clt.GetProcEntity(ctx)
+		notOk(t, err)
+		equals(t, Failed(ctx), true)
+		equals(t, Failed(context.Background()), false)
+
+		var b bytes.Buffer
+		metrics.WriteTo(&b)
+		equals(t, strings.Contains(b.String(), "\naci_chatbot_apic_request_errors_total{class=\"procEntity\"} "), true)
+		equals(t, strings.Contains(b.String(), "\naci_chatbot_apic_request_duration_seconds_count{class=\"aaaLogin\"} "), true)
+	})
+}
@@ -0,0 +1,373 @@
+package apic
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// MO of a known class. See UnmarshalMos()
+// The fields tagged with apic:"<attribute>" are set from the attributes of the MO
+// The slices of typed MOs are set from the children of their class
+type TypedMo interface {
+	MoClass() string
+}
+
+var typedMoType = reflect.TypeOf((*TypedMo)(nil)).Elem()
+
+// Decode the MOs of a query into a slice of typed MOs, e.g. *[]FaultInst
+// MOs of other classes are skipped. Missing attributes are left empty
+func UnmarshalMos(mos []ApicMo, out interface{}) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("cannot decode MOs into %T. A pointer to a slice is required", out)
+	}
+	s := v.Elem()
+	class, ok := moClass(s.Type().Elem())
+	if !ok {
+		return fmt.Errorf("cannot decode MOs into %T. %s is not a typed MO", out, s.Type().Elem())
+	}
+	return unmarshalClass(mos, class, s)
+}
+
+// Decode the MOs of a class into a slice of tagged structs shared by several classes, e.g. *[]HealthHist
+func unmarshalRecords(mos []ApicMo, class string, out interface{}) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice || v.Elem().Type().Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cannot decode MOs into %T. A pointer to a slice of structs is required", out)
+	}
+	return unmarshalClass(mos, class, v.Elem())
+}
+
+func unmarshalClass(mos []ApicMo, class string, s reflect.Value) error {
+	for _, mo := range mos {
+		if mo.Class != class {
+			continue
+		}
+		e := reflect.New(s.Type().Elem()).Elem()
+		if err := decodeMo(mo, e); err != nil {
+			return err
+		}
+		s.Set(reflect.Append(s, e))
+	}
+	return nil
+}
+
+// Encode a slice of typed MOs, e.g. []FaultInst, into their tagged attributes. See UnmarshalMos()
+// The children of the MOs are not encoded
+func MarshalMos(mos interface{}) ([]ApicMoAttributes, error) {
+	s := reflect.ValueOf(mos)
+	if s.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("cannot encode %T. A slice of typed MOs is required", mos)
+	}
+	if _, ok := moClass(s.Type().Elem()); !ok {
+		return nil, fmt.Errorf("cannot encode %T. %s is not a typed MO", mos, s.Type().Elem())
+	}
+	attributes := []ApicMoAttributes{}
+	for i := 0; i < s.Len(); i++ {
+		mo := s.Index(i)
+		a := ApicMoAttributes{}
+		for j := 0; j < mo.NumField(); j++ {
+			if name, ok := mo.Type().Field(j).Tag.Lookup("apic"); ok && mo.Field(j).Kind() == reflect.String {
+				a[name] = mo.Field(j).String()
+			}
+		}
+		attributes = append(attributes, a)
+	}
+	return attributes, nil
+}
+
+// Class of a typed MO struct
+func moClass(t reflect.Type) (string, bool) {
+	if t.Kind() != reflect.Struct || !t.Implements(typedMoType) {
+		return "", false
+	}
+	return reflect.Zero(t).Interface().(TypedMo).MoClass(), true
+}
+
+func decodeMo(mo ApicMo, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if name, ok := f.Tag.Lookup("apic"); ok {
+			if f.Type.Kind() != reflect.String {
+				return fmt.Errorf("cannot decode the attribute %s of %s. Attributes must be strings", name, mo.Class)
+			}
+			v.Field(i).SetString(mo.Attributes[name])
+			continue
+		}
+		if f.Type.Kind() != reflect.Slice {
+			continue
+		}
+		if _, ok := moClass(f.Type.Elem()); ok {
+			if err := UnmarshalMos(mo.Children, v.Field(i).Addr().Interface()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Decode the MOs of a result into a slice of typed MOs. See UnmarshalMos()
+func unmarshalResult(p map[string]interface{}, out interface{}) error {
+	r, err := getQueryResult(p)
+	if err != nil {
+		return err
+	}
+	return UnmarshalMos(r.Mos, out)
+}
+
+type AaaLogin struct {
+	Token                 string `apic:"token"`
+	RefreshTimeoutSeconds string `apic:"refreshTimeoutSeconds"`
+}
+
+func (AaaLogin) MoClass() string { return "aaaLogin" }
+
+type AaaPreLoginBanner struct {
+	Dn             string `apic:"dn"`
+	GuiTextMessage string `apic:"guiTextMessage"`
+}
+
+func (AaaPreLoginBanner) MoClass() string { return "aaaPreLoginBanner" }
+
+// Fault
+type FaultInst struct {
+	Dn             string `apic:"dn"`
+	Code           string `apic:"code"`
+	Severity       string `apic:"severity"`
+	Lc             string `apic:"lc"`
+	Ack            string `apic:"ack"`
+	Cause          string `apic:"cause"`
+	Descr          string `apic:"descr"`
+	Domain         string `apic:"domain"`
+	Subject        string `apic:"subject"`
+	Type           string `apic:"type"`
+	Created        string `apic:"created"`
+	LastTransition string `apic:"lastTransition"`
+}
+
+func (FaultInst) MoClass() string { return "faultInst" }
+
+// Health history record. Shared by the classes of every granularity, e.g. fabricOverallHealthHist1h
+// The record with index 0 is the most recent one
+type HealthHist struct {
+	Dn        string `apic:"dn"`
+	Index     string `apic:"index"`
+	HealthAvg string `apic:"healthAvg"`
+	HealthMin string `apic:"healthMin"`
+	HealthMax string `apic:"healthMax"`
+}
+
+// Audit log record
+type AaaModLR struct {
+	Dn       string `apic:"dn"`
+	Code     string `apic:"code"`
+	User     string `apic:"user"`
+	Ind      string `apic:"ind"`
+	Affected string `apic:"affected"`
+	Descr    string `apic:"descr"`
+	Cause    string `apic:"cause"`
+	Trig     string `apic:"trig"`
+	Created  string `apic:"created"`
+}
+
+func (AaaModLR) MoClass() string { return "aaaModLR" }
+
+// Event record
+type EventRecord struct {
+	Dn       string `apic:"dn"`
+	Code     string `apic:"code"`
+	Affected string `apic:"affected"`
+	Descr    string `apic:"descr"`
+	Cause    string `apic:"cause"`
+	Severity string `apic:"severity"`
+	Created  string `apic:"created"`
+}
+
+func (EventRecord) MoClass() string { return "eventRecord" }
+
+// Endpoint. Ips and Paths are only set if the children are requested
+type FvCEp struct {
+	Dn    string `apic:"dn"`
+	Mac   string `apic:"mac"`
+	Ip    string `apic:"ip"`
+	Encap string `apic:"encap"`
+	Name  string `apic:"name"`
+	Ips   []FvIp
+	Paths []FvRsCEpToPathEp
+}
+
+func (FvCEp) MoClass() string { return "fvCEp" }
+
+type FvIp struct {
+	Dn   string `apic:"dn"`
+	Addr string `apic:"addr"`
+}
+
+func (FvIp) MoClass() string { return "fvIp" }
+
+// Path (interface) an endpoint is learnt on
+type FvRsCEpToPathEp struct {
+	Dn  string `apic:"dn"`
+	TDn string `apic:"tDn"`
+}
+
+func (FvRsCEpToPathEp) MoClass() string { return "fvRsCEpToPathEp" }
+
+type FabricPod struct {
+	Dn      string `apic:"dn"`
+	Id      string `apic:"id"`
+	PodType string `apic:"podType"`
+}
+
+func (FabricPod) MoClass() string { return "fabricPod" }
+
+type FabricNode struct {
+	Dn       string `apic:"dn"`
+	Id       string `apic:"id"`
+	Name     string `apic:"name"`
+	Role     string `apic:"role"`
+	Model    string `apic:"model"`
+	Serial   string `apic:"serial"`
+	Address  string `apic:"address"`
+	FabricSt string `apic:"fabricSt"`
+	Version  string `apic:"version"`
+}
+
+func (FabricNode) MoClass() string { return "fabricNode" }
+
+type TopSystem struct {
+	Dn           string `apic:"dn"`
+	Id           string `apic:"id"`
+	Name         string `apic:"name"`
+	Role         string `apic:"role"`
+	PodId        string `apic:"podId"`
+	Address      string `apic:"address"`
+	SystemUpTime string `apic:"systemUpTime"`
+}
+
+func (TopSystem) MoClass() string { return "topSystem" }
+
+type HealthInst struct {
+	Dn  string `apic:"dn"`
+	Cur string `apic:"cur"`
+}
+
+func (HealthInst) MoClass() string { return "healthInst" }
+
+// Supervisor of a node
+type EqptSupC struct {
+	Dn     string `apic:"dn"`
+	Model  string `apic:"model"`
+	OperSt string `apic:"operSt"`
+}
+
+func (EqptSupC) MoClass() string { return "eqptSupC" }
+
+type EqptFan struct {
+	Dn     string `apic:"dn"`
+	Id     string `apic:"id"`
+	OperSt string `apic:"operSt"`
+}
+
+func (EqptFan) MoClass() string { return "eqptFan" }
+
+type EqptPsu struct {
+	Dn     string `apic:"dn"`
+	Model  string `apic:"model"`
+	OperSt string `apic:"operSt"`
+}
+
+func (EqptPsu) MoClass() string { return "eqptPsu" }
+
+// CPU and memory usage of an APIC
+type ProcEntity struct {
+	Dn          string `apic:"dn"`
+	CpuPct      string `apic:"cpuPct"`
+	MemFree     string `apic:"memFree"`
+	MaxMemAlloc string `apic:"maxMemAlloc"`
+}
+
+func (ProcEntity) MoClass() string { return "procEntity" }
+
+// LLDP neighbor
+type LldpAdjEp struct {
+	Dn      string `apic:"dn"`
+	SysName string `apic:"sysName"`
+	PortIdV string `apic:"portIdV"`
+	MgmtIp  string `apic:"mgmtIp"`
+	SysDesc string `apic:"sysDesc"`
+}
+
+func (LldpAdjEp) MoClass() string { return "lldpAdjEp" }
+
+// CDP neighbor
+type CdpAdjEp struct {
+	Dn       string `apic:"dn"`
+	SysName  string `apic:"sysName"`
+	DevId    string `apic:"devId"`
+	PortId   string `apic:"portId"`
+	Platform string `apic:"platId"`
+}
+
+func (CdpAdjEp) MoClass() string { return "cdpAdjEp" }
+
+// Physical interface
+type L1PhysIf struct {
+	Dn      string `apic:"dn"`
+	Id      string `apic:"id"`
+	AdminSt string `apic:"adminSt"`
+	Speed   string `apic:"speed"`
+}
+
+func (L1PhysIf) MoClass() string { return "l1PhysIf" }
+
+// Operational state of a physical interface
+type EthpmPhysIf struct {
+	Dn            string `apic:"dn"`
+	OperSt        string `apic:"operSt"`
+	OperSpeed     string `apic:"operSpeed"`
+	LastLinkStChg string `apic:"lastLinkStChg"`
+}
+
+func (EthpmPhysIf) MoClass() string { return "ethpmPhysIf" }
+
+// Ethernet counters of an interface
+type RmonEtherStats struct {
+	Dn             string `apic:"dn"`
+	CRCAlignErrors string `apic:"cRCAlignErrors"`
+}
+
+func (RmonEtherStats) MoClass() string { return "rmonEtherStats" }
+
+// Input counters of an interface
+type RmonIfIn struct {
+	Dn     string `apic:"dn"`
+	Errors string `apic:"errors"`
+}
+
+func (RmonIfIn) MoClass() string { return "rmonIfIn" }
+
+// Output counters of an interface
+type RmonIfOut struct {
+	Dn     string `apic:"dn"`
+	Errors string `apic:"errors"`
+}
+
+func (RmonIfOut) MoClass() string { return "rmonIfOut" }
+
+// Input utilization of an interface over the last 5 minutes
+type EqptIngrTotal5min struct {
+	Dn      string `apic:"dn"`
+	UtilAvg string `apic:"utilAvg"`
+}
+
+func (EqptIngrTotal5min) MoClass() string { return "eqptIngrTotal5min" }
+
+// Output utilization of an interface over the last 5 minutes
+type EqptEgrTotal5min struct {
+	Dn      string `apic:"dn"`
+	UtilAvg string `apic:"utilAvg"`
+}
+
+func (EqptEgrTotal5min) MoClass() string { return "eqptEgrTotal5min" }
@@ -0,0 +1,134 @@
+package apic
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// Decode a fixture of testdata/mos into typed MOs
+func unmarshalFixture(tb testing.TB, name string, out interface{}) error {
+	tb.Helper()
+	body, err := ioutil.ReadFile(filepath.Join("testdata", "mos", name+".json"))
+	ok(tb, err)
+	var p map[string]interface{}
+	ok(tb, json.Unmarshal(body, &p))
+	return unmarshalResult(p, out)
+}
+
+func TestUnmarshalMos(t *testing.T) {
+	t.Run("Faults", func(t *testing.T) {
+		var faults []FaultInst
+		ok(t, unmarshalFixture(t, "faultInst", &faults))
+		equals(t, len(faults), 2)
+		equals(t, faults[0], FaultInst{
+			Dn:             "uni/tn-tenant/cif-CON_IFACE/rsif/fault-F1123",
+			Code:           "F1123",
+			Severity:       "warning",
+			Lc:             "raised",
+			Ack:            "no",
+			Cause:          "resolution-failed",
+			Descr:          "Failed to form relation to MO uni/tn-common/cif-CON_IFACE",
+			Domain:         "tenant",
+			Subject:        "relation-resolution",
+			Type:           "config",
+			Created:        "2021-09-01T10:00:00.000+00:00",
+			LastTransition: "2021-09-01T10:02:00.000+00:00",
+		})
+		// Missing attributes are empty
+		equals(t, faults[1], FaultInst{Dn: "topology/pod-1/node-101/sys/phys-[eth1/1]/phys/fault-F0532", Code: "F0532", Severity: "cleared"})
+	})
+	t.Run("Endpoint with children", func(t *testing.T) {
+		var eps []FvCEp
+		ok(t, unmarshalFixture(t, "fvCEp", &eps))
+		equals(t, eps, []FvCEp{{
+			Dn:    "uni/tn-myTenant/ap-myApp/epg-myEPG/cep-AA:AA:AA:BB:BB:CC",
+			Mac:   "AA:AA:AA:BB:BB:CC",
+			Ip:    "192.168.1.1",
+			Encap: "vlan-100",
+			Name:  "AA:AA:AA:BB:BB:CC",
+			Ips:   []FvIp{{Addr: "192.168.1.1"}, {Addr: "192.168.1.2"}},
+			Paths: []FvRsCEpToPathEp{{TDn: "topology/pod-1/paths-101/pathep-[eth1/1]"}},
+		}})
+	})
+	t.Run("Nodes", func(t *testing.T) {
+		var nodes []FabricNode
+		ok(t, unmarshalFixture(t, "fabricNode", &nodes))
+		equals(t, nodes, []FabricNode{
+			{Dn: "topology/pod-1/node-101", Id: "101", Name: "leaf-101", Role: "leaf", Model: "N9K-C9396PX", Serial: "TEP-1-101", Address: "10.0.72.64", FabricSt: "active", Version: "n9000-15.2(1g)"},
+			{Dn: "topology/pod-1/node-1", Id: "1", Name: "apic1", Role: "controller", Version: "5.2(1g)"},
+		})
+	})
+	t.Run("Audit log", func(t *testing.T) {
+		var records []AaaModLR
+		ok(t, unmarshalFixture(t, "aaaModLR", &records))
+		equals(t, records, []AaaModLR{{
+			Dn:       "subj-[uni/tn-myTenant]/mod-4294967297",
+			User:     "admin",
+			Ind:      "creation",
+			Affected: "uni/tn-myTenant",
+			Descr:    "Tenant myTenant created",
+			Cause:    "transition",
+			Trig:     "config",
+			Created:  "2021-09-07T13:20:13.645+01:00",
+		}})
+	})
+	t.Run("Processes", func(t *testing.T) {
+		var procs []ProcEntity
+		ok(t, unmarshalFixture(t, "procEntity", &procs))
+		equals(t, procs, []ProcEntity{{Dn: "topology/pod-1/node-1/sys/proc", CpuPct: "12", MemFree: "4150140", MaxMemAlloc: "32801112"}})
+	})
+	t.Run("Other classes are skipped", func(t *testing.T) {
+		var neighs []LldpAdjEp
+		ok(t, unmarshalFixture(t, "lldpAdjEp", &neighs))
+		equals(t, neighs, []LldpAdjEp{{Dn: "topology/pod-1/node-101/sys/lldp/inst/if-[eth1/49]/adj-1", SysName: "spine-201", PortIdV: "Eth1/1", MgmtIp: "10.0.0.201", SysDesc: "topology/pod-1/node-201"}})
+	})
+	t.Run("Unexpected responses", func(t *testing.T) {
+		for _, name := range []string{"imdata_object", "attributes_list", "attribute_number", "children_object", "mo_string"} {
+			var eps []FvCEp
+			if err := unmarshalFixture(t, name, &eps); err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+		}
+	})
+	t.Run("Records of the class of the query", func(t *testing.T) {
+		body, err := ioutil.ReadFile(filepath.Join("testdata", "mos", "fabricNodeHealthHist1h.json"))
+		ok(t, err)
+		var p map[string]interface{}
+		ok(t, json.Unmarshal(body, &p))
+		r, err := getQueryResult(p)
+		ok(t, err)
+		var records []HealthHist
+		ok(t, unmarshalRecords(r.Mos, "fabricNodeHealthHist1h", &records))
+		equals(t, records, []HealthHist{{Dn: "topology/pod-1/node-101/HDfabricNodeHealth1h-0", Index: "0", HealthAvg: "98", HealthMin: "95", HealthMax: "100"}})
+		notOk(t, unmarshalRecords(r.Mos, "fabricNodeHealthHist1h", records))
+		var attributes []ApicMoAttributes
+		notOk(t, unmarshalRecords(r.Mos, "fabricNodeHealthHist1h", &attributes))
+	})
+	t.Run("Invalid output", func(t *testing.T) {
+		var nodes []FabricNode
+		notOk(t, UnmarshalMos(nil, nodes))
+		var attributes []ApicMoAttributes
+		notOk(t, UnmarshalMos(nil, &attributes))
+		var invalid []struct {
+			FabricNode
+			Id int `apic:"id"`
+		}
+		notOk(t, UnmarshalMos([]ApicMo{{Class: "fabricNode"}}, &invalid))
+	})
+}
+
+func TestMarshalMos(t *testing.T) {
+	t.Run("Tagged attributes", func(t *testing.T) {
+		attributes, err := MarshalMos([]ProcEntity{{Dn: "topology/pod-1/node-1/sys/proc", CpuPct: "12"}})
+		ok(t, err)
+		equals(t, attributes, []ApicMoAttributes{{"dn": "topology/pod-1/node-1/sys/proc", "cpuPct": "12", "memFree": "", "maxMemAlloc": ""}})
+	})
+	t.Run("Not typed MOs", func(t *testing.T) {
+		_, err := MarshalMos([]ApicMoAttributes{{"dn": "uni"}})
+		notOk(t, err)
+		_, err = MarshalMos(ProcEntity{})
+		notOk(t, err)
+	})
+}
@@ -0,0 +1,185 @@
+package apic
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// Paging of the class queries. See reqApicMos()
+const (
+	defaultPageSize  = 1000
+	defaultPageFetch = 4
+)
+
+// Number of MOs per page of the class queries
+func SetPageSize(n int) Option {
+	return func(client *ApicClient) {
+		if n > 0 {
+			client.pageSize = n
+		}
+	}
+}
+
+// Number of pages of a class query fetched in parallel
+func SetPageConcurrency(n int) Option {
+	return func(client *ApicClient) {
+		if n > 0 {
+			client.pageFetch = n
+		}
+	}
+}
+
+// Get the raw result of a query
+func (client *ApicClient) getPage(ctx context.Context, q *Query) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	req, err := client.makeCall(ctx, http.MethodGet, q.String(), nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = client.doCall(req, &result); err != nil {
+		log.Println("Error: ", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// Get all the pages of a query, in order
+// The first page gives the totalCount. The other pages are fetched in parallel, pageFetch at a time
+// Queries setting their page or page size only get that page
+func (client *ApicClient) getPages(ctx context.Context, q *Query) ([]map[string]interface{}, error) {
+	if q.GetParam("page") != "" || q.GetParam("page-size") != "" {
+		p, err := client.getPage(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		return []map[string]interface{}{p}, nil
+	}
+	size := client.pageSize
+	first, err := client.getPage(ctx, q.Clone().Page(0).PageSize(size))
+	if err != nil {
+		return nil, err
+	}
+	count, _ := first["totalCount"].(string)
+	total, _ := strconv.Atoi(count)
+	n := (total + size - 1) / size
+	if n <= 1 {
+		return []map[string]interface{}{first}, nil
+	}
+	pages := make([]map[string]interface{}, n)
+	pages[0] = first
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	sem := make(chan struct{}, client.pageFetch)
+	for i := 1; i < len(pages) && ctx.Err() == nil; i++ {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			p, err := client.getPage(ctx, q.Clone().Page(i).PageSize(size))
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+				// The result is incomplete. Stop fetching the other pages
+				cancel()
+				return
+			}
+			pages[i] = p
+		}(i)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
+
+// Iterator over the MOs of a query. The MOs are fetched one page at a time, so huge classes are not loaded at once
+//
+//	it := NewMoIterator(c, ClassQuery("fvCEp"), 500)
+//	for it.Next(ctx) {
+//		mo := it.Mo()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type MoIterator struct {
+	c     ApicInterface
+	q     *Query
+	size  int
+	page  int // Next page to fetch
+	mos   []ApicMo
+	i     int
+	total int
+	done  bool
+	err   error
+}
+
+// Create an iterator over the MOs of a query. Its page and page size are replaced
+func NewMoIterator(c ApicInterface, q *Query, size int) *MoIterator {
+	if size < 1 {
+		size = defaultPageSize
+	}
+	return &MoIterator{c: c, q: q.Clone(), size: size, i: -1}
+}
+
+// Move to the next MO. The next page is fetched once the MOs of the current page are consumed
+// Returns false when there are no more MOs or on errors. See Err()
+func (it *MoIterator) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	if it.i+1 < len(it.mos) {
+		it.i++
+		return true
+	}
+	if it.done {
+		return false
+	}
+	r, err := it.c.Query(ctx, it.q.Clone().Page(it.page).PageSize(it.size))
+	if err != nil {
+		it.err = err
+		return false
+	}
+	it.page++
+	it.total = r.TotalCount
+	it.mos = r.Mos
+	it.i = 0
+	if len(r.Mos) < it.size || it.page*it.size >= r.TotalCount {
+		it.done = true
+	}
+	return len(it.mos) > 0
+}
+
+// Current MO
+func (it *MoIterator) Mo() ApicMo {
+	return it.mos[it.i]
+}
+
+// Number of MOs matching the query, as reported by the last page
+func (it *MoIterator) TotalCount() int {
+	return it.total
+}
+
+// Error that stopped the iteration, if any
+func (it *MoIterator) Err() error {
+	return it.err
+}
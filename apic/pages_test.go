@@ -0,0 +1,130 @@
+package apic
+
+import (
+	"aci-chatbot/mocks"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Mock APIC returning total fvCEp MOs, paged with the page and page-size options
+func mockPages(total int, fail int) (*[]string, *int) {
+	login := `{"totalCount": "1", "imdata": [{"aaaLogin": {"attributes": {"token": "eyJhbGciOiJSUzI1NiIsImtpZCI6InJqcmRjazBuNW"}}}]}`
+	var mu sync.Mutex
+	var requests []string
+	inFlight, maxInFlight := 0, 0
+	mocks.GetDoFunc = func(req *http.Request) (*http.Response, error) {
+		if strings.Contains(req.URL.Path, "aaaLogin") {
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(login)))}, nil
+		}
+		mu.Lock()
+		requests = append(requests, req.URL.RawQuery)
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+
+		page, _ := strconv.Atoi(req.URL.Query().Get("page"))
+		size, _ := strconv.Atoi(req.URL.Query().Get("page-size"))
+		if page == fail {
+			return &http.Response{StatusCode: 500, Body: ioutil.NopCloser(bytes.NewReader([]byte(`{}`)))}, nil
+		}
+		items := []string{}
+		for i := page * size; i < (page+1)*size && i < total; i++ {
+			items = append(items, fmt.Sprintf(`{"fvCEp": {"attributes": {"mac": "%d"}}}`, i))
+		}
+		body := fmt.Sprintf(`{"totalCount": "%d", "imdata": [%s]}`, total, strings.Join(items, ","))
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(body)))}, nil
+	}
+	return &requests, &maxInFlight
+}
+
+func TestPages(t *testing.T) {
+	Client = &mocks.MockClient{}
+	t.Run("All the pages in order", func(t *testing.T) {
+		requests, maxInFlight := mockPages(11, -1)
+		clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetTimeout(8), SetPageSize(2), SetPageConcurrency(2))
+		var mos []FvCEp
+		err := clt.getClass(context.Background(), ClassQuery("fvCEp"), &mos)
+		ok(t, err)
+		equals(t, len(mos), 11)
+		for i, mo := range mos {
+			equals(t, mo.Mac, strconv.Itoa(i))
+		}
+		equals(t, len(*requests), 6)
+		equals(t, (*requests)[0], "page=0&page-size=2")
+		equals(t, *maxInFlight <= 2, true)
+	})
+	t.Run("Single page", func(t *testing.T) {
+		requests, _ := mockPages(3, -1)
+		clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetTimeout(8))
+		mos, err := clt.reqApicMos(context.Background(), ClassQuery("fvCEp"))
+		ok(t, err)
+		equals(t, len(mos), 3)
+		equals(t, *requests, []string{"page=0&page-size=1000"})
+	})
+	t.Run("Page size set by the query", func(t *testing.T) {
+		requests, _ := mockPages(11, -1)
+		clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetTimeout(8), SetPageSize(2))
+		mos, err := clt.reqApicMos(context.Background(), ClassQuery("fvCEp").PageSize(5))
+		ok(t, err)
+		equals(t, len(mos), 5)
+		equals(t, *requests, []string{"page-size=5"})
+	})
+	t.Run("Failed page", func(t *testing.T) {
+		mockPages(11, 3)
+		clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetTimeout(8), SetPageSize(2))
+		_, err := clt.reqApicMos(context.Background(), ClassQuery("fvCEp"))
+		notOk(t, err)
+	})
+}
+
+func TestMoIterator(t *testing.T) {
+	Client = &mocks.MockClient{}
+	t.Run("All the MOs", func(t *testing.T) {
+		requests, _ := mockPages(5, -1)
+		clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetTimeout(8))
+		it := NewMoIterator(clt, ClassQuery("fvCEp").Filter(Wcard("fvCEp.dn", "tn-a")), 2)
+		var macs []string
+		for it.Next(context.Background()) {
+			macs = append(macs, it.Mo().Attributes["mac"])
+		}
+		ok(t, it.Err())
+		equals(t, macs, []string{"0", "1", "2", "3", "4"})
+		equals(t, it.TotalCount(), 5)
+		equals(t, len(*requests), 3)
+		equals(t, (*requests)[2], "query-target-filter=wcard%28fvCEp.dn%2C%22tn-a%22%29&page=2&page-size=2")
+	})
+	t.Run("No MOs", func(t *testing.T) {
+		mockPages(0, -1)
+		clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetTimeout(8))
+		it := NewMoIterator(clt, ClassQuery("fvCEp"), 2)
+		equals(t, it.Next(context.Background()), false)
+		ok(t, it.Err())
+	})
+	t.Run("Failed page", func(t *testing.T) {
+		mockPages(5, 1)
+		clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetTimeout(8))
+		it := NewMoIterator(clt, ClassQuery("fvCEp"), 2)
+		n := 0
+		for it.Next(context.Background()) {
+			n++
+		}
+		equals(t, n, 2)
+		notOk(t, it.Err())
+	})
+}
@@ -0,0 +1,210 @@
+package apic
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Query of the APIC REST API. Create it with ClassQuery() or DnQuery() and chain its options
+// The values of the options are URL-encoded. Setting an option twice replaces its value
+//
+//	ClassQuery("faultInst").Filter(Ne("faultInst.severity", "cleared")).OrderBy("faultInst.lastTransition", Desc).PageSize(10)
+type Query struct {
+	class  string // Class of the MOs of a class query. Empty for DN queries
+	dn     string // DN of a DN query. Empty for class queries
+	path   string
+	params []queryParam
+}
+
+type queryParam struct {
+	key   string
+	value string
+}
+
+// Sort orders. See OrderBy()
+const (
+	Asc  = "asc"
+	Desc = "desc"
+)
+
+// Query the instances of a class
+func ClassQuery(c string) *Query {
+	return &Query{class: c, path: "/api/node/class/" + escapePath(c) + ".json"}
+}
+
+// Query a MO by its DN
+func DnQuery(dn string) *Query {
+	return &Query{dn: dn, path: "/api/node/mo/" + escapePath(dn) + ".json"}
+}
+
+// Class of the MOs returned by a class query. Empty for DN queries
+func (q *Query) Class() string {
+	return q.class
+}
+
+// DN of the MO of a DN query. Empty for class queries
+func (q *Query) Dn() string {
+	return q.dn
+}
+
+// Scope of the query: self, children or subtree
+func (q *Query) Target(t string) *Query {
+	return q.Param("query-target", t)
+}
+
+// Only return the MOs of these classes in the scope of the query
+func (q *Query) TargetClass(c ...string) *Query {
+	return q.Param("target-subtree-class", strings.Join(c, ","))
+}
+
+// Only return the MOs matching the filter
+func (q *Query) Filter(f Filter) *Query {
+	return q.Param("query-target-filter", string(f))
+}
+
+// Include the children (children) or the whole subtree (full) of the MOs in the response
+func (q *Query) Subtree(s string) *Query {
+	return q.Param("rsp-subtree", s)
+}
+
+// Only include the children of these classes in the response
+func (q *Query) SubtreeClass(c ...string) *Query {
+	return q.Param("rsp-subtree-class", strings.Join(c, ","))
+}
+
+// Only include the children matching the filter in the response
+func (q *Query) SubtreeFilter(f Filter) *Query {
+	return q.Param("rsp-subtree-filter", string(f))
+}
+
+// Include related objects (e.g. faults, health, count) in the response
+func (q *Query) SubtreeInclude(s string) *Query {
+	return q.Param("rsp-subtree-include", s)
+}
+
+// Properties of the MOs in the response: all, naming-only or config-only
+func (q *Query) PropInclude(p string) *Query {
+	return q.Param("rsp-prop-include", p)
+}
+
+// Sort the MOs by a property (class.attribute). The order is Asc or Desc
+func (q *Query) OrderBy(prop, order string) *Query {
+	return q.Param("order-by", prop+"|"+order)
+}
+
+// Page of the response. The first page is 0
+func (q *Query) Page(p int) *Query {
+	return q.Param("page", strconv.Itoa(p))
+}
+
+// Number of MOs per page
+func (q *Query) PageSize(s int) *Query {
+	return q.Param("page-size", strconv.Itoa(s))
+}
+
+// Receive the events of the MOs of the query over the APIC WebSocket
+// The subscription must be refreshed before the timeout (seconds)
+func (q *Query) Subscribe(timeout int) *Query {
+	return q.Param("subscription", "yes").Param("refresh-timeout", strconv.Itoa(timeout))
+}
+
+// Set any other option of the query
+func (q *Query) Param(k, v string) *Query {
+	for i, p := range q.params {
+		if p.key == k {
+			q.params[i].value = v
+			return q
+		}
+	}
+	q.params = append(q.params, queryParam{k, v})
+	return q
+}
+
+// Get the value of an option. Empty if it is not set
+func (q *Query) GetParam(k string) string {
+	for _, p := range q.params {
+		if p.key == k {
+			return p.value
+		}
+	}
+	return ""
+}
+
+// Copy of the query. Options set on the copy do not change the original query
+func (q *Query) Clone() *Query {
+	c := *q
+	c.params = append([]queryParam{}, q.params...)
+	return &c
+}
+
+// Get the URL of the query, relative to the APIC. The options are kept in the order they were set
+func (q *Query) String() string {
+	if len(q.params) == 0 {
+		return q.path
+	}
+	params := make([]string, 0, len(q.params))
+	for _, p := range q.params {
+		params = append(params, url.QueryEscape(p.key)+"="+url.QueryEscape(p.value))
+	}
+	return q.path + "?" + strings.Join(params, "&")
+}
+
+// Escape a class or a DN for the URL path. The slashes of the DN are kept
+func escapePath(dn string) string {
+	return strings.ReplaceAll(url.PathEscape(dn), "%2F", "/")
+}
+
+// Filter expression of a query. Build it with Eq(), Ne(), Wcard(), Gt(), Lt(), And() and Or()
+type Filter string
+
+// The property (class.attribute) is equal to the value
+func Eq(prop, v string) Filter {
+	return filterOp("eq", prop, v)
+}
+
+// The property is not equal to the value
+func Ne(prop, v string) Filter {
+	return filterOp("ne", prop, v)
+}
+
+// The property contains the value
+func Wcard(prop, v string) Filter {
+	return filterOp("wcard", prop, v)
+}
+
+// The property is greater than the value
+func Gt(prop, v string) Filter {
+	return filterOp("gt", prop, v)
+}
+
+// The property is lower than the value
+func Lt(prop, v string) Filter {
+	return filterOp("lt", prop, v)
+}
+
+// All the filters match
+func And(f ...Filter) Filter {
+	return filterList("and", f)
+}
+
+// Any of the filters match
+func Or(f ...Filter) Filter {
+	return filterList("or", f)
+}
+
+// Values are quoted. Quotes and backslashes in the values are escaped
+var filterEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+func filterOp(op, prop, v string) Filter {
+	return Filter(fmt.Sprintf(`%s(%s,"%s")`, op, prop, filterEscaper.Replace(v)))
+}
+
+func filterList(op string, f []Filter) Filter {
+	s := make([]string, len(f))
+	for i, e := range f {
+		s[i] = string(e)
+	}
+	return Filter(op + "(" + strings.Join(s, ",") + ")")
+}
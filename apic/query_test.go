@@ -0,0 +1,37 @@
+package apic
+
+import (
+	"testing"
+)
+
+func TestQueryBuilder(t *testing.T) {
+	t.Run("Class query", func(t *testing.T) {
+		q := ClassQuery("faultInst").Filter(And(Wcard("faultInst.dn", "node-101/"), Ne("faultInst.severity", "cleared"))).OrderBy("faultInst.lastTransition", Desc).Page(2).PageSize(10)
+		equals(t, q.String(), "/api/node/class/faultInst.json?query-target-filter=and%28wcard%28faultInst.dn%2C%22node-101%2F%22%29%2Cne%28faultInst.severity%2C%22cleared%22%29%29&order-by=faultInst.lastTransition%7Cdesc&page=2&page-size=10")
+		equals(t, q.Class(), "faultInst")
+		equals(t, q.Dn(), "")
+	})
+	t.Run("DN query", func(t *testing.T) {
+		q := DnQuery("topology/pod-1/paths-101/pathep-[eth1/1]").Target("subtree").TargetClass("l1PhysIf", "ethpmPhysIf")
+		equals(t, q.String(), "/api/node/mo/topology/pod-1/paths-101/pathep-%5Beth1/1%5D.json?query-target=subtree&target-subtree-class=l1PhysIf%2CethpmPhysIf")
+		equals(t, q.Class(), "")
+		equals(t, q.Dn(), "topology/pod-1/paths-101/pathep-[eth1/1]")
+	})
+	t.Run("No options", func(t *testing.T) {
+		equals(t, ClassQuery("fvTenant").String(), "/api/node/class/fvTenant.json")
+	})
+	t.Run("Filters", func(t *testing.T) {
+		equals(t, Or(Eq("aaaModLR.user", "admin"), Gt("aaaModLR.created", "2021"), Lt("aaaModLR.created", "2022")), Filter(`or(eq(aaaModLR.user,"admin"),gt(aaaModLR.created,"2021"),lt(aaaModLR.created,"2022"))`))
+		equals(t, Eq("fvBD.descr", `a "quoted" \ value`), Filter(`eq(fvBD.descr,"a \"quoted\" \\ value")`))
+	})
+	t.Run("Options are replaced", func(t *testing.T) {
+		q := ClassQuery("fvBD").PageSize(10).OrderBy("fvBD.name", Asc).PageSize(5)
+		equals(t, q.String(), "/api/node/class/fvBD.json?page-size=5&order-by=fvBD.name%7Casc")
+	})
+	t.Run("Clone", func(t *testing.T) {
+		q := ClassQuery("fvBD").PageSize(10)
+		c := q.Clone().Page(1).PageSize(20)
+		equals(t, q.String(), "/api/node/class/fvBD.json?page-size=10")
+		equals(t, c.String(), "/api/node/class/fvBD.json?page-size=20&page=1")
+	})
+}
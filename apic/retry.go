@@ -0,0 +1,234 @@
+package apic
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Retries of the idempotent requests and circuit breaker. See SetRetries() and SetCircuitBreaker()
+const (
+	defaultRetries         = 2
+	defaultBackoff         = 250 * time.Millisecond
+	maxBackoff             = 5 * time.Second
+	defaultBreakerFailures = 5
+	defaultBreakerCooldown = 30 * time.Second
+)
+
+// Returned while the circuit breaker of an APIC is open. Requests fail fast instead of waiting out the timeout
+var ErrUnavailable = errors.New("the APIC is not reachable")
+
+// Retry the GET requests failing with a network error or throttled by the APIC (429, 502, 503, 504)
+// The delay doubles after every attempt, starting at backoff, with a random jitter. 0 retries disables them
+func SetRetries(n int, backoff time.Duration) Option {
+	return func(client *ApicClient) {
+		if n >= 0 {
+			client.retries = n
+		}
+		if backoff > 0 {
+			client.backoff = backoff
+		}
+	}
+}
+
+// Stop sending requests for the cooldown after a number of consecutive failed requests
+// A single request is then sent to check whether the APIC is back. 0 failures disables the circuit breaker
+func SetCircuitBreaker(failures int, cooldown time.Duration) Option {
+	return func(client *ApicClient) {
+		client.breaker = &circuitBreaker{threshold: failures, cooldown: cooldown}
+	}
+}
+
+// Whether the APIC accepts requests. Returns an error wrapping ErrUnavailable while the circuit breaker is open
+func (client *ApicClient) CheckAvailable() error {
+	if open, retryIn := client.breaker.state(); open {
+		return client.unavailable(retryIn)
+	}
+	return nil
+}
+
+func (client *ApicClient) unavailable(retryIn time.Duration) error {
+	if retryIn <= 0 {
+		return fmt.Errorf("%w. Requests to %s are paused while the connection is checked", ErrUnavailable, client.baseURL)
+	}
+	return fmt.Errorf("%w. Requests to %s are paused for %s", ErrUnavailable, client.baseURL, retryIn.Round(time.Second))
+}
+
+// Send a request and read its response. The circuit breaker counts the failed requests
+func (client *ApicClient) send(req *http.Request) (int, []byte, error) {
+	if ok, retryIn := client.breaker.allow(); !ok {
+		return 0, nil, client.unavailable(retryIn)
+	}
+	status, body, err := client.sendWithRetries(req)
+	// Requests cancelled by the caller tell nothing about the APIC
+	// Requests rejected by a reachable APIC (e.g. 400 or 401) are not failures
+	if req.Context().Err() != nil {
+		client.breaker.abort()
+	} else if err != nil || status >= http.StatusInternalServerError || status == http.StatusTooManyRequests {
+		client.breaker.failure()
+	} else {
+		client.breaker.success()
+	}
+	return status, body, err
+}
+
+// Send a request. Idempotent requests are retried on network errors and throttling
+func (client *ApicClient) sendWithRetries(req *http.Request) (int, []byte, error) {
+	attempts := 1
+	if req.Method == http.MethodGet {
+		attempts += client.retries
+	}
+	for i := 0; ; i++ {
+		status, body, retryAfter, err := client.sendOnce(req)
+		if i+1 >= attempts || !retryable(status, err) || req.Context().Err() != nil {
+			return status, body, err
+		}
+		wait := client.retryDelay(i, retryAfter)
+		log.Printf("Request to %s failed. Retrying in %s (%d/%d)", req.URL.Path, wait, i+1, attempts-1)
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return 0, nil, req.Context().Err()
+		}
+	}
+}
+
+// Send a request once. Returns the status, the body and the Retry-After delay of the response
+func (client *ApicClient) sendOnce(req *http.Request) (int, []byte, time.Duration, error) {
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, 0, err
+	}
+	if resp == nil {
+		return 0, nil, 0, fmt.Errorf("no response to the request %s", req.URL)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, 0, err
+	}
+	var retryAfter time.Duration
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s > 0 {
+		retryAfter = time.Duration(s) * time.Second
+	}
+	return resp.StatusCode, body, retryAfter, nil
+}
+
+func retryable(status int, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Delay before the next attempt. Half of the exponential delay is randomized so that clients do not retry at once
+// The Retry-After delay of a throttled response is used if it is longer
+func (client *ApicClient) retryDelay(attempt int, retryAfter time.Duration) time.Duration {
+	d := client.backoff << uint(attempt)
+	if d > maxBackoff || d <= 0 {
+		d = maxBackoff
+	}
+	d = d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+	if retryAfter > d {
+		d = retryAfter
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
+
+// Circuit breaker of an APIC
+// It opens after threshold consecutive failures. Once the cooldown elapses, a single trial request is allowed
+// The breaker closes if the trial succeeds and opens again otherwise
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	trial     bool // The trial request is in flight
+}
+
+// Whether a request may be sent. Otherwise returns the time left before the next trial
+func (cb *circuitBreaker) allow() (bool, time.Duration) {
+	if cb == nil || cb.threshold <= 0 {
+		return true, 0
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.failures < cb.threshold {
+		return true, 0
+	}
+	if now := time.Now(); now.Before(cb.openUntil) {
+		return false, cb.openUntil.Sub(now)
+	}
+	if cb.trial {
+		return false, 0
+	}
+	cb.trial = true
+	return true, 0
+}
+
+// Whether the breaker is open, and the time left before the next trial
+func (cb *circuitBreaker) state() (bool, time.Duration) {
+	if cb == nil || cb.threshold <= 0 {
+		return false, 0
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.failures < cb.threshold {
+		return false, 0
+	}
+	left := time.Until(cb.openUntil)
+	if left < 0 {
+		left = 0
+	}
+	return left > 0 || cb.trial, left
+}
+
+func (cb *circuitBreaker) success() {
+	if cb == nil {
+		return
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.failures = 0
+	cb.trial = false
+}
+
+// The request was cancelled. Another trial request may be sent
+func (cb *circuitBreaker) abort() {
+	if cb == nil {
+		return
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.trial = false
+}
+
+func (cb *circuitBreaker) failure() {
+	if cb == nil {
+		return
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.failures++
+	cb.trial = false
+	if cb.threshold > 0 && cb.failures >= cb.threshold {
+		if cb.failures == cb.threshold {
+			log.Printf("Circuit breaker open after %d failed requests. Requests are paused for %s", cb.failures, cb.cooldown)
+		}
+		cb.openUntil = time.Now().Add(cb.cooldown)
+	}
+}
@@ -0,0 +1,121 @@
+package apic
+
+import (
+	"aci-chatbot/mocks"
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Mock APIC answering the requests other than the login with the statuses in order. The last status is repeated
+func mockStatuses(statuses ...int) *int {
+	login := `{"totalCount": "1", "imdata": [{"aaaLogin": {"attributes": {"token": "eyJhbGciOiJSUzI1NiIsImtpZCI6InJqcmRjazBuNW"}}}]}`
+	calls := 0
+	mocks.GetDoFunc = func(req *http.Request) (*http.Response, error) {
+		if strings.Contains(req.URL.Path, "aaaLogin") {
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(login)))}, nil
+		}
+		status := statuses[len(statuses)-1]
+		if calls < len(statuses) {
+			status = statuses[calls]
+		}
+		calls++
+		header := http.Header{}
+		if status == http.StatusTooManyRequests {
+			header.Set("Retry-After", "1")
+		}
+		body := `{"totalCount": "1", "imdata": [{"procEntity": {"attributes": {"cpuPct": "10"}}}]}`
+		return &http.Response{StatusCode: status, Header: header, Body: ioutil.NopCloser(bytes.NewReader([]byte(body)))}, nil
+	}
+	return &calls
+}
+
+func TestRetries(t *testing.T) {
+	Client = &mocks.MockClient{}
+	t.Run("Throttled GET retried", func(t *testing.T) {
+		calls := mockStatuses(503, 200)
+		clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetRetries(2, time.Millisecond))
+		_, err := clt.GetProcEntity(context.Background())
+		ok(t, err)
+		equals(t, *calls, 2)
+	})
+	t.Run("Retries exhausted", func(t *testing.T) {
+		calls := mockStatuses(502)
+		clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetRetries(2, time.Millisecond))
+		_, err := clt.GetProcEntity(context.Background())
+		notOk(t, err)
+		equals(t, *calls, 3)
+	})
+	t.Run("Client errors not retried", func(t *testing.T) {
+		calls := mockStatuses(400)
+		clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetRetries(2, time.Millisecond))
+		_, err := clt.GetProcEntity(context.Background())
+		notOk(t, err)
+		equals(t, *calls, 1)
+	})
+	t.Run("POST not retried", func(t *testing.T) {
+		calls := mockStatuses(503)
+		clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetRetries(2, time.Millisecond))
+		notOk(t, clt.PostMo(context.Background(), "uni/tn-myTenant", []byte(`{}`)))
+		equals(t, *calls, 1)
+	})
+	t.Run("Retry-After honored", func(t *testing.T) {
+		clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetRetries(2, time.Millisecond))
+		equals(t, clt.retryDelay(0, 2*time.Second), 2*time.Second)
+		equals(t, clt.retryDelay(0, time.Minute), maxBackoff)
+		for i := 0; i < 10; i++ {
+			d := clt.retryDelay(3, 0)
+			equals(t, d >= 4*time.Millisecond && d <= 8*time.Millisecond, true)
+		}
+	})
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	Client = &mocks.MockClient{}
+	t.Run("Open after consecutive failures", func(t *testing.T) {
+		calls := mockStatuses(500)
+		clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetRetries(0, 0), SetCircuitBreaker(3, time.Minute))
+		for i := 0; i < 3; i++ {
+			ok(t, clt.CheckAvailable())
+			_, err := clt.GetProcEntity(context.Background())
+			notOk(t, err)
+		}
+		_, err := clt.GetProcEntity(context.Background())
+		equals(t, errors.Is(err, ErrUnavailable), true)
+		equals(t, errors.Is(clt.CheckAvailable(), ErrUnavailable), true)
+		equals(t, *calls, 3)
+	})
+	t.Run("Closed after a successful trial", func(t *testing.T) {
+		calls := mockStatuses(500, 500, 200)
+		clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetRetries(0, 0), SetCircuitBreaker(2, 10*time.Millisecond))
+		clt.GetProcEntity(context.Background())
+		clt.GetProcEntity(context.Background())
+		notOk(t, clt.CheckAvailable())
+		time.Sleep(20 * time.Millisecond)
+		_, err := clt.GetProcEntity(context.Background())
+		ok(t, err)
+		ok(t, clt.CheckAvailable())
+		equals(t, *calls, 3)
+	})
+	t.Run("Rejected requests are not failures", func(t *testing.T) {
+		mockStatuses(400)
+		clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetRetries(0, 0), SetCircuitBreaker(2, time.Minute))
+		for i := 0; i < 5; i++ {
+			clt.GetProcEntity(context.Background())
+		}
+		ok(t, clt.CheckAvailable())
+	})
+	t.Run("Disabled", func(t *testing.T) {
+		mockStatuses(500)
+		clt, _ := NewApicClient("http://mocking.com", "admin", "admin", SetRetries(0, 0), SetCircuitBreaker(0, time.Minute))
+		for i := 0; i < 10; i++ {
+			clt.GetProcEntity(context.Background())
+		}
+		ok(t, clt.CheckAvailable())
+	})
+}
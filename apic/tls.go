@@ -0,0 +1,72 @@
+package apic
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Build the TLS settings of an APIC client
+// Certificates are verified against the system CAs, or against the CAs of the PEM bundle caFile if set
+// Pinned certificates are trusted without a CA, which suits the self-signed certificate of an APIC. The pins are the
+// SHA-256 fingerprints of the certificates, in hex. Colons are ignored (e.g. the output of openssl x509 -fingerprint -sha256)
+// insecure skips the verification of the certificate. Pins are still checked
+func NewTLSConfig(caFile string, pins []string, insecure bool) (*tls.Config, error) {
+	cfg := &tls.Config{}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read the CA bundle. %s", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in the CA bundle %s", caFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if len(pins) > 0 {
+		fingerprints := []string{}
+		for _, p := range pins {
+			f := strings.ToLower(strings.Replace(strings.TrimSpace(p), ":", "", -1))
+			if b, err := hex.DecodeString(f); err != nil || len(b) != sha256.Size {
+				return nil, fmt.Errorf("invalid certificate pin %s. Pins are SHA-256 fingerprints", p)
+			}
+			fingerprints = append(fingerprints, f)
+		}
+		// The chain is not verified. The certificate of the APIC must be one of the pinned ones
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return errors.New("no certificate sent by the APIC")
+			}
+			sum := sha256.Sum256(rawCerts[0])
+			if !stringInSlice(hex.EncodeToString(sum[:]), fingerprints) {
+				return fmt.Errorf("the certificate of the APIC (SHA-256 %x) is not pinned", sum)
+			}
+			return nil
+		}
+	} else if insecure {
+		log.Printf("The certificate of the APIC is not verified")
+		cfg.InsecureSkipVerify = true
+	}
+	return cfg, nil
+}
+
+// The TLS settings of the client. Used by the REST API and the WebSocket. See NewTLSConfig()
+func SetTLSConfig(cfg *tls.Config) Option {
+	return func(client *ApicClient) {
+		client.tlsConfig = cfg
+		if hc, ok := client.httpClient.(*http.Client); ok {
+			if t, ok := hc.Transport.(*http.Transport); ok {
+				t.TLSClientConfig = cfg
+			}
+		}
+	}
+}
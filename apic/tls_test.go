@@ -0,0 +1,68 @@
+package apic
+
+import (
+	"crypto/sha256"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTLSConfig(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"totalCount": "1", "imdata": [{"aaaLogin": {"attributes": {"token": "eyJhbGciOiJSUzI1NiIsImtpZCI6InJqcmRjazBuNW"}}}]}`))
+	}))
+	defer srv.Close()
+	defer func(c HttpClient) { Client = c }(Client)
+	Client = &http.Client{}
+
+	dir, err := ioutil.TempDir("", "tls")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+	ca := filepath.Join(dir, "ca.pem")
+	ok(t, ioutil.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0600))
+	pin := fmt.Sprintf("%X", sha256.Sum256(srv.Certificate().Raw))
+
+	t.Run("Certificate verified by default", func(t *testing.T) {
+		_, err := NewApicClient(srv.URL, "admin", "admin")
+		notOk(t, err)
+	})
+	t.Run("CA bundle", func(t *testing.T) {
+		cfg, err := NewTLSConfig(ca, nil, false)
+		ok(t, err)
+		clt, err := NewApicClient(srv.URL, "admin", "admin", SetTLSConfig(cfg))
+		ok(t, err)
+		equals(t, clt.GetDialer().TLSClientConfig, cfg)
+	})
+	t.Run("Pinned certificate", func(t *testing.T) {
+		cfg, err := NewTLSConfig("", []string{pin[:2] + ":" + pin[2:]}, false)
+		ok(t, err)
+		_, err = NewApicClient(srv.URL, "admin", "admin", SetTLSConfig(cfg))
+		ok(t, err)
+	})
+	t.Run("Certificate not pinned", func(t *testing.T) {
+		cfg, err := NewTLSConfig("", []string{fmt.Sprintf("%x", sha256.Sum256([]byte("other")))}, true)
+		ok(t, err)
+		_, err = NewApicClient(srv.URL, "admin", "admin", SetTLSConfig(cfg))
+		notOk(t, err)
+	})
+	t.Run("Insecure", func(t *testing.T) {
+		cfg, err := NewTLSConfig("", nil, true)
+		ok(t, err)
+		_, err = NewApicClient(srv.URL, "admin", "admin", SetTLSConfig(cfg))
+		ok(t, err)
+		// The settings of a client do not apply to the others
+		_, err = NewApicClient(srv.URL, "admin", "admin")
+		notOk(t, err)
+	})
+	t.Run("Invalid settings", func(t *testing.T) {
+		_, err := NewTLSConfig(filepath.Join(dir, "missing.pem"), nil, false)
+		notOk(t, err)
+		_, err = NewTLSConfig("", []string{"AB:CD"}, false)
+		notOk(t, err)
+	})
+}
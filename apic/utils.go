@@ -1,38 +1,73 @@
 package apic
 
 import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 )
 
-func getApicManagedObjects(p map[string]interface{}, c string) []ApicMoAttributes {
-	mos := []ApicMoAttributes{}
-
-	for _, item := range p["imdata"].([]interface{}) {
-		att := make(map[string]string)
-		for k, v := range item.(map[string]interface{})[c].(map[string]interface{})["attributes"].(map[string]interface{}) {
-			att[k] = v.(string)
-		}
-		mos = append(mos, att)
+// Decode the MOs of a query result. The MOs may be of any class
+// Unexpected payloads return an error instead of panicking
+func getQueryResult(p map[string]interface{}) (QueryResult, error) {
+	r := QueryResult{}
+	imdata, ok := p["imdata"].([]interface{})
+	if !ok {
+		return r, errors.New("unexpected APIC response. imdata not found")
+	}
+	if c, ok := p["totalCount"].(string); ok {
+		r.TotalCount, _ = strconv.Atoi(c)
 	}
-	return mos
+	mos, err := getMos(imdata)
+	if err != nil {
+		return r, err
+	}
+	r.Mos = mos
+	return r, nil
 }
 
-func getApicManagedObjectsChildren(p map[string]interface{}, parent string, children string) []ApicMoAttributes {
-	mos := []ApicMoAttributes{}
-
-	for _, p := range p["imdata"].([]interface{}) {
-		ch := p.(map[string]interface{})[parent].(map[string]interface{})["children"]
-		if ch != nil {
-			for _, c := range ch.([]interface{}) {
-				att := make(map[string]string)
-				for k, v := range c.(map[string]interface{})[children].(map[string]interface{})["attributes"].(map[string]interface{}) {
-					att[k] = v.(string)
+// Decode a list of MOs, with their children
+// Each item is a single-key object: {"<class>": {"attributes": {...}, "children": [...]}}
+func getMos(items []interface{}) ([]ApicMo, error) {
+	mos := []ApicMo{}
+	for _, item := range items {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("unexpected APIC response. MO is not an object")
+		}
+		for class, body := range obj {
+			b, ok := body.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("unexpected APIC response. %s is not an object", class)
+			}
+			mo := ApicMo{Class: class, Attributes: ApicMoAttributes{}}
+			attributes, ok := b["attributes"].(map[string]interface{})
+			if !ok && b["attributes"] != nil {
+				return nil, fmt.Errorf("unexpected APIC response. The attributes of %s are not an object", class)
+			}
+			for k, v := range attributes {
+				s, ok := v.(string)
+				if !ok {
+					return nil, fmt.Errorf("unexpected APIC response. The attribute %s of %s is not a string", k, class)
+				}
+				mo.Attributes[k] = s
+			}
+			if b["children"] != nil {
+				children, ok := b["children"].([]interface{})
+				if !ok {
+					return nil, fmt.Errorf("unexpected APIC response. The children of %s are not a list", class)
+				}
+				ch, err := getMos(children)
+				if err != nil {
+					return nil, err
 				}
-				mos = append(mos, att)
+				mo.Children = ch
 			}
+			mos = append(mos, mo)
 		}
 	}
-	return mos
+	return mos, nil
 }
 
 func GetRn(dn string, rnId string) string {
@@ -98,3 +133,61 @@ func stringInSlice(a string, list []string) bool {
 	}
 	return false
 }
+
+// Build a unique key for a physical interface (pod/node/interface) out of any DN below it
+func interfaceKey(dn string) string {
+	return strings.Join([]string{GetRn(dn, "pod"), GetRn(dn, "node"), strings.Trim(GetRn(dn, "phys"), "[]")}, "/")
+}
+
+// Sum of the CRC and input errors of an interface
+func interfaceErrors(i InterfaceInformation) int {
+	crc, _ := strconv.Atoi(i.CrcErrors)
+	in, _ := strconv.Atoi(i.InputErrors)
+	return crc + in
+}
+
+// Group *HealthHist* records by object and build one series per object.
+// The series name is the value of the rnId in the DN of the object
+func getHealthSeries(mos []HealthHist, rnId string) []HealthSeries {
+	records := make(map[string][]HealthHist)
+	names := []string{}
+	for _, mo := range mos {
+		parent := strings.Split(mo.Dn, "/HD")[0]
+		if _, ok := records[parent]; !ok {
+			names = append(names, parent)
+		}
+		records[parent] = append(records[parent], mo)
+	}
+
+	series := []HealthSeries{}
+	for _, parent := range names {
+		r := records[parent]
+		// The record with index 0 is the most recent one
+		sort.SliceStable(r, func(i, j int) bool {
+			idxI, _ := strconv.Atoi(r[i].Index)
+			idxJ, _ := strconv.Atoi(r[j].Index)
+			return idxI > idxJ
+		})
+		hs := HealthSeries{Name: GetRn(parent, rnId), Min: 100}
+		sum := 0
+		for _, item := range r {
+			avg, _ := strconv.Atoi(item.HealthAvg)
+			min, _ := strconv.Atoi(item.HealthMin)
+			max, _ := strconv.Atoi(item.HealthMax)
+			hs.Samples = append(hs.Samples, avg)
+			if min < hs.Min {
+				hs.Min = min
+			}
+			if max > hs.Max {
+				hs.Max = max
+			}
+			sum += avg
+		}
+		hs.Avg = float64(sum) / float64(len(r))
+		series = append(series, hs)
+	}
+	sort.SliceStable(series, func(i, j int) bool {
+		return series[i].Avg < series[j].Avg
+	})
+	return series
+}
@@ -1,7 +1,6 @@
 package apic
 
 import (
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -17,23 +16,21 @@ type ApicWebSocket struct {
 	dl  *websocket.Dialer
 }
 
-func NewApicWebSClient(ip string, token string) (*ApicWebSocket, error) {
-	u := fmt.Sprintf("wss://%s/socket%s", strings.Replace(ip, "https://", "", -1), token)
-	d := *websocket.DefaultDialer
-	d.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+// Connect to the WebSocket of an APIC. The dialer holds the TLS settings of the APIC client. See GetDialer()
+func NewApicWebSClient(ip string, token string, d *websocket.Dialer) (*ApicWebSocket, error) {
 	log.Printf("Setting up Websocket...")
-	wsc, _, err := d.Dial(u, nil)
+	wsc, _, err := d.Dial(webSocketUrl(ip, token), nil)
 	if err != nil {
 		log.Printf("Error setting up the websocket connection . Error %s", err)
 		return nil, err
 	}
-	aws := ApicWebSocket{ip: ip, ws: wsc, tkn: token, dl: &d}
+	aws := ApicWebSocket{ip: ip, ws: wsc, tkn: token, dl: d}
 
 	return &aws, nil
 }
 
 func (aws *ApicWebSocket) NewDial(token string) error {
-	ws, _, err := aws.dl.Dial(fmt.Sprintf("wss://10.49.208.146/socket%s", token), nil)
+	ws, _, err := aws.dl.Dial(webSocketUrl(aws.ip, token), nil)
 	if err != nil {
 		log.Printf("Error setting up the websocket connection . Error %s", err)
 		return err
@@ -42,6 +39,10 @@ func (aws *ApicWebSocket) NewDial(token string) error {
 	return nil
 }
 
+func webSocketUrl(ip string, token string) string {
+	return fmt.Sprintf("wss://%s/socket%s", strings.Replace(ip, "https://", "", -1), token)
+}
+
 func (aws *ApicWebSocket) readSocket(data interface{}) error {
 
 	_, message, err := aws.ws.ReadMessage()
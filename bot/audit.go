@@ -0,0 +1,54 @@
+package bot
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// Record of an action on a configuration change
+type auditEntry struct {
+	Time     time.Time       `json:"time"`
+	Action   string          `json:"action"` // requested, approved, applied, failed or cancelled
+	Change   string          `json:"change"`
+	User     string          `json:"user"`
+	PersonId string          `json:"personId,omitempty"`
+	Room     string          `json:"room"`
+	Dn       string          `json:"dn"`
+	Payload  json.RawMessage `json:"payload"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// Audit log of the configuration changes. One JSON entry per line
+// The entries are written to the standard logger unless a writer is set
+type auditLog struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Write the audit log of the configuration changes to w instead of the standard logger
+func SetAuditLog(w io.Writer) Option {
+	return func(b *Bot) {
+		b.changes.audit.w = w
+	}
+}
+
+// Record an action of a person on a change
+func (a *auditLog) record(action string, c *pendingChange, wm ChatMessage, err error) {
+	e := auditEntry{Time: time.Now().UTC(), Action: action, Change: c.id, User: wm.sender, PersonId: wm.personId, Room: wm.roomKey(), Dn: c.dn, Payload: c.payload}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	line, _ := json.Marshal(e)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.w == nil {
+		log.Printf("audit: %s", line)
+		return
+	}
+	if _, err := a.w.Write(append(line, '\n')); err != nil {
+		log.Printf("could not write the audit log. Err %s", err)
+	}
+}
@@ -2,11 +2,15 @@ package bot
 
 import (
 	"aci-chatbot/apic"
+	"aci-chatbot/slack"
+	"aci-chatbot/teams"
 	"aci-chatbot/webex"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"sort"
@@ -14,17 +18,55 @@ import (
 )
 
 // Callback helpers
-type Callback func(a apic.ApicInterface, m Message, wm WebexMessage) string
+type Callback func(ctx context.Context, a apic.ApicInterface, m Message, wm ChatMessage) Reply
 
-// Struct to represent the incomming Webex message
-type WebexMessage struct {
-	sender string
-	roomId string
+// Struct to represent the reply of a command
+type Reply struct {
+	text string              // Rendered message sent back to the room
+	data interface{}         // Structured data behind the message. Used to export it
+	card *webex.AdaptiveCard // Optional card. The text is the fallback for clients without card support
+}
+
+// Type to define the Bot configuration option
+type Option func(*Bot)
+
+// Receive the messages over the Webex WebSocket instead of webhooks
+// The bot does not need to be reachable from the internet. See ListenWebexDevice()
+func SetWebexDeviceMode() Option {
+	return func(b *Bot) {
+		b.deviceMode = true
+	}
+}
+
+// Command execution settings
+var (
+	slowCommandDelay = 2 * time.Second  // Commands taking longer than this delay are considered slow
+	commandTimeout   = 30 * time.Second // Deadline of a command. The APIC requests are cancelled afterwards
+	maxWorkers       = 8                // Commands executed concurrently
+	maxQueuedJobs    = 64               // Commands waiting for a worker. Further commands are rejected
+	webhookTTL       = 10 * time.Minute // Time during which a redelivered webhook is ignored
+	reconnectDelay   = 5 * time.Second  // Delay before reconnecting to the Webex WebSocket
+	changeTTL        = 10 * time.Minute // Time during which a configuration change can be confirmed
+)
+
+// Struct to represent the incomming chat message
+type ChatMessage struct {
+	sender    string
+	personId  string
+	roomId    string
+	parentId  string    // Replies are threaded under this message
+	transport Transport // Chat platform the message comes from. Replies are sent through it
+}
+
+// Identify a room across all the chat platforms
+func (cm ChatMessage) roomKey() string {
+	return cm.transport.Name() + ":" + cm.roomId
 }
 
 // Struct to represent the CLI command
 type Message struct {
-	cmd string
+	cmd    string
+	export string // Export format (csv or json). Empty if not requested
 }
 
 // Struct to save the suported CLI commands
@@ -37,19 +79,32 @@ type Command struct {
 
 // Bot definition
 type Bot struct {
-	wbx      webex.WebexInterface
-	apic     apic.ApicInterface
-	wsck     *apic.ApicWebSocket
-	server   *http.Server
-	router   *http.ServeMux
-	url      string
-	commands map[string]Command
-	wsSubs   *webSocketDb
-	info     webex.WebexPeople
+	wbx          webex.WebexInterface
+	apic         apic.ApicInterface
+	wsck         *apic.ApicWebSocket
+	server       *http.Server
+	router       *http.ServeMux
+	url          string
+	commands     map[string]Command
+	wsSubs       *webSocketDb
+	settings     *roomSettings
+	admins       *admins      // People allowed to run the administration commands. See SetAdmins()
+	changes      *changeStore // Configuration changes waiting for a confirmation
+	info         webex.WebexPeople
+	workers      *workerPool
+	webhooks     *webhookCache
+	deviceMode   bool                 // Receive the messages over the Webex WebSocket
+	transports   map[string]Transport // Chat platforms the bot is connected to, by name
+	slack        slack.SlackInterface // Optional Slack workspace. See SetSlack()
+	slackSecret  string
+	slackInfo    slack.SlackAuth
+	teams        teams.TeamsInterface // Optional Microsoft Teams bot. See SetTeams()
+	teamsRefs    *teamsConversations
+	metricsToken string // Bearer token of the metrics scrapers. See SetMetricsToken()
 }
 
 // Bot Generator
-func NewBot(wbx webex.WebexInterface, apic apic.ApicInterface, botUrl string) (Bot, error) {
+func NewBot(wbx webex.WebexInterface, apic apic.ApicInterface, botUrl string, options ...Option) (Bot, error) {
 
 	info, err := wbx.GetBotDetails()
 	if err != nil {
@@ -57,81 +112,136 @@ func NewBot(wbx webex.WebexInterface, apic apic.ApicInterface, botUrl string) (B
 		return Bot{}, err
 	}
 	bot := Bot{
-		wbx:    wbx,
-		apic:   apic,
-		router: http.NewServeMux(),
-		url:    botUrl,
-		info:   info,
+		wbx:      wbx,
+		apic:     apic,
+		router:   http.NewServeMux(),
+		url:      botUrl,
+		info:     info,
+		workers:  newWorkerPool(maxWorkers, maxQueuedJobs),
+		webhooks: newWebhookCache(webhookTTL),
 	}
+	bot.transports = map[string]Transport{"webex": webexTransport{wbx}}
 
-	bot.commands = make(map[string]Command)
 	bot.wsSubs = NewWsDb()
+	bot.settings = newRoomSettings()
+	bot.admins = newAdmins()
+	bot.changes = newChangeStore(changeTTL, bot.admins)
+	bot.commands = newCommands(bot.wsSubs, bot.settings, bot.changes, bot.admins)
+	for _, opt := range options {
+		opt(&bot)
+	}
+	if bot.slack != nil {
+		// The bot user ID is required to strip its mentions and ignore its own messages
+		if bot.slackInfo, err = bot.slack.AuthTest(); err != nil {
+			log.Printf("could not retrieve the Slack bot information. Err %s", err)
+			return Bot{}, err
+		}
+	}
+	// Webhooks are not needed when the messages are received over the Webex WebSocket
+	if !bot.deviceMode {
+		log.Println("Setting up Webex Webhook")
+		if err = bot.setupWebhook(); err != nil {
+			log.Printf("could not setup the webhook. Err %s", err)
+			return Bot{}, err
+		}
+	}
+	bot.routes()
+	return bot, nil
+}
+
+// Commands supported by the bot, by name
+// The same commands are served on every chat platform and in the CLI
+func newCommands(wsDb *webSocketDb, rs *roomSettings, cs *changeStore, adm *admins) map[string]Command {
+	cmds := make(map[string]Command)
 
 	log.Println("Adding `/info` command")
-	bot.addCommand("/info", "Get Fabric Information ℹ️", "\\/info", "$", infoCommand)
+	addCommand(cmds, "/info", "Get Fabric Information ℹ️", "\\/info", "$", infoCommand)
 	log.Println("Adding `/cpu` command")
-	bot.addCommand("/cpu", "Get APIC CPU Information 💾", "\\/cpu", "$", cpuCommand)
+	addCommand(cmds, "/cpu", "Get APIC CPU Information 💾", "\\/cpu", "$", cpuCommand)
 	log.Println("Adding `/ep` command")
-	bot.addCommand("/ep", "Get APIC Endpoint Information 💻. Usage <code>/ep [ep_mac] </code>", "\\/ep", " ([[:xdigit:]]{2}[:.-]?){5}[[:xdigit:]]{2}$", endpointCommand)
+	addCommand(cmds, "/ep", "Get APIC Endpoint Information 💻. Usage <code>/ep [ep_mac] [history:opt] </code>", "\\/ep", " ([[:xdigit:]]{2}[:.-]?){5}[[:xdigit:]]{2}( history)?$", endpointCommand)
 	log.Println("Adding `/neigh` command")
-	bot.addCommand("/neigh", "Get Fabric Topology Information 🔢. Usage <code>/neigh [node_id] </code>", "\\/neigh", "( )?([0-9]{1,4})?$", neighCommand)
+	addCommand(cmds, "/neigh", "Get Fabric Topology Information 🔢. Usage <code>/neigh [node_id] </code>", "\\/neigh", "( )?([0-9]{1,4})?$", neighCommand)
+	log.Println("Adding `/health` command")
+	addCommand(cmds, "/health", "Get Fabric health trend 📈. Usage <code>/health [15m|1h|1d:opt] </code>", "\\/health", "( )?(15m|1h|1d)?$", healthCommand)
+	log.Println("Adding `/node` command")
+	addCommand(cmds, "/node", "Get Node health and hardware status 🖥️. Usage <code>/node [node_id] </code>", "\\/node", " [0-9]{1,4}$", nodeCommand)
+	log.Println("Adding `/iface` command")
+	addCommand(cmds, "/iface", "Get Interface status and counters 🔌. Usage <code>/iface [node_id] [iface:opt] | top [count(1-10):opt] </code>", "\\/iface", " ([0-9]{1,4}( eth[0-9]{1,2}\\/[0-9]{1,3}(\\/[0-9]{1,3})?)?|top( ([1-9]|10))?)$", ifaceCommand)
 	log.Println("Adding `/faults` command")
-	bot.addCommand("/faults", "Get Fabric latest faults ⚠️. Usage <code>/faults [count(1-10):opt] </code>", "\\/faults", "( )?([1-9]|10)?$", faultCommand)
+	addCommand(cmds, "/faults", "Get Fabric latest faults ⚠️. Usage <code>/faults [count(1-10):opt] | ack [fault_dn] </code>", "\\/faults", "(( )?([1-9]|10)?| ack [^ ]+\\/fault-F[0-9]+)$", faultsCommand(cs))
 	log.Println("Adding `/events` command")
-	bot.addCommand("/events", "Get Fabric latest events ❎.   Usage <code>/events [user:opt] [count(1-10):opt] </code>", "\\/events", "( )?([A-Za-z]{5,10})?( )?([1-9]|10)?$", eventCommand)
-	bot.addCommand("/websocket", "Subscribe to Fabric events 📩", "\\/websocket", " [A-Za-z]{1,20}( )?(rm)?$", websocketCommand(bot.wsSubs))
+	addCommand(cmds, "/events", "Get Fabric latest events ❎.   Usage <code>/events [user:opt] [count(1-10):opt] </code>", "\\/events", "( )?([A-Za-z]{5,10})?( )?([1-9]|10)?$", eventCommand)
+	addCommand(cmds, "/websocket", "Subscribe to Fabric events 📩", "\\/websocket", " ([A-Za-z]{1,20}|topology\\/pod-[0-9]{1,2}\\/node-[0-9]{1,4})( )?(rm)?$", websocketCommand(wsDb))
+	log.Println("Adding `/mention` command")
+	addCommand(cmds, "/mention", "Require a mention in this group room 🔔. Usage <code>/mention [on|off:opt] </code>", "\\/mention", "( (on|off))?$", mentionCommand(rs))
+	log.Println("Adding `/query` command")
+	addCommand(cmds, "/query", "Query any class or DN 🔍. Usage <code>/query class [class] [filter:opt] [attrs:opt] | dn [dn] [subtree:opt] </code>", "\\/query", " (class [A-Za-z][A-Za-z0-9]{1,63}|dn [^ ?#&]+)( [^ ]+)*$", queryCommand)
+	log.Println("Adding `/shut` command")
+	addCommand(cmds, "/shut", "Shut down an interface 🛑. Usage <code>/shut [node_id] [iface] </code>", "\\/shut", " [0-9]{1,4} eth[0-9]{1,2}\\/[0-9]{1,3}(\\/[0-9]{1,3})?$", shutCommand(cs))
+	log.Println("Adding `/epg` command")
+	addCommand(cmds, "/epg", "Add a static path to an EPG 🔗. Usage <code>/epg add-static-path [tenant/app/epg] [node_id] [iface] [vlan] </code>", "\\/epg", " add-static-path [A-Za-z0-9_.:-]{1,64}\\/[A-Za-z0-9_.:-]{1,64}\\/[A-Za-z0-9_.:-]{1,64} [0-9]{1,4} eth[0-9]{1,2}\\/[0-9]{1,3}(\\/[0-9]{1,3})? [0-9]{1,4}$", epgCommand(cs))
+	log.Println("Adding `/tenant` command")
+	addCommand(cmds, "/tenant", "Create a tenant 🏢. Usage <code>/tenant create [name] </code>", "\\/tenant", " create [A-Za-z0-9_.:-]{1,64}$", tenantCommand(cs))
+	log.Println("Adding `/confirm` command")
+	addCommand(cmds, "/confirm", "Confirm a configuration change ✅. Usage <code>/confirm [change_id] </code>", "\\/confirm", " [0-9]{1,6}$", confirmCommand(cs))
+	log.Println("Adding `/cancel` command")
+	addCommand(cmds, "/cancel", "Cancel a configuration change 🗑️. Usage <code>/cancel [change_id] </code>", "\\/cancel", " [0-9]{1,6}$", cancelCommand(cs))
+	log.Println("Adding `/cache` command")
+	addCommand(cmds, "/cache", "Show the APIC cache statistics or flush it 🗄️. Usage <code>/cache [flush:opt] </code>", "\\/cache", "( flush)?$", cacheCommand(adm))
 	log.Println("Adding `/help` command")
 	log.Println("Adding `/help` command")
-	bot.addCommand("/help", "Chatbot Help ❔", "\\/help", "$", helpCommand(bot.commands))
-	log.Println("Setting up Webex Webhook")
-	if err = bot.setupWebhook(); err != nil {
-		log.Printf("could not setup the webhook. Err %s", err)
-		return Bot{}, err
-	}
-	bot.routes()
-	return bot, nil
+	addCommand(cmds, "/help", "Chatbot Help ❔", "\\/help", "$", helpCommand(cmds))
+	return cmds
 }
 
 // Command Handlers
 // /websocket handler
 func websocketCommand(wsDb *webSocketDb) Callback {
-	return func(c apic.ApicInterface, m Message, wm WebexMessage) string {
+	return func(ctx context.Context, c apic.ApicInterface, m Message, wm ChatMessage) Reply {
 		class := splitWebsocketCommand(m.cmd)["class"]
 		operation := splitWebsocketCommand(m.cmd)["op"]
 
 		// /websocket list -> Return the list of subscriptions
 		if class == "list" {
 			res := "<ul>"
-			classes := wsDb.getClassesbyRoomId(wm.roomId)
+			classes := wsDb.getClassesbyRoomId(wm.roomKey())
 			for _, class := range classes {
 				res += fmt.Sprintf("<li><code>%s</code></li>", class)
 			}
 			res += "</ul>"
 			if len(classes) == 0 {
-				return fmt.Sprintf("Hi %s 🤖 !\n You are no subscribed to any class", wm.sender)
+				return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n You are no subscribed to any class", wm.sender)}
 			} else {
-				return fmt.Sprintf("Hi %s 🤖 !\n Here the list of subcribed classes:\n %s", wm.sender, res)
+				return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n Here the list of subcribed classes:\n %s", wm.sender, res)}
 			}
 		}
 		// /websocket xxxx rm -> Remove subscirption to this Room
 		if operation == "rm" {
-			if wsDb.checkSubsciption(class, wm.roomId) {
-				wsDb.removeSubcription(class, wm.roomId)
-				return fmt.Sprintf("Hi %s 🤖 !\n\n Websocket subscription to MO/Class <code>%s</code> deleted 🔧 !", wm.sender, class)
+			if wsDb.checkSubsciption(class, wm.roomKey()) {
+				wsDb.removeSubcription(class, wm.roomKey())
+				return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n\n Websocket subscription to MO/Class <code>%s</code> deleted 🔧 !", wm.sender, class)}
 			} else {
-				return fmt.Sprintf("Hi %s 🤖 !\n\n You are not subscribed to MO/Class <code>%s</code>", wm.sender, class)
+				return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n\n You are not subscribed to MO/Class <code>%s</code>", wm.sender, class)}
 			}
 			// /websocket xxxx -> Add subscirption to this Room
 		} else {
-			if !wsDb.checkSubsciption(class, wm.roomId) {
-				id, err := c.SubscribeClassWebSocket(class)
+			if !wsDb.checkSubsciption(class, wm.roomKey()) {
+				var id string
+				var err error
+				// Subscribe to a MO (DN) or to all MOs of a class
+				if strings.Contains(class, "/") {
+					id, err = c.SubscribeMoWebSocket(ctx, class)
+				} else {
+					id, err = c.SubscribeClassWebSocket(ctx, class)
+				}
 				if err != nil {
-					return fmt.Sprintf("Hi %s 🤖 !\n Sorry... I could not subscribe to the class <code>%s</code>", wm.sender, class)
+					return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n Sorry... I could not subscribe to the class <code>%s</code>", wm.sender, class)}
 				}
-				wsDb.addSubcription(class, id, wm.roomId)
-				return fmt.Sprintf("Hi %s 🤖 !\n\n Websocket subscription to MO/Class <code>%s</code> configured 🔧 !", wm.sender, class)
+				wsDb.addSubcription(class, id, wm.roomKey())
+				return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n\n Websocket subscription to MO/Class <code>%s</code> configured 🔧 !", wm.sender, class)}
 			} else {
-				return fmt.Sprintf("Hi %s 🤖 !\n\n You are already subscribed to MO/Class <code>%s</code>", wm.sender, class)
+				return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n\n You are already subscribed to MO/Class <code>%s</code>", wm.sender, class)}
 			}
 
 		}
@@ -139,81 +249,90 @@ func websocketCommand(wsDb *webSocketDb) Callback {
 }
 
 // /event handler
-func eventCommand(c apic.ApicInterface, m Message, wm WebexMessage) string {
+func eventCommand(ctx context.Context, c apic.ApicInterface, m Message, wm ChatMessage) Reply {
 	res := ""
-	indMap := map[string]string{"creation": "❇️", "modification": "🔄", "deletion": "🗑"}
 	events := splitFaultsAndEnvents(m.cmd)
 
 	var err error
-	var info []apic.ApicMoAttributes
+	var info []apic.AaaModLR
 
 	if user, ok := events["user"]; ok {
-		info, err = c.GetLatestEvents(events["count"], user)
+		info, err = c.GetLatestEvents(ctx, events["count"], user)
 	} else {
-		info, err = c.GetLatestEvents(events["count"])
+		info, err = c.GetLatestEvents(ctx, events["count"])
 	}
 
 	if err != nil {
 		log.Printf("Error while connecting to the Apic. Err: %s", err)
-		return fmt.Sprintf("Hi %s 🤖 !. I could not reach the APIC... Are there any issues?", wm.sender)
+		return Reply{text: fmt.Sprintf("Hi %s 🤖 !. I could not reach the APIC... Are there any issues?", wm.sender)}
 	}
 
 	if len(info) == 0 {
-		return fmt.Sprintf("Hi %s 🤖 !. There are no events", wm.sender)
+		return Reply{text: fmt.Sprintf("Hi %s 🤖 !. There are no events", wm.sender)}
 	}
 
 	res += fmt.Sprintf("\nThese are the latest %s events in the the Fabric : \n\n", events["count"])
 
 	res += "<ul>"
 	for _, f := range info {
-		res += fmt.Sprintf("<li><strong>%s</strong> - <em>%s</em>", f["code"], f["affected"])
+		res += fmt.Sprintf("<li><strong>%s</strong> - <em>%s</em>", f.Code, f.Affected)
 		res += "<ul>"
-		res += fmt.Sprintf("<li>%s</li>", f["descr"])
-		res += fmt.Sprintf("<li><strong>User</strong>: %s</li>", f["user"])
-		res += fmt.Sprintf("<li><strong>Type</strong>: %s %s</li>", f["ind"], indMap[f["ind"]])
-		res += fmt.Sprintf("<li><strong>Created</strong>: %s</li>", f["created"])
+		res += fmt.Sprintf("<li>%s</li>", f.Descr)
+		res += fmt.Sprintf("<li><strong>User</strong>: %s</li>", f.User)
+		res += fmt.Sprintf("<li><strong>Type</strong>: %s %s</li>", f.Ind, indMap[f.Ind])
+		res += fmt.Sprintf("<li><strong>Created</strong>: %s</li>", f.Created)
 		res += "</ul>"
 	}
 	res += "</ul>"
-	return fmt.Sprintf("Hi %s 🤖 !\n\n%s", wm.sender, res)
+	return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n\n%s", wm.sender, res), data: info, card: eventsCard(info)}
 }
 
-// /fault handler
-func faultCommand(c apic.ApicInterface, m Message, wm WebexMessage) string {
+// /faults handler
+// /faults ack <fault_dn> -> Acknowledge a fault. Acknowledgements are confirmed and audited like the other changes
+func faultsCommand(cs *changeStore) Callback {
+	return func(ctx context.Context, c apic.ApicInterface, m Message, wm ChatMessage) Reply {
+		if dn, ok := splitFaultAckCommand(m.cmd)["ack"]; ok {
+			payload := moPayload("faultInst", map[string]string{"dn": dn, "ack": "yes"})
+			return requestChange(cs, wm, fmt.Sprintf("Acknowledge the fault %s", dn), dn, payload)
+		}
+		return faultCommand(ctx, c, m, wm)
+	}
+}
+
+// Latest faults
+func faultCommand(ctx context.Context, c apic.ApicInterface, m Message, wm ChatMessage) Reply {
 	res := ""
-	sevMap := map[string]string{"critical": "📛", "major": "☢️", "minor": "⚠️", "warning": "🌀", "cleared": "❎"}
-	lcMap := map[string]string{"soaking": "♻️", "retaining": "✅", "raised": "❌", "soaking-clearing": "♻️", "raised-clearing": "♻️"}
 	faults := splitFaultsAndEnvents(m.cmd)
 
-	info, err := c.GetLatestFaults(faults["count"])
+	info, err := c.GetLatestFaults(ctx, faults["count"])
 
 	if err != nil {
 		log.Printf("Error while connecting to the Apic. Err: %s", err)
-		return fmt.Sprintf("Hi %s 🤖 !. I could not reach the APIC... Are there any issues?", wm.sender)
+		return Reply{text: fmt.Sprintf("Hi %s 🤖 !. I could not reach the APIC... Are there any issues?", wm.sender)}
 	}
 
 	res += fmt.Sprintf("\nThese are the latest %s faults in the the Fabric : \n\n", faults["count"])
 
 	res += "<ul>"
 	for _, f := range info {
-		res += fmt.Sprintf("<li><strong>%s</strong> - <em>%s</em>", f["code"], f["dn"])
+		res += fmt.Sprintf("<li><strong>%s</strong> - <em>%s</em>", f.Code, f.Dn)
 		res += "<ul>"
-		res += fmt.Sprintf("<li>%s</li>", f["descr"])
-		res += fmt.Sprintf("<li><strong>Severity</strong>: %s %s</li>", f["severity"], sevMap[f["severity"]])
-		res += fmt.Sprintf("<li><strong>Current Lyfecycle</strong>: %s %s</li>", f["lc"], lcMap[f["lc"]])
-		res += fmt.Sprintf("<li><strong>Type</strong>: %s</li>", f["type"])
-		res += fmt.Sprintf("<li><strong>Created</strong>: %s</li>", f["created"])
+		res += fmt.Sprintf("<li>%s</li>", f.Descr)
+		res += fmt.Sprintf("<li><strong>Severity</strong>: %s %s</li>", f.Severity, sevMap[f.Severity])
+		res += fmt.Sprintf("<li><strong>Current Lyfecycle</strong>: %s %s</li>", f.Lc, lcMap[f.Lc])
+		res += fmt.Sprintf("<li><strong>Type</strong>: %s</li>", f.Type)
+		res += fmt.Sprintf("<li><strong>Created</strong>: %s</li>", f.Created)
 		res += "</ul>"
 	}
 	res += "</ul>"
-	return fmt.Sprintf("Hi %s 🤖 !\n\n%s", wm.sender, res)
+	return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n\n%s", wm.sender, res), data: info, card: faultsCard(info)}
 }
 
 // /neigh handler
-func neighCommand(c apic.ApicInterface, m Message, wm WebexMessage) string {
+func neighCommand(ctx context.Context, c apic.ApicInterface, m Message, wm ChatMessage) Reply {
 	res := ""
 	neighId := splitNeighCommand(m.cmd)
-	info, err := c.GetFabricNeighbors(neighId["neigh"])
+	info, err := c.GetFabricNeighbors(ctx, neighId["neigh"])
 
 	// Sort by Neigh Name
 	keys := make([]string, 0, len(info))
@@ -224,13 +343,13 @@ func neighCommand(c apic.ApicInterface, m Message, wm WebexMessage) string {
 
 	if err != nil {
 		log.Printf("Error while connecting to the Apic. Err: %s", err)
-		return fmt.Sprintf("Hi %s 🤖 !. I could not reach the APIC... Are there any issues?", wm.sender)
+		return Reply{text: fmt.Sprintf("Hi %s 🤖 !. I could not reach the APIC... Are there any issues?", wm.sender)}
 	}
 
 	if len(info) == 0 && neighId["neigh"] != "all" {
-		return fmt.Sprintf("Hi %s 🤖 !\n It seems there are no Neighbors for Node <code>%s</code>", wm.sender, neighId["neigh"])
+		return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n It seems there are no Neighbors for Node <code>%s</code>", wm.sender, neighId["neigh"])}
 	} else if len(info) == 0 && neighId["neigh"] == "all" {
-		return fmt.Sprintf("Hi %s 🤖 !\n Sorry.. I could not discover the Topology of the Fabric", wm.sender)
+		return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n Sorry.. I could not discover the Topology of the Fabric", wm.sender)}
 	}
 
 	if neighId["neigh"] == "all" {
@@ -248,17 +367,151 @@ func neighCommand(c apic.ApicInterface, m Message, wm WebexMessage) string {
 		res += "</li>"
 	}
 	res += "</ul>"
-	return fmt.Sprintf("Hi %s 🤖 !\n\n%s", wm.sender, res)
+	return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n\n%s", wm.sender, res), data: info, card: neighCard(neighId["neigh"], info)}
+}
+
+// /health [interval] handler
+func healthCommand(ctx context.Context, c apic.ApicInterface, m Message, wm ChatMessage) Reply {
+	res := ""
+	intvMap := map[string]string{"15m": "15min", "1h": "1h", "1d": "1d"}
+	interval := splitHealthCommand(m.cmd)["interval"]
+	info, err := c.GetHealthHistory(ctx, intvMap[interval])
+
+	if err != nil {
+		log.Printf("Error while connecting to the Apic. Err: %s", err)
+		return Reply{text: fmt.Sprintf("Hi %s 🤖 !. I could not reach the APIC... Are there any issues?", wm.sender)}
+	}
+
+	if len(info.Fabric.Samples) == 0 {
+		return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n There is no health history available for the Fabric", wm.sender)}
+	}
+
+	res += fmt.Sprintf("\nThis is the health trend of the Fabric (<em>%s</em> intervals): \n\n", interval)
+	res += fmt.Sprintf("<ul><li><strong>Fabric</strong>: %s</li>", healthTrend(info.Fabric))
+	for _, group := range []struct {
+		title  string
+		series []apic.HealthSeries
+	}{{"Pods", info.Pods}, {"Tenants", info.Tenants}, {"Nodes", info.Nodes}} {
+		if len(group.series) == 0 {
+			continue
+		}
+		res += fmt.Sprintf("<li><strong>Worst %s</strong><ul>", group.title)
+		for idx, hs := range group.series {
+			// Only the worst offenders
+			if idx == 3 {
+				break
+			}
+			res += fmt.Sprintf("<li><code>%s</code>: %s</li>", hs.Name, healthTrend(hs))
+		}
+		res += "</ul></li>"
+	}
+	res += "</ul>"
+	return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n\n%s", wm.sender, res), data: info}
+}
+
+// /node <node_id> handler
+func nodeCommand(ctx context.Context, c apic.ApicInterface, m Message, wm ChatMessage) Reply {
+	res := ""
+	nodeId := splitNodeCommand(m.cmd)["node"]
+	info, err := c.GetNodeInformation(ctx, nodeId)
+
+	if err != nil {
+		log.Printf("Error while connecting to the Apic. Err: %s", err)
+		return Reply{text: fmt.Sprintf("Hi %s 🤖 !. I could not reach the APIC... Are there any issues?", wm.sender)}
+	}
+
+	if info.Id == "" {
+		return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n It seems there is no Node <code>%s</code> in the Fabric", wm.sender, nodeId)}
+	}
+
+	res += fmt.Sprintf("\nThis is the information of the Node <code>%s</code> (%s): \n\n", info.Id, info.Name)
+	res += fmt.Sprintf("<ul><li>Current Health Score: <strong>%s</strong></li>", info.Health)
+	res += fmt.Sprintf("<li><strong>Role</strong>: %s - <strong>Pod</strong>: %s</li>", info.Role, info.Pod)
+	res += fmt.Sprintf("<li><strong>Model</strong>: %s - <strong>Serial</strong>: %s</li>", info.Model, info.Serial)
+	res += fmt.Sprintf("<li><strong>Firmware</strong>: %s</li>", info.Firmware)
+	res += fmt.Sprintf("<li><strong>TEP Address</strong>: %s</li>", info.Address)
+	res += fmt.Sprintf("<li><strong>Fabric State</strong>: %s</li>", info.FabricSt)
+	res += fmt.Sprintf("<li><strong>Uptime</strong>: %s</li>", info.Uptime)
+	res += "<li><strong>Supervisors</strong><ul>"
+	for _, item := range info.Supervisors {
+		res += fmt.Sprintf("<li>Slot %s %s (<strong>%s</strong>)</li>", item["id"], item["model"], item["status"])
+	}
+	res += "</ul></li><li><strong>Fans</strong><ul>"
+	for _, item := range info.Fans {
+		res += fmt.Sprintf("<li>Fan %s (<strong>%s</strong>)</li>", item["id"], item["status"])
+	}
+	res += "</ul></li><li><strong>Power Supplies</strong><ul>"
+	for _, item := range info.Psus {
+		res += fmt.Sprintf("<li>PSU %s %s (<strong>%s</strong>)</li>", item["id"], item["model"], item["status"])
+	}
+	res += "</ul></li>"
+	res += fmt.Sprintf("<li><strong>Active Faults</strong>: %d<ul>", len(info.Faults))
+	for _, f := range info.Faults {
+		res += fmt.Sprintf("<li><strong>%s</strong> %s %s - <em>%s</em></li>", f.Code, f.Severity, sevMap[f.Severity], f.Descr)
+	}
+	res += "</ul></li></ul>"
+	return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n\n%s", wm.sender, res), data: info, card: nodeCard(info)}
+}
+
+// /iface <node_id> [iface] handler
+func ifaceCommand(ctx context.Context, c apic.ApicInterface, m Message, wm ChatMessage) Reply {
+	res := ""
+	stMap := map[string]string{"up": "✅", "down": "❌"}
+	iface := splitIfaceCommand(m.cmd)
+
+	var err error
+	var info []apic.InterfaceInformation
+
+	if count, ok := iface["top"]; ok {
+		info, err = c.GetTopErrorInterfaces(ctx, count)
+	} else {
+		info, err = c.GetInterfaceInformation(ctx, iface["node"], iface["iface"])
+	}
+
+	if err != nil {
+		log.Printf("Error while connecting to the Apic. Err: %s", err)
+		return Reply{text: fmt.Sprintf("Hi %s 🤖 !. I could not reach the APIC... Are there any issues?", wm.sender)}
+	}
+
+	if count, ok := iface["top"]; ok {
+		if len(info) == 0 {
+			return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n There are no interfaces with errors in the Fabric", wm.sender)}
+		}
+		res += fmt.Sprintf("\nThese are the top %s interfaces with errors in the Fabric : \n\n", count)
+	} else {
+		if len(info) == 0 {
+			return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n It seems there are no Interfaces matching <code>%s %s</code>", wm.sender, iface["node"], iface["iface"])}
+		}
+		res += fmt.Sprintf("\nThese are the Interfaces of the Node <code>%s</code>: \n\n", iface["node"])
+	}
+
+	res += "<ul>"
+	for _, i := range info {
+		res += fmt.Sprintf("<li><strong>%s</strong> - <em>Node %s</em>", i.Id, i.Node)
+		res += "<ul>"
+		res += fmt.Sprintf("<li><strong>State</strong>: admin %s %s / oper %s %s</li>", i.AdminSt, stMap[i.AdminSt], i.OperSt, stMap[i.OperSt])
+		res += fmt.Sprintf("<li><strong>Speed</strong>: %s</li>", i.Speed)
+		res += fmt.Sprintf("<li><strong>Last Link Change</strong>: %s</li>", i.LastLinkStChg)
+		res += fmt.Sprintf("<li><strong>Errors</strong>: CRC %s / Input %s / Output %s</li>", i.CrcErrors, i.InputErrors, i.OutputErrors)
+		res += fmt.Sprintf("<li><strong>Utilization</strong>: In %s%% / Out %s%%</li>", i.InputUtil, i.OutputUtil)
+		res += "</ul></li>"
+	}
+	res += "</ul>"
+	return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n\n%s", wm.sender, res), data: info}
 }
 
 // /ep <ep_mac> handler
-func endpointCommand(c apic.ApicInterface, m Message, wm WebexMessage) string {
+func endpointCommand(ctx context.Context, c apic.ApicInterface, m Message, wm ChatMessage) Reply {
 
 	res := ""
-	info, err := c.GetEndpointInformation(splitEpCommand(m.cmd)["mac"])
+	// /ep <ep_mac> history -> Latest events of the endpoint
+	if _, ok := splitEpCommand(m.cmd)["history"]; ok {
+		return endpointHistoryCommand(ctx, c, m, wm)
+	}
+	info, err := c.GetEndpointInformation(ctx, splitEpCommand(m.cmd)["mac"])
 	if err != nil {
 		log.Printf("Error while connecting to the Apic. Err: %s", err)
-		return fmt.Sprintf("Hi %s 🤖 !. I could not reach the APIC... Are there any issues?", wm.sender)
+		return Reply{text: fmt.Sprintf("Hi %s 🤖 !. I could not reach the APIC... Are there any issues?", wm.sender)}
 
 	}
 	res = res + fmt.Sprintf("\nThis is the information for the Endpoint <code>%s</code>", splitEpCommand(m.cmd)["mac"])
@@ -286,17 +539,40 @@ func endpointCommand(c apic.ApicInterface, m Message, wm WebexMessage) string {
 		}
 	}
 	res = res + "</ul>"
-	return fmt.Sprintf("Hi %s 🤖 !\n\n%s", wm.sender, res)
+	return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n\n%s", wm.sender, res), data: info, card: endpointCard(splitEpCommand(m.cmd)["mac"], info)}
+}
+
+// /ep <ep_mac> history handler
+func endpointHistoryCommand(ctx context.Context, c apic.ApicInterface, m Message, wm ChatMessage) Reply {
+	res := ""
+	mac := splitEpCommand(m.cmd)["mac"]
+	info, err := c.GetEndpointHistory(ctx, mac)
+	if err != nil {
+		log.Printf("Error while connecting to the Apic. Err: %s", err)
+		return Reply{text: fmt.Sprintf("Hi %s 🤖 !. I could not reach the APIC... Are there any issues?", wm.sender)}
+	}
+
+	if len(info) == 0 {
+		return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n There is no history for the Endpoint <code>%s</code>", wm.sender, mac)}
+	}
+
+	res += fmt.Sprintf("\nThis is the history of the Endpoint <code>%s</code>: \n\n", mac)
+	res += "<ul>"
+	for _, e := range info {
+		res += fmt.Sprintf("<li><strong>%s</strong> - %s (<em>%s</em>)</li>", e.Created, e.Descr, e.Code)
+	}
+	res += "</ul>"
+	return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n\n%s", wm.sender, res), data: info}
 }
 
 // /info handler
-func infoCommand(c apic.ApicInterface, m Message, wm WebexMessage) string {
+func infoCommand(ctx context.Context, c apic.ApicInterface, m Message, wm ChatMessage) Reply {
 	res := ""
-	info, err := c.GetFabricInformation()
+	info, err := c.GetFabricInformation(ctx)
 
 	if err != nil {
 		log.Printf("Error while connecting to the Apic. Err: %s", err)
-		return fmt.Sprintf("Hi %s 🤖 !. I could not reach the APIC... Are there any issues?", wm.sender)
+		return Reply{text: fmt.Sprintf("Hi %s 🤖 !. I could not reach the APIC... Are there any issues?", wm.sender)}
 
 	}
 	res = res + fmt.Sprintf("\nThis is the general information of the Fabric <code>%s</code> (%s): \n\n", info.Name, info.Url)
@@ -305,41 +581,91 @@ func infoCommand(c apic.ApicInterface, m Message, wm WebexMessage) string {
 	for _, item := range info.Apics {
 		res = res + "<li>" + item["name"] + " (<strong>" + item["version"] + "</strong>)</li>"
 	}
-	res = res + "</ul><li><strong>Pods</strong><ul>"
+	res = res + "</ul></li><li><strong>Pods</strong><ul>"
 	for _, item := range info.Pods {
 		res = res + "<li>Pod" + item["id"] + " <em>" + item["type"] + "</em></li>"
 	}
-	res = res + "</ul><li></strong>Switches</strong><ul>"
+	res = res + "</ul></li><li><strong>Switches</strong><ul>"
 	res = res + fmt.Sprintf("<li># of Spines : <strong>%d</strong></li>", len(info.Spines))
 	res = res + fmt.Sprintf("<li># of Leafs : <strong>%d</strong></li>", len(info.Leafs))
-	res = res + "</ul></ul></li>"
-	return fmt.Sprintf("Hi %s 🤖 !\n\n%s", wm.sender, res)
+	res = res + "</ul></li></ul>"
+	return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n\n%s", wm.sender, res), data: info, card: infoCard(info)}
 }
 
 // /cpu handler
-func cpuCommand(c apic.ApicInterface, m Message, wm WebexMessage) string {
+func cpuCommand(ctx context.Context, c apic.ApicInterface, m Message, wm ChatMessage) Reply {
 	res := ""
-	cpu, err := c.GetProcEntity()
+	cpu, err := c.GetProcEntity(ctx)
 
 	if err != nil {
 		log.Printf("Error while connecting to the Apic. Err: %s", err)
-		return fmt.Sprintf("Hi %s 🤖 !. I could not reach the APIC... Are there any issues?", wm.sender)
+		return Reply{text: fmt.Sprintf("Hi %s 🤖 !. I could not reach the APIC... Are there any issues?", wm.sender)}
 	}
 	res = res + "\nThis is the CPU information of the controllers: \n\n"
 	res = res + "<ul>"
 
 	for _, item := range cpu {
-		memFree, _ := strconv.ParseFloat(item["memFree"], 32)
-		memMax, _ := strconv.ParseFloat(item["maxMemAlloc"], 32)
-		res = res + fmt.Sprintf("<li><code>APIC %s</code> -> \t💻 <strong>CPU: </strong>%s\t💾 <strong>Memory %%: </strong> %f</li>", apic.GetRn(item["dn"], "node"), item["cpuPct"], 100.0*memFree/memMax)
+		memFree, _ := strconv.ParseFloat(item.MemFree, 32)
+		memMax, _ := strconv.ParseFloat(item.MaxMemAlloc, 32)
+		res = res + fmt.Sprintf("<li><code>APIC %s</code> -> \t💻 <strong>CPU: </strong>%s\t💾 <strong>Memory %%: </strong> %f</li>", apic.GetRn(item.Dn, "node"), item.CpuPct, 100.0*memFree/memMax)
 	}
 	res = res + "</ul>"
-	return fmt.Sprintf("Hi %s 🤖 !\n\n	%s", wm.sender, res)
+	return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n\n	%s", wm.sender, res), data: cpu, card: cpuCard(cpu)}
+}
+
+// /mention handler
+// Mentions are required in group rooms unless they are turned off for the room
+func mentionCommand(rs *roomSettings) Callback {
+	return func(ctx context.Context, c apic.ApicInterface, m Message, wm ChatMessage) Reply {
+		switch splitMentionCommand(m.cmd)["mode"] {
+		case "on":
+			rs.setAnswersWithoutMention(wm.roomKey(), false)
+		case "off":
+			rs.setAnswersWithoutMention(wm.roomKey(), true)
+		}
+		if rs.answersWithoutMention(wm.roomKey()) {
+			return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n I answer every message of this room, even without a mention 🔕", wm.sender)}
+		}
+		return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n I only answer the messages of this room which mention me 🔔", wm.sender)}
+	}
+}
+
+// APIC client caching its results. See apic.CachedClient
+type apicCache interface {
+	Flush() int
+	Invalidate(class, dn string) int
+	Stats() apic.CacheStats
+}
+
+// /cache handler
+// /cache flush is an administration command. See SetAdmins()
+func cacheCommand(adm *admins) Callback {
+	return func(ctx context.Context, c apic.ApicInterface, m Message, wm ChatMessage) Reply {
+		cache, ok := c.(apicCache)
+		if !ok {
+			return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n The APIC cache is disabled", wm.sender)}
+		}
+		if splitCacheCommand(m.cmd)["op"] == "flush" {
+			if !adm.isAdmin(wm.personId) {
+				return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n Sorry... Only the administrators of the bot can flush the APIC cache", wm.sender)}
+			}
+			n := cache.Flush()
+			log.Printf("APIC cache flushed by %s. %d results dropped", wm.sender, n)
+			return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n APIC cache flushed 🧹. %d results dropped", wm.sender, n), data: map[string]int{"flushed": n}}
+		}
+		st := cache.Stats()
+		ratio := 0.0
+		if st.Hits+st.Misses > 0 {
+			ratio = float64(st.Hits) * 100 / float64(st.Hits+st.Misses)
+		}
+		res := fmt.Sprintf("Hi %s 🤖 !\n APIC cache 🗄️:<ul><li><strong>Results</strong>: %d</li><li><strong>Hits</strong>: %d</li><li><strong>Misses</strong>: %d</li><li><strong>Hit ratio</strong>: %.1f%%</li></ul>", wm.sender, st.Entries, st.Hits, st.Misses, ratio)
+		return Reply{text: res, data: st}
+	}
 }
 
 // /help handler
 func helpCommand(cmd map[string]Command) Callback {
-	return func(a apic.ApicInterface, m Message, wm WebexMessage) string {
+	return func(ctx context.Context, a apic.ApicInterface, m Message, wm ChatMessage) Reply {
 
 		keys := make([]string, 0, len(cmd))
 		for k := range cmd {
@@ -352,8 +678,8 @@ func helpCommand(cmd map[string]Command) Callback {
 		for _, k := range keys {
 			res = res + fmt.Sprintf("<li><code>%s</code>\t->\t%s</li>", k, cmd[k].help)
 		}
-		res = res + "<ul>"
-		return res
+		res = res + "</ul>"
+		return Reply{text: res}
 	}
 }
 
@@ -387,7 +713,7 @@ func aboutMeHandler(wbx webex.WebexInterface) http.HandlerFunc {
 
 // /webhook handler
 // TODO: Separate by method (GET, POST, PUT)
-func webhookHandler(wbx webex.WebexInterface, ap apic.ApicInterface, cmd map[string]Command, b webex.WebexPeople) http.HandlerFunc {
+func webhookHandler(wbx webex.WebexInterface, ap apic.ApicInterface, cmd map[string]Command, b webex.WebexPeople, rs *roomSettings, wp *workerPool, wc *webhookCache) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Receiving %s on /webhook URI", r.Method)
 		// Parse incoming webhook. From which room does it come  from?
@@ -397,59 +723,231 @@ func webhookHandler(wbx webex.WebexInterface, ap apic.ApicInterface, cmd map[str
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
-		// Retrieve the last message, it should not have been written by the bot
-		message, err := wbx.GetMessageById(wh.Data.Id)
-		if err != nil {
-			log.Printf("failed trying to retrieve the last message. Error %s", err)
+		if wh.Data == nil {
+			log.Printf("incoming webhook %s does not carry any data", wh.Name)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(processMessage(wbx, ap, cmd, b, rs, wp, wc, *wh.Data))
+	})
+}
+
+// /actions handler
+// Cards submit the command to execute in the action inputs
+func actionsHandler(wbx webex.WebexInterface, ap apic.ApicInterface, cmd map[string]Command, b webex.WebexPeople, wp *workerPool, wc *webhookCache) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("Receiving %s on /actions URI", r.Method)
+		wh := webex.WebexWebhook{}
+		if err := parseWebHook(&wh, r); err != nil {
+			log.Printf("failed to parse incoming webhook. Error %s", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
-		// Is the message send from someone who is not the bot
-		if message.PersonId != b.Id {
-			// Get sender personal information
-			sender, _ := wbx.GetPersonInformation(message.PersonId)
-			found := false
-			// Check which command was sent in the webex room
-			messageText := cleanCommand(b.DisplayName, message.Text)
-			for cli, element := range cmd {
-				if MatchCommand(messageText, element.regex) {
-					// Send message back the text is returned from the commandHandler
-					wbx.SendMessageToRoom(element.callback(ap, Message{cmd: messageText}, WebexMessage{sender: sender.NickName, roomId: message.RoomId}), wh.Data.RoomId)
-					found = true
-					w.WriteHeader(http.StatusOK)
-					return
-				}
-				// Matches the first word but the arguments does not fit. Send back the usage
-				if MatchCommand(messageText, element.suffix) {
-					wbx.SendMessageToRoom(fmt.Sprintf("Hi %s 🤖 \n I could not fully understand the input\n Please check the usage of the <code>%s</code> command:\n <ul><li>%s</ul></li>\n", sender.NickName, cli, element.help), wh.Data.RoomId)
-					found = true
-					w.WriteHeader(http.StatusOK)
-					return
-				}
-			}
-			// If command sent does not match anything, send back the help menu
-			if !found {
-				wbx.SendMessageToRoom(cmd["/help"].callback(ap, Message{cmd: messageText}, WebexMessage{sender: sender.NickName, roomId: message.RoomId}), wh.Data.RoomId)
-				w.WriteHeader(http.StatusOK)
-				return
-			}
+		if wh.Data == nil {
+			log.Printf("incoming webhook %s does not carry any data", wh.Name)
+			w.WriteHeader(http.StatusBadRequest)
+			return
 		}
-		// To differentiate Webhooks triggered from the Bot
-		w.WriteHeader(http.StatusAccepted)
+		w.WriteHeader(processAction(wbx, ap, cmd, b, wp, wc, wh.Data.Id))
 	})
 }
 
+// Process a new message, received either from a webhook or from the Webex WebSocket
+// The events of the Webex WebSocket only carry the message ID. The room type and the mentions are then read from the message
+// Returns the HTTP status code used to answer the webhook
+func processMessage(wbx webex.WebexInterface, ap apic.ApicInterface, cmd map[string]Command, b webex.WebexPeople, rs *roomSettings, wp *workerPool, wc *webhookCache, data webex.WebexWebhookData) int {
+	id := data.Id
+	// Webex may redeliver the same webhook. Each one is processed once
+	if id != "" && !wc.firstSeen(id) {
+		log.Printf("webhook %s already processed. Ignoring it", id)
+		return http.StatusOK
+	}
+	// Retrieve the last message, it should not have been written by the bot
+	message, err := wbx.GetMessageById(id)
+	if err != nil {
+		log.Printf("failed trying to retrieve the last message. Error %s", err)
+		wc.forget(id)
+		return http.StatusInternalServerError
+	}
+	// Is the message send from someone who is not the bot
+	if message.PersonId != b.Id {
+		wm := ChatMessage{personId: message.PersonId, roomId: message.RoomId, parentId: threadId(message), transport: webexTransport{wbx}}
+		// Messages of group rooms are only answered if they mention the bot, unless the room turned it off
+		if data.RoomType == "" {
+			data.RoomType, data.MentionedPeople, data.MentionedGroups = message.RoomType, message.MentionedPeople, message.MentionedGroups
+		}
+		if data.RoomType == "group" && !isMentioned(b, data.MentionedPeople, data.MentionedGroups) && !rs.answersWithoutMention(wm.roomKey()) {
+			log.Printf("message %s does not mention the bot. Ignoring it", id)
+			return http.StatusOK
+		}
+		// Get sender personal information
+		sender, _ := wbx.GetPersonInformation(message.PersonId)
+		wm.sender = sender.NickName
+		// Check which command was sent in the webex room
+		dispatchCommand(ap, cmd, wp, messageCommand(b, message), wm)
+		return http.StatusOK
+	}
+	// To differentiate Webhooks triggered from the Bot
+	return http.StatusAccepted
+}
+
+// Process a new card action, received either from a webhook or from the Webex WebSocket
+// Returns the HTTP status code used to answer the webhook
+func processAction(wbx webex.WebexInterface, ap apic.ApicInterface, cmd map[string]Command, b webex.WebexPeople, wp *workerPool, wc *webhookCache, id string) int {
+	// Webex may redeliver the same webhook. Each one is processed once
+	if id != "" && !wc.firstSeen(id) {
+		log.Printf("webhook %s already processed. Ignoring it", id)
+		return http.StatusOK
+	}
+	// Retrieve the action submitted from the card
+	action, err := wbx.GetAttachmentActionById(id)
+	if err != nil {
+		log.Printf("failed trying to retrieve the card action. Error %s", err)
+		wc.forget(id)
+		return http.StatusInternalServerError
+	}
+	// Actions submitted by the bot itself are ignored
+	if action.PersonId == b.Id {
+		log.Printf("card action %s submitted by the bot. Ignoring it", action.Id)
+		return http.StatusOK
+	}
+	command, ok := action.Inputs["command"].(string)
+	if !ok {
+		log.Printf("card action %s does not carry any command", action.Id)
+		return http.StatusBadRequest
+	}
+	// The command is executed on behalf of the person who submitted the action
+	sender, _ := wbx.GetPersonInformation(action.PersonId)
+	// Reply in the thread of the card
+	wm := ChatMessage{sender: sender.NickName, personId: action.PersonId, roomId: action.RoomId, transport: webexTransport{wbx}}
+	if card, err := wbx.GetMessageById(action.MessageId); err == nil {
+		wm.parentId = threadId(card)
+	}
+	dispatchCommand(ap, cmd, wp, command, wm)
+	return http.StatusOK
+}
+
+// Find the command matching the text
+// Returns the name of the command, empty if none matches, and whether its arguments are valid
+func findCommand(cmd map[string]Command, text string) (string, bool) {
+	for cli, element := range cmd {
+		// Commands are anchored to the start of the message. Arguments (e.g. DNs) may contain other command names
+		// The name of a command may be the prefix of another one (/ep and /epg). The name must end with a space
+		if MatchCommand(text, "^"+element.regex) {
+			return cli, true
+		}
+		if MatchCommand(text, "^"+element.suffix+"( |$)") {
+			return cli, false
+		}
+	}
+	return "", false
+}
+
+// Execute the command matching the text and send the reply back to the room
+func dispatchCommand(ap apic.ApicInterface, cmd map[string]Command, wp *workerPool, text string, wm ChatMessage) {
+	// The export modifier is valid for every command
+	messageText, export := splitExportModifier(text)
+	cli, valid := findCommand(cmd, messageText)
+	if cli != "" {
+		element := cmd[cli]
+		if valid {
+			// The command is executed asynchronously. The webhook is acknowledged right away
+			m := Message{cmd: messageText, export: export}
+			job := func() { runCommand(ap, cli, element, m, wm) }
+			if !wp.submit(job) {
+				log.Printf("worker pool is full. Discarding command %s", messageText)
+				commandsTotal.Inc(cli, "busy")
+				wm.transport.SendText(fmt.Sprintf("Hi %s 🤖 !\n Sorry... I am busy right now. Please try again later ⏳", wm.sender), wm.roomId, wm.parentId)
+			}
+			return
+		}
+		// Matches the first word but the arguments does not fit. Send back the usage
+		commandsTotal.Inc(cli, "invalid")
+		wm.transport.SendText(fmt.Sprintf("Hi %s 🤖 \n I could not fully understand the input\n Please check the usage of the <code>%s</code> command:\n <ul><li>%s</ul></li>\n", wm.sender, cli, element.help), wm.roomId, wm.parentId)
+		return
+	}
+	// If command sent does not match anything, send back the help menu
+	// The text sent is not used as label, the number of series would be unbounded
+	commandsTotal.Inc("unknown", "invalid")
+	wm.transport.SendText(cmd["/help"].callback(context.Background(), ap, Message{cmd: messageText}, wm).text, wm.roomId, wm.parentId)
+}
+
+// Execute the callback of a command and send its reply
+// Slow commands post a placeholder message first, which is edited once the reply is available
+// Commands exceeding the commandTimeout deadline are cancelled
+// The metrics are labelled with the name the command is registered with
+func runCommand(ap apic.ApicInterface, name string, c Command, m Message, wm ChatMessage) {
+	// The commands failing to query the APIC are counted as errors
+	ctx, cancel := context.WithTimeout(apic.TrackFailures(context.Background()), commandTimeout)
+	defer cancel()
+	start, res := time.Now(), "ok"
+	defer func() {
+		if res == "ok" && apic.Failed(ctx) {
+			res = "error"
+		}
+		commandsTotal.Inc(name, res)
+		commandDuration.Observe(time.Since(start).Seconds(), name)
+	}()
+	if r, unavailable := unavailableReply(ap, m, wm); unavailable {
+		res = "unavailable"
+		sendReply(r, m, wm, wm.roomId)
+		return
+	}
+	done := make(chan Reply, 1)
+	go func() {
+		done <- c.callback(ctx, ap, m, wm)
+	}()
+	select {
+	case r := <-done:
+		sendReply(r, m, wm, wm.roomId)
+	case <-ctx.Done():
+		res = "timeout"
+		sendReply(timeoutReply(m, wm), m, wm, wm.roomId)
+	case <-time.After(slowCommandDelay):
+		placeholder, err := wm.transport.SendPlaceholder("Querying APIC… ⏳", wm.roomId, wm.parentId)
+		var r Reply
+		select {
+		case r = <-done:
+		case <-ctx.Done():
+			res = "timeout"
+			r = timeoutReply(m, wm)
+		}
+		if err != nil {
+			log.Printf("could not send the placeholder message. Err %s", err)
+			sendReply(r, m, wm, wm.roomId)
+			return
+		}
+		// Only the markdown of a message can be edited. The cards and files are sent as new replies,
+		// carrying the text of the reply. The placeholder then only points to them, not to show the text twice
+		if r.card != nil || (m.export != "" && r.data != nil) {
+			wm.transport.EditText(placeholder, "Querying APIC… ✅ See the reply below", wm.roomId)
+			sendReply(r, m, wm, wm.roomId)
+			return
+		}
+		wm.transport.EditText(placeholder, r.text, wm.roomId)
+	}
+}
+
 // Bot Methods
 func (b *Bot) routes() {
 	// TODO: is this fine?
 	b.router.HandleFunc("/about", aboutMeHandler(b.wbx))
 	b.router.HandleFunc("/test", testHandler)
-	b.router.HandleFunc("/webhook", webhookHandler(b.wbx, b.apic, b.commands, b.info))
+	b.router.HandleFunc("/metrics", metricsHandler(b.wsSubs, b.metricsToken))
+	b.router.HandleFunc("/webhook", webhookHandler(b.wbx, b.apic, b.commands, b.info, b.settings, b.workers, b.webhooks))
+	b.router.HandleFunc("/actions", actionsHandler(b.wbx, b.apic, b.commands, b.info, b.workers, b.webhooks))
+	if b.slack != nil {
+		b.router.HandleFunc("/slack/events", slackEventsHandler(b.slack, b.slackSecret, b.slackInfo, b.apic, b.commands, b.workers, b.webhooks))
+		b.router.HandleFunc("/slack/actions", slackActionsHandler(b.slack, b.slackSecret, b.apic, b.commands, b.workers))
+	}
+	if b.teams != nil {
+		b.router.HandleFunc("/api/messages", teamsHandler(b.teams, b.teamsRefs, b.apic, b.commands, b.workers, b.webhooks))
+	}
 	// b.router.HandleFunc("/webscket", websocketHandler(b.wbx, b.apic, b.info))
 }
-func (b *Bot) addCommand(cmd string, help string, suf string, re string, call Callback) {
+func addCommand(cmds map[string]Command, cmd string, help string, suf string, re string, call Callback) {
 	// add item to the dispatch table
-	b.commands[cmd] = Command{
+	cmds[cmd] = Command{
 		help:     help,
 		callback: call,
 		suffix:   suf,
@@ -476,6 +974,11 @@ func (b *Bot) setupWebhook() error {
 		log.Printf("could not create brand new webhook. Err %s", err)
 		return err
 	}
+	log.Printf("Creating brand new Card Actions Webhook Name: %s - URL: %s\n", b.info.DisplayName, b.url)
+	if err := b.wbx.CreateWebhook(b.info.DisplayName, b.url+"/actions", "attachmentActions", "created"); err != nil {
+		log.Printf("could not create brand new card actions webhook. Err %s", err)
+		return err
+	}
 	return nil
 }
 
@@ -486,16 +989,34 @@ func readWebsocket(b *Bot) {
 		subId, events := b.wsck.ReadSocketEvent()
 		className := b.wsSubs.getClassNamebySubId(subId)
 
+		// The cached results built from the changed objects are outdated
+		if cache, ok := b.apic.(apicCache); ok {
+			for _, event := range events {
+				class, _ := event["class"].(string)
+				dn, _ := event["dn"].(string)
+				cache.Invalidate(class, dn)
+			}
+		}
+		for _, event := range events {
+			class, _ := event["class"].(string)
+			websocketEvents.Inc(class)
+		}
+
 		msg := "<ul>"
 		for _, event := range events {
 			msg += fmt.Sprintf("<li>The object <code>%s</code> has been <strong>%s</strong></li> %s", event["dn"], event["status"], statusMap[event["status"].(string)])
 		}
 		msg += "</ul>"
 
-		for _, room := range b.wsSubs.getRoomsIdbyClass(className) {
-			roomInfo, _ := b.wbx.GetRoomById(room)
-			res := fmt.Sprintf("Hi %s 🤖\n Notification from the APIC %s \n %s", roomInfo.Title, b.apic.GetIp(), msg)
-			b.wbx.SendMessageToRoom(res, room)
+		// Rooms of every chat platform may be subscribed to the same class
+		for _, key := range b.wsSubs.getRoomsIdbyClass(className) {
+			name, room := splitRoomKey(key)
+			t, ok := b.transports[name]
+			if !ok {
+				continue
+			}
+			res := fmt.Sprintf("Hi %s 🤖\n Notification from the APIC %s \n %s", t.RoomName(room), b.apic.GetIp(), msg)
+			t.SendText(res, room, "")
 		}
 	}
 }
@@ -510,14 +1031,15 @@ func refreshApicClient(b *Bot, t int) {
 		select {
 		case <-tickerToken.C:
 			log.Printf("Refreshing REST APIC Token\n")
-			b.apic.Login()
+			err := b.apic.Login(context.Background())
+			tokenRefreshes.Inc(result(err))
 			log.Printf("Refreshing Websocket APIC Token")
 			b.wsck.NewDial(b.apic.GetToken())
 
 		case <-tickerWs.C:
 			for class, subId := range b.wsSubs.getActiveSubscriptions() {
 				log.Printf("Refreshing subscription %s - %s", class, subId)
-				b.apic.RefreshSubscriptionWebSocket(subId)
+				b.apic.RefreshSubscriptionWebSocket(context.Background(), subId)
 			}
 		}
 	}
@@ -525,7 +1047,7 @@ func refreshApicClient(b *Bot, t int) {
 
 func (b *Bot) SetupWebSocket() error {
 
-	wsck, err := apic.NewApicWebSClient(b.apic.GetIp(), b.apic.GetToken())
+	wsck, err := apic.NewApicWebSClient(b.apic.GetIp(), b.apic.GetToken(), b.apic.GetDialer())
 	if err != nil {
 		b.wsck = nil
 		return err
@@ -536,6 +1058,43 @@ func (b *Bot) SetupWebSocket() error {
 	return nil
 }
 
+// Receive the messages and card actions over the Webex WebSocket and dispatch them like the webhooks
+// The connection is restored if it drops. Blocks until the context is cancelled
+func (b *Bot) ListenWebexDevice(ctx context.Context) error {
+
+	device, err := b.wbx.RegisterDevice(b.info.DisplayName)
+	if err != nil {
+		log.Printf("could not register the Webex device. Err %s", err)
+		return err
+	}
+	events := make(chan webex.WebexEvent)
+	go func() {
+		defer close(events)
+		for {
+			log.Printf("Listening to Webex events on %s", device.WebSocketUrl)
+			err := b.wbx.ListenEvents(ctx, device, events)
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Webex websocket connection lost. Err %s", err)
+			select {
+			case <-time.After(reconnectDelay):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for ev := range events {
+		switch ev.Resource {
+		case "messages":
+			processMessage(b.wbx, b.apic, b.commands, b.info, b.settings, b.workers, b.webhooks, webex.WebexWebhookData{Id: ev.Id})
+		case "attachmentActions":
+			processAction(b.wbx, b.apic, b.commands, b.info, b.workers, b.webhooks, ev.Id)
+		}
+	}
+	return ctx.Err()
+}
+
 func (b *Bot) Start(addr string) error {
 	// Start the http server
 	b.server = &http.Server{
@@ -4,6 +4,7 @@ import (
 	"aci-chatbot/apic"
 	"aci-chatbot/webex"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,7 +14,9 @@ import (
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 // Helper function
@@ -104,6 +107,7 @@ func TestTestHandler(t *testing.T) {
 		request, _ := http.NewRequest(http.MethodGet, "/test", nil)
 		response := httptest.NewRecorder()
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 
 		equals(t, response.Code, http.StatusOK)
 		equals(t, response.Body.String(), "I am alive!")
@@ -121,6 +125,7 @@ func TestAboutMeHandler(t *testing.T) {
 		request, _ := http.NewRequest(http.MethodGet, "/about", nil)
 		response := httptest.NewRecorder()
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 
 		exp, _ := wmc.GetBotDetailsF()
 		expByte, _ := json.Marshal(exp)
@@ -138,6 +143,7 @@ func TestAboutMeHandler(t *testing.T) {
 		request, _ := http.NewRequest(http.MethodGet, "/about", nil)
 		response := httptest.NewRecorder()
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 
 		equals(t, response.Code, http.StatusInternalServerError)
 	})
@@ -162,6 +168,7 @@ func TestWebHookHanlderGeneral(t *testing.T) {
 		response := httptest.NewRecorder()
 
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusInternalServerError)
 		equals(t, wmc.LastMsgSent, "")
 	})
@@ -186,6 +193,7 @@ func TestWebHookHanlderGeneral(t *testing.T) {
 		response := httptest.NewRecorder()
 
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusInternalServerError)
 		equals(t, wmc.LastMsgSent, "")
 	})
@@ -212,11 +220,177 @@ func TestWebHookHanlderGeneral(t *testing.T) {
 		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
 		response := httptest.NewRecorder()
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusAccepted)
 
 	})
 }
 
+func TestWebHookHanlderThreadedReplies(t *testing.T) {
+	wmc := webex.WebexMockClient
+	wmc.SetDefaultFunctions()
+	amc := apic.ApicMockClient
+	amc.SetDefaultFunctions()
+	b, _ := NewBot(&wmc, &amc, "http://test_bot.com")
+	reqB := webex.WebexWebhook{
+		Name: "test-bot",
+		Data: &webex.WebexWebhookData{
+			RoomId: "AbC13",
+		},
+	}
+
+	t.Run("Reply threaded under the command", func(t *testing.T) {
+		wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
+			return webex.WebexMessage{Id: "M1", Text: "/health", RoomId: "AbC13"}, nil
+		}
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		b.workers.wait()
+		equals(t, response.Code, http.StatusOK)
+		equals(t, wmc.LastParentId, "M1")
+	})
+	t.Run("Command sent in a thread", func(t *testing.T) {
+		wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
+			return webex.WebexMessage{Id: "M2", ParentId: "M1", Text: "/health", RoomId: "AbC13"}, nil
+		}
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		b.workers.wait()
+		equals(t, response.Code, http.StatusOK)
+		equals(t, wmc.LastParentId, "M1")
+	})
+	t.Run("Slow command edits the placeholder", func(t *testing.T) {
+		defer func(d time.Duration) { slowCommandDelay = d }(slowCommandDelay)
+		slowCommandDelay = 0
+		amc.GetHealthHistoryF = func(ctx context.Context, g string) (apic.HealthHistory, error) {
+			time.Sleep(20 * time.Millisecond)
+			return apic.HealthHistory{}, errors.New("Generic APIC Error")
+		}
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		b.workers.wait()
+		equals(t, response.Code, http.StatusOK)
+		equals(t, wmc.LastEditedId, "MockMessageId")
+		equals(t, wmc.LastMsgSent, "Hi  🤖 !. I could not reach the APIC... Are there any issues?")
+	})
+	t.Run("Slow command with a card", func(t *testing.T) {
+		defer func(d time.Duration) { slowCommandDelay = d }(slowCommandDelay)
+		slowCommandDelay = 0
+		wmc.LastEditedId = ""
+		wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
+			return webex.WebexMessage{Id: "M1", Text: "/info", RoomId: "AbC13"}, nil
+		}
+		edited := ""
+		editMessage := wmc.EditMessageF
+		wmc.EditMessageF = func(id, m, roomId string) error {
+			edited = m
+			return editMessage(id, m, roomId)
+		}
+		defer func() { wmc.EditMessageF = editMessage }()
+		getInfo := amc.GetFabricInformationF
+		amc.GetFabricInformationF = func(ctx context.Context) (apic.FabricInformation, error) {
+			time.Sleep(20 * time.Millisecond)
+			return getInfo(ctx)
+		}
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		b.workers.wait()
+		equals(t, response.Code, http.StatusOK)
+		equals(t, wmc.LastCardSent != nil, true)
+		equals(t, wmc.LastParentId, "M1")
+		equals(t, wmc.LastEditedId, "MockMessageId")
+		// The text of the reply is only sent with the card, the placeholder points to it
+		equals(t, edited, "Querying APIC… ✅ See the reply below")
+		equals(t, strings.Contains(wmc.LastMsgSent, "This is the general information of the Fabric"), true)
+	})
+}
+
+func TestWebHookHanlderAsyncCommands(t *testing.T) {
+	wmc := webex.WebexMockClient
+	wmc.SetDefaultFunctions()
+	amc := apic.ApicMockClient
+	amc.SetDefaultFunctions()
+	wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
+		return webex.WebexMessage{Id: "M1", Text: "/health", RoomId: "AbC13"}, nil
+	}
+	b, _ := NewBot(&wmc, &amc, "http://test_bot.com")
+	reqB := webex.WebexWebhook{
+		Name: "test-bot",
+		Data: &webex.WebexWebhookData{
+			RoomId: "AbC13",
+		},
+	}
+
+	t.Run("Webhook acknowledged before the command completes", func(t *testing.T) {
+		release := make(chan struct{})
+		amc.GetHealthHistoryF = func(ctx context.Context, g string) (apic.HealthHistory, error) {
+			<-release
+			return apic.HealthHistory{}, errors.New("Generic APIC Error")
+		}
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		equals(t, response.Code, http.StatusOK)
+		close(release)
+		b.workers.wait()
+		equals(t, wmc.LastMsgSent, "Hi  🤖 !. I could not reach the APIC... Are there any issues?")
+	})
+	t.Run("Command exceeding its deadline", func(t *testing.T) {
+		defer func(d time.Duration) { commandTimeout = d }(commandTimeout)
+		commandTimeout = 10 * time.Millisecond
+		amc.GetHealthHistoryF = func(ctx context.Context, g string) (apic.HealthHistory, error) {
+			<-ctx.Done()
+			return apic.HealthHistory{}, ctx.Err()
+		}
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		b.workers.wait()
+		equals(t, response.Code, http.StatusOK)
+		equals(t, wmc.LastMsgSent, "Hi  🤖 !\n Sorry... <code>/health</code> took too long and was cancelled ⏱️")
+	})
+	t.Run("APIC not reachable", func(t *testing.T) {
+		defer func() { amc.CheckAvailableF = func() error { return nil } }()
+		amc.CheckAvailableF = func() error {
+			return fmt.Errorf("%w. Requests to https://1.2.3.4 are paused for 30s", apic.ErrUnavailable)
+		}
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		b.workers.wait()
+		equals(t, response.Code, http.StatusOK)
+		equals(t, wmc.LastMsgSent, "Hi  🤖 !\n Sorry... the APIC is not reachable. Requests to https://1.2.3.4 are paused for 30s ⛔")
+	})
+	t.Run("Worker pool full", func(t *testing.T) {
+		workers := b.workers
+		defer func() {
+			b.workers = workers
+			b.router = http.NewServeMux()
+			b.routes()
+		}()
+		b.workers = newWorkerPool(0, 0)
+		b.router = http.NewServeMux()
+		b.routes()
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		equals(t, response.Code, http.StatusOK)
+		equals(t, wmc.LastMsgSent, "Hi  🤖 !\n Sorry... I am busy right now. Please try again later ⏳")
+	})
+}
+
 func TestWebHookHanlderCpuCommand(t *testing.T) {
 	// Test a /CPU command/message without errors
 	t.Run("Errorless /cpu command", func(t *testing.T) {
@@ -239,6 +413,7 @@ func TestWebHookHanlderCpuCommand(t *testing.T) {
 		response := httptest.NewRecorder()
 
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusOK)
 		expectedMessage := "Hi  🤖 !\n\n\t\nThis is the CPU information of the controllers: \n\n" +
 			"<ul><li><code>APIC 1</code> -> \t💻 <strong>CPU: </strong>50\t💾 <strong>Memory %: </strong> 66.666667</li>" +
@@ -261,14 +436,15 @@ func TestWebHookHanlderCpuCommand(t *testing.T) {
 				RoomId: "AbC13",
 			},
 		}
-		amc.GetProcEntityF = func() ([]apic.ApicMoAttributes, error) {
-			return []apic.ApicMoAttributes{{}}, errors.New("Generic APIC Error")
+		amc.GetProcEntityF = func(ctx context.Context) ([]apic.ProcEntity, error) {
+			return []apic.ProcEntity{{}}, errors.New("Generic APIC Error")
 		}
 		jp, _ := json.Marshal(reqB)
 		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
 		response := httptest.NewRecorder()
 
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusOK)
 		expectedMessage := "Hi  🤖 !. I could not reach the APIC... Are there any issues?"
 		equals(t, wmc.LastMsgSent, expectedMessage)
@@ -296,28 +472,30 @@ func TestWebHookHanlderInfoCommand(t *testing.T) {
 		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
 		response := httptest.NewRecorder()
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusOK)
 		expectedMessage := "Hi  🤖 !\n\n\n" +
 			"This is the general information of the Fabric <code>Test Fabric</code> (https://test-apic.com): \n\n" +
 			"<ul><li>Current Health Score: <strong>95</strong></li>" +
 			"<li><strong>APIC Controllers</strong><ul>" +
-			"<li>APIC1 (<strong>5.2(3e)</strong>)</li></ul>" +
+			"<li>APIC1 (<strong>5.2(3e)</strong>)</li></ul></li>" +
 			"<li><strong>Pods</strong><ul>" +
 			"<li>Pod1 <em>physical</em></li>" +
-			"<li>Pod2 <em>physical</em></li></ul>" +
-			"<li></strong>Switches</strong><ul>" +
+			"<li>Pod2 <em>physical</em></li></ul></li>" +
+			"<li><strong>Switches</strong><ul>" +
 			"<li># of Spines : <strong>1</strong></li>" +
-			"<li># of Leafs : <strong>2</strong></li></ul></ul></li>"
+			"<li># of Leafs : <strong>2</strong></li></ul></li></ul>"
 		equals(t, wmc.LastMsgSent, expectedMessage)
 	})
 	t.Run("Error APIC unreachable", func(t *testing.T) {
-		amc.GetFabricInformationF = func() (apic.FabricInformation, error) {
+		amc.GetFabricInformationF = func(ctx context.Context) (apic.FabricInformation, error) {
 			return apic.FabricInformation{}, errors.New("Generic APIC Error")
 		}
 		jp, _ := json.Marshal(reqB)
 		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
 		response := httptest.NewRecorder()
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusOK)
 		expectedMessage := "Hi  🤖 !. I could not reach the APIC... Are there any issues?"
 		equals(t, wmc.LastMsgSent, expectedMessage)
@@ -345,6 +523,7 @@ func TestWebHookHanlderEndpointCommand(t *testing.T) {
 		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
 		response := httptest.NewRecorder()
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusOK)
 		expectedMessage := "Hi  🤖 !\n\n\n" +
 			"This is the information for the Endpoint <code>AA:AA:BB:BB:CC:CC</code>" +
@@ -358,17 +537,33 @@ func TestWebHookHanlderEndpointCommand(t *testing.T) {
 		equals(t, wmc.LastMsgSent, expectedMessage)
 	})
 	t.Run("Error APIC unreachable", func(t *testing.T) {
-		amc.GetEndpointInformationF = func(m string) ([]apic.EndpointInformation, error) {
+		amc.GetEndpointInformationF = func(ctx context.Context, m string) ([]apic.EndpointInformation, error) {
 			return []apic.EndpointInformation{}, errors.New("Generic APIC Error")
 		}
 		jp, _ := json.Marshal(reqB)
 		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
 		response := httptest.NewRecorder()
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusOK)
 		expectedMessage := "Hi  🤖 !. I could not reach the APIC... Are there any issues?"
 		equals(t, wmc.LastMsgSent, expectedMessage)
 	})
+	t.Run("Endpoint history", func(t *testing.T) {
+		wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
+			return webex.WebexMessage{Text: "/ep AA:AA:AA:BB:BB:CC history"}, nil
+		}
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		b.workers.wait()
+		equals(t, response.Code, http.StatusOK)
+		expectedMessage := "Hi  🤖 !\n\n\n" +
+			"This is the history of the Endpoint <code>AA:AA:AA:BB:BB:CC</code>: \n\n" +
+			"<ul><li><strong>2021-09-07T13:20:13.645+01:00</strong> - ip 192.168.1.1 created (<em>E4209236</em>)</li></ul>"
+		equals(t, wmc.LastMsgSent, expectedMessage)
+	})
 }
 
 func TestWebHookHanlderneighCommand(t *testing.T) {
@@ -392,6 +587,7 @@ func TestWebHookHanlderneighCommand(t *testing.T) {
 		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
 		response := httptest.NewRecorder()
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusOK)
 		expectedMessage := "Hi  🤖 !\n\n\n" +
 			"This is the Topology information of the Fabric : \n\n" +
@@ -404,13 +600,14 @@ func TestWebHookHanlderneighCommand(t *testing.T) {
 		wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
 			return webex.WebexMessage{Text: "/neigh 103"}, nil
 		}
-		amc.GetFabricNeighborsF = func(nd string) (map[string][]string, error) {
+		amc.GetFabricNeighborsF = func(ctx context.Context, nd string) (map[string][]string, error) {
 			return map[string][]string{"SW2": {"103:[eth1/4]"}, "SW3": {"103:[eth1/6]"}}, nil
 		}
 		jp, _ := json.Marshal(reqB)
 		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
 		response := httptest.NewRecorder()
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusOK)
 		expectedMessage := "Hi  🤖 !\n\n\n" +
 			"These are the Neighbors of the Node <code>103</code>: \n\n" +
@@ -422,13 +619,14 @@ func TestWebHookHanlderneighCommand(t *testing.T) {
 		wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
 			return webex.WebexMessage{Text: "/neigh"}, nil
 		}
-		amc.GetFabricNeighborsF = func(nd string) (map[string][]string, error) {
+		amc.GetFabricNeighborsF = func(ctx context.Context, nd string) (map[string][]string, error) {
 			return map[string][]string{}, nil
 		}
 		jp, _ := json.Marshal(reqB)
 		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
 		response := httptest.NewRecorder()
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusOK)
 		expectedMessage := "Hi  🤖 !\n Sorry.. I could not discover the Topology of the Fabric"
 		equals(t, wmc.LastMsgSent, expectedMessage)
@@ -437,13 +635,14 @@ func TestWebHookHanlderneighCommand(t *testing.T) {
 		wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
 			return webex.WebexMessage{Text: "/neigh 999"}, nil
 		}
-		amc.GetFabricNeighborsF = func(nd string) (map[string][]string, error) {
+		amc.GetFabricNeighborsF = func(ctx context.Context, nd string) (map[string][]string, error) {
 			return map[string][]string{}, nil
 		}
 		jp, _ := json.Marshal(reqB)
 		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
 		response := httptest.NewRecorder()
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusOK)
 		expectedMessage := "Hi  🤖 !\n It seems there are no Neighbors for Node <code>999</code>"
 		equals(t, wmc.LastMsgSent, expectedMessage)
@@ -452,13 +651,248 @@ func TestWebHookHanlderneighCommand(t *testing.T) {
 		wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
 			return webex.WebexMessage{Text: "/neigh"}, nil
 		}
-		amc.GetFabricNeighborsF = func(nd string) (map[string][]string, error) {
+		amc.GetFabricNeighborsF = func(ctx context.Context, nd string) (map[string][]string, error) {
 			return map[string][]string{}, errors.New("Generic APIC Error")
 		}
 		jp, _ := json.Marshal(reqB)
 		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
 		response := httptest.NewRecorder()
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
+		equals(t, response.Code, http.StatusOK)
+		expectedMessage := "Hi  🤖 !. I could not reach the APIC... Are there any issues?"
+		equals(t, wmc.LastMsgSent, expectedMessage)
+	})
+}
+
+func TestWebHookHanlderHealthCommand(t *testing.T) {
+	wmc := webex.WebexMockClient
+	wmc.SetDefaultFunctions()
+	amc := apic.ApicMockClient
+	amc.SetDefaultFunctions()
+	wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
+		return webex.WebexMessage{Text: "/health"}, nil
+	}
+	b, _ := NewBot(&wmc, &amc, "http://test_bot.com")
+	reqB := webex.WebexWebhook{
+		Name: "test-bot",
+		Data: &webex.WebexWebhookData{
+			RoomId: "AbC13",
+		},
+	}
+
+	t.Run("Errorless /health command", func(t *testing.T) {
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		b.workers.wait()
+		equals(t, response.Code, http.StatusOK)
+		expectedMessage := "Hi  🤖 !\n\n\n" +
+			"This is the health trend of the Fabric (<em>1h</em> intervals): \n\n" +
+			"<ul><li><strong>Fabric</strong>: <code>▁▂▄█</code> min <strong>88</strong> / max <strong>100</strong> / avg <strong>94.2</strong></li>" +
+			"<li><strong>Worst Pods</strong><ul><li><code>1</code>: <code>▁█</code> min <strong>90</strong> / max <strong>95</strong> / avg <strong>92.5</strong></li></ul></li>" +
+			"<li><strong>Worst Tenants</strong><ul>" +
+			"<li><code>myTenant</code>: <code>▁▄█</code> min <strong>45</strong> / max <strong>75</strong> / avg <strong>60.0</strong></li>" +
+			"<li><code>common</code>: <code>███</code> min <strong>100</strong> / max <strong>100</strong> / avg <strong>100.0</strong></li></ul></li>" +
+			"<li><strong>Worst Nodes</strong><ul><li><code>101</code>: <code>▁█</code> min <strong>80</strong> / max <strong>100</strong> / avg <strong>90.0</strong></li></ul></li></ul>"
+		equals(t, wmc.LastMsgSent, expectedMessage)
+	})
+	t.Run("15 minutes interval", func(t *testing.T) {
+		var granularity string
+		wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
+			return webex.WebexMessage{Text: "/health 15m"}, nil
+		}
+		amc.GetHealthHistoryF = func(ctx context.Context, g string) (apic.HealthHistory, error) {
+			granularity = g
+			return apic.HealthHistory{}, nil
+		}
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		b.workers.wait()
+		equals(t, response.Code, http.StatusOK)
+		equals(t, granularity, "15min")
+		expectedMessage := "Hi  🤖 !\n There is no health history available for the Fabric"
+		equals(t, wmc.LastMsgSent, expectedMessage)
+	})
+	t.Run("Error APIC unreachable", func(t *testing.T) {
+		amc.GetHealthHistoryF = func(ctx context.Context, g string) (apic.HealthHistory, error) {
+			return apic.HealthHistory{}, errors.New("Generic APIC Error")
+		}
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		b.workers.wait()
+		equals(t, response.Code, http.StatusOK)
+		expectedMessage := "Hi  🤖 !. I could not reach the APIC... Are there any issues?"
+		equals(t, wmc.LastMsgSent, expectedMessage)
+	})
+}
+
+func TestWebHookHanlderNodeCommand(t *testing.T) {
+	wmc := webex.WebexMockClient
+	wmc.SetDefaultFunctions()
+	amc := apic.ApicMockClient
+	amc.SetDefaultFunctions()
+	wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
+		return webex.WebexMessage{Text: "/node 101"}, nil
+	}
+	b, _ := NewBot(&wmc, &amc, "http://test_bot.com")
+	reqB := webex.WebexWebhook{
+		Name: "test-bot",
+		Data: &webex.WebexWebhookData{
+			RoomId: "AbC13",
+		},
+	}
+
+	t.Run("Errorless /node command", func(t *testing.T) {
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		b.workers.wait()
+		equals(t, response.Code, http.StatusOK)
+		expectedMessage := "Hi  🤖 !\n\n\n" +
+			"This is the information of the Node <code>101</code> (LEAF1): \n\n" +
+			"<ul><li>Current Health Score: <strong>98</strong></li>" +
+			"<li><strong>Role</strong>: leaf - <strong>Pod</strong>: 1</li>" +
+			"<li><strong>Model</strong>: N9K-C93180YC-EX - <strong>Serial</strong>: FDO12345678</li>" +
+			"<li><strong>Firmware</strong>: n9000-15.2(3e)</li>" +
+			"<li><strong>TEP Address</strong>: 10.0.72.64</li>" +
+			"<li><strong>Fabric State</strong>: active</li>" +
+			"<li><strong>Uptime</strong>: 10:02:30:15.000</li>" +
+			"<li><strong>Supervisors</strong><ul><li>Slot 1 N9K-C93180YC-EX (<strong>online</strong>)</li></ul></li>" +
+			"<li><strong>Fans</strong><ul><li>Fan 1/1 (<strong>operable</strong>)</li><li>Fan 2/1 (<strong>inoperable</strong>)</li></ul></li>" +
+			"<li><strong>Power Supplies</strong><ul><li>PSU 1 NXA-PAC-650W-PE (<strong>shut</strong>)</li></ul></li>" +
+			"<li><strong>Active Faults</strong>: 1<ul>" +
+			"<li><strong>F1451</strong> minor ⚠️ - <em>Power supply shutdown. (serial number ABCDEF)</em></li></ul></li></ul>"
+		equals(t, wmc.LastMsgSent, expectedMessage)
+	})
+	t.Run("Unknown Node", func(t *testing.T) {
+		amc.GetNodeInformationF = func(ctx context.Context, nd string) (apic.NodeInformation, error) {
+			return apic.NodeInformation{}, nil
+		}
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		b.workers.wait()
+		equals(t, response.Code, http.StatusOK)
+		expectedMessage := "Hi  🤖 !\n It seems there is no Node <code>101</code> in the Fabric"
+		equals(t, wmc.LastMsgSent, expectedMessage)
+	})
+	t.Run("Error APIC unreachable", func(t *testing.T) {
+		amc.GetNodeInformationF = func(ctx context.Context, nd string) (apic.NodeInformation, error) {
+			return apic.NodeInformation{}, errors.New("Generic APIC Error")
+		}
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		b.workers.wait()
+		equals(t, response.Code, http.StatusOK)
+		expectedMessage := "Hi  🤖 !. I could not reach the APIC... Are there any issues?"
+		equals(t, wmc.LastMsgSent, expectedMessage)
+	})
+}
+
+func TestWebHookHanlderIfaceCommand(t *testing.T) {
+	wmc := webex.WebexMockClient
+	wmc.SetDefaultFunctions()
+	amc := apic.ApicMockClient
+	amc.SetDefaultFunctions()
+	wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
+		return webex.WebexMessage{Text: "/iface 101"}, nil
+	}
+	b, _ := NewBot(&wmc, &amc, "http://test_bot.com")
+	reqB := webex.WebexWebhook{
+		Name: "test-bot",
+		Data: &webex.WebexWebhookData{
+			RoomId: "AbC13",
+		},
+	}
+
+	t.Run("Node interfaces", func(t *testing.T) {
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		b.workers.wait()
+		equals(t, response.Code, http.StatusOK)
+		expectedMessage := "Hi  🤖 !\n\n\n" +
+			"These are the Interfaces of the Node <code>101</code>: \n\n" +
+			"<ul><li><strong>eth1/1</strong> - <em>Node 101</em><ul>" +
+			"<li><strong>State</strong>: admin up ✅ / oper up ✅</li>" +
+			"<li><strong>Speed</strong>: 10G</li>" +
+			"<li><strong>Last Link Change</strong>: 2021-09-07T13:20:13.645+01:00</li>" +
+			"<li><strong>Errors</strong>: CRC 0 / Input 0 / Output 0</li>" +
+			"<li><strong>Utilization</strong>: In 1% / Out 2%</li></ul></li>" +
+			"<li><strong>eth1/2</strong> - <em>Node 101</em><ul>" +
+			"<li><strong>State</strong>: admin up ✅ / oper down ❌</li>" +
+			"<li><strong>Speed</strong>: inherit</li>" +
+			"<li><strong>Last Link Change</strong>: 2021-09-08T10:00:00.000+01:00</li>" +
+			"<li><strong>Errors</strong>: CRC 12 / Input 3 / Output 0</li>" +
+			"<li><strong>Utilization</strong>: In 0% / Out 0%</li></ul></li></ul>"
+		equals(t, wmc.LastMsgSent, expectedMessage)
+	})
+	t.Run("Single interface", func(t *testing.T) {
+		var node, iface string
+		wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
+			return webex.WebexMessage{Text: "/iface 101 eth1/2"}, nil
+		}
+		amc.GetInterfaceInformationF = func(ctx context.Context, nd string, i string) ([]apic.InterfaceInformation, error) {
+			node, iface = nd, i
+			return []apic.InterfaceInformation{}, nil
+		}
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		b.workers.wait()
+		equals(t, response.Code, http.StatusOK)
+		equals(t, node, "101")
+		equals(t, iface, "eth1/2")
+		expectedMessage := "Hi  🤖 !\n It seems there are no Interfaces matching <code>101 eth1/2</code>"
+		equals(t, wmc.LastMsgSent, expectedMessage)
+	})
+	t.Run("Top error interfaces", func(t *testing.T) {
+		wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
+			return webex.WebexMessage{Text: "/iface top 2"}, nil
+		}
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		b.workers.wait()
+		equals(t, response.Code, http.StatusOK)
+		expectedMessage := "Hi  🤖 !\n\n\n" +
+			"These are the top 2 interfaces with errors in the Fabric : \n\n" +
+			"<ul><li><strong>eth1/10</strong> - <em>Node 102</em><ul>" +
+			"<li><strong>State</strong>: admin up ✅ / oper up ✅</li>" +
+			"<li><strong>Speed</strong>: 25G</li>" +
+			"<li><strong>Last Link Change</strong>: </li>" +
+			"<li><strong>Errors</strong>: CRC 1500 / Input 20 / Output 0</li>" +
+			"<li><strong>Utilization</strong>: In 40% / Out 35%</li></ul></li>" +
+			"<li><strong>eth1/2</strong> - <em>Node 101</em><ul>" +
+			"<li><strong>State</strong>: admin up ✅ / oper down ❌</li>" +
+			"<li><strong>Speed</strong>: inherit</li>" +
+			"<li><strong>Last Link Change</strong>: </li>" +
+			"<li><strong>Errors</strong>: CRC 12 / Input 3 / Output 0</li>" +
+			"<li><strong>Utilization</strong>: In 0% / Out 0%</li></ul></li></ul>"
+		equals(t, wmc.LastMsgSent, expectedMessage)
+	})
+	t.Run("Error APIC unreachable", func(t *testing.T) {
+		amc.GetTopErrorInterfacesF = func(ctx context.Context, c string) ([]apic.InterfaceInformation, error) {
+			return []apic.InterfaceInformation{}, errors.New("Generic APIC Error")
+		}
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusOK)
 		expectedMessage := "Hi  🤖 !. I could not reach the APIC... Are there any issues?"
 		equals(t, wmc.LastMsgSent, expectedMessage)
@@ -486,6 +920,7 @@ func TestWebHookHanlderFaultCommand(t *testing.T) {
 		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
 		response := httptest.NewRecorder()
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusOK)
 		expectedMessage := "Hi  🤖 !\n\n\n" +
 			"These are the latest 10 faults in the the Fabric : \n\n" +
@@ -498,13 +933,14 @@ func TestWebHookHanlderFaultCommand(t *testing.T) {
 		equals(t, wmc.LastMsgSent, expectedMessage)
 	})
 	t.Run("Error APIC unreachable", func(t *testing.T) {
-		amc.GetLatestFaultsF = func(c string) ([]apic.ApicMoAttributes, error) {
-			return []apic.ApicMoAttributes{}, errors.New("Generic APIC Error")
+		amc.GetLatestFaultsF = func(ctx context.Context, c string) ([]apic.FaultInst, error) {
+			return []apic.FaultInst{}, errors.New("Generic APIC Error")
 		}
 		jp, _ := json.Marshal(reqB)
 		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
 		response := httptest.NewRecorder()
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusOK)
 		expectedMessage := "Hi  🤖 !. I could not reach the APIC... Are there any issues?"
 		equals(t, wmc.LastMsgSent, expectedMessage)
@@ -532,6 +968,7 @@ func TestWebHookHanlderEventCommand(t *testing.T) {
 		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
 		response := httptest.NewRecorder()
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusOK)
 		expectedMessage := "Hi  🤖 !\n\n\n" +
 			"These are the latest 10 events in the the Fabric : \n\n" +
@@ -543,13 +980,14 @@ func TestWebHookHanlderEventCommand(t *testing.T) {
 		equals(t, wmc.LastMsgSent, expectedMessage)
 	})
 	t.Run("No Events Returned", func(t *testing.T) {
-		amc.GetLatestEventsF = func(c string, usr ...string) ([]apic.ApicMoAttributes, error) {
-			return []apic.ApicMoAttributes{}, nil
+		amc.GetLatestEventsF = func(ctx context.Context, c string, usr ...string) ([]apic.AaaModLR, error) {
+			return []apic.AaaModLR{}, nil
 		}
 		jp, _ := json.Marshal(reqB)
 		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
 		response := httptest.NewRecorder()
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusOK)
 		expectedMessage := "Hi  🤖 !. There are no events"
 		equals(t, wmc.LastMsgSent, expectedMessage)
@@ -562,18 +1000,20 @@ func TestWebHookHanlderEventCommand(t *testing.T) {
 		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
 		response := httptest.NewRecorder()
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusOK)
 		expectedMessage := "Hi  🤖 !. There are no events"
 		equals(t, wmc.LastMsgSent, expectedMessage)
 	})
 	t.Run("Error APIC unreachable", func(t *testing.T) {
-		amc.GetLatestEventsF = func(c string, usr ...string) ([]apic.ApicMoAttributes, error) {
-			return []apic.ApicMoAttributes{}, errors.New("Generic APIC Error")
+		amc.GetLatestEventsF = func(ctx context.Context, c string, usr ...string) ([]apic.AaaModLR, error) {
+			return []apic.AaaModLR{}, errors.New("Generic APIC Error")
 		}
 		jp, _ := json.Marshal(reqB)
 		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
 		response := httptest.NewRecorder()
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusOK)
 		expectedMessage := "Hi  🤖 !. I could not reach the APIC... Are there any issues?"
 		equals(t, wmc.LastMsgSent, expectedMessage)
@@ -602,6 +1042,7 @@ func TestWebHookHanlderWebsocketCommand(t *testing.T) {
 		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
 		response := httptest.NewRecorder()
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusOK)
 		expectedMessage := "Hi  🤖 !\n You are no subscribed to any class"
 		equals(t, wmc.LastMsgSent, expectedMessage)
@@ -615,6 +1056,7 @@ func TestWebHookHanlderWebsocketCommand(t *testing.T) {
 		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
 		response := httptest.NewRecorder()
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusOK)
 		expectedMessage := "Hi  🤖 !\n\n Websocket subscription to MO/Class <code>fvTenant</code> configured 🔧 !"
 		equals(t, wmc.LastMsgSent, expectedMessage)
@@ -628,6 +1070,7 @@ func TestWebHookHanlderWebsocketCommand(t *testing.T) {
 		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
 		response := httptest.NewRecorder()
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusOK)
 		expectedMessage := "Hi  🤖 !\n\n You are already subscribed to MO/Class <code>fvTenant</code>"
 		equals(t, wmc.LastMsgSent, expectedMessage)
@@ -641,6 +1084,7 @@ func TestWebHookHanlderWebsocketCommand(t *testing.T) {
 		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
 		response := httptest.NewRecorder()
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusOK)
 		expectedMessage := "Hi  🤖 !\n Here the list of subcribed classes:\n <ul><li><code>fvTenant</code></li></ul>"
 		equals(t, wmc.LastMsgSent, expectedMessage)
@@ -654,6 +1098,7 @@ func TestWebHookHanlderWebsocketCommand(t *testing.T) {
 		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
 		response := httptest.NewRecorder()
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusOK)
 		expectedMessage := "Hi  🤖 !\n\n Websocket subscription to MO/Class <code>fvTenant</code> deleted 🔧 !"
 		equals(t, wmc.LastMsgSent, expectedMessage)
@@ -667,10 +1112,24 @@ func TestWebHookHanlderWebsocketCommand(t *testing.T) {
 		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
 		response := httptest.NewRecorder()
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusOK)
 		expectedMessage := "Hi  🤖 !\n\n You are not subscribed to MO/Class <code>fvTenant</code>"
 		equals(t, wmc.LastMsgSent, expectedMessage)
 	})
+	t.Run("/websocket - Subscribe to Node", func(t *testing.T) {
+		wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
+			return webex.WebexMessage{Text: "/websocket topology/pod-1/node-101"}, nil
+		}
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		b.workers.wait()
+		equals(t, response.Code, http.StatusOK)
+		expectedMessage := "Hi  🤖 !\n\n Websocket subscription to MO/Class <code>topology/pod-1/node-101</code> configured 🔧 !"
+		equals(t, wmc.LastMsgSent, expectedMessage)
+	})
 }
 
 func TestWebHookHanlderHelpCommand(t *testing.T) {
@@ -694,15 +1153,28 @@ func TestWebHookHanlderHelpCommand(t *testing.T) {
 		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
 		response := httptest.NewRecorder()
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusOK)
 		expectedMessage := "Hello , How can I help you?\n\n" +
-			"<ul><li><code>/cpu</code>\t->\tGet APIC CPU Information 💾</li>" +
-			"<li><code>/ep</code>\t->\tGet APIC Endpoint Information 💻. Usage <code>/ep [ep_mac] </code></li>" +
+			"<ul><li><code>/cache</code>\t->\tShow the APIC cache statistics or flush it 🗄️. Usage <code>/cache [flush:opt] </code></li>" +
+			"<li><code>/cancel</code>\t->\tCancel a configuration change 🗑️. Usage <code>/cancel [change_id] </code></li>" +
+			"<li><code>/confirm</code>\t->\tConfirm a configuration change ✅. Usage <code>/confirm [change_id] </code></li>" +
+			"<li><code>/cpu</code>\t->\tGet APIC CPU Information 💾</li>" +
+			"<li><code>/ep</code>\t->\tGet APIC Endpoint Information 💻. Usage <code>/ep [ep_mac] [history:opt] </code></li>" +
+			"<li><code>/epg</code>\t->\tAdd a static path to an EPG 🔗. Usage <code>/epg add-static-path [tenant/app/epg] [node_id] [iface] [vlan] </code></li>" +
 			"<li><code>/events</code>\t->\tGet Fabric latest events ❎.   Usage <code>/events [user:opt] [count(1-10):opt] </code></li>" +
-			"<li><code>/faults</code>\t->\tGet Fabric latest faults ⚠️. Usage <code>/faults [count(1-10):opt] </code></li>" +
-			"<li><code>/help</code>\t->\tChatbot Help ❔</li><li><code>/info</code>\t->\tGet Fabric Information ℹ️</li>" +
+			"<li><code>/faults</code>\t->\tGet Fabric latest faults ⚠️. Usage <code>/faults [count(1-10):opt] | ack [fault_dn] </code></li>" +
+			"<li><code>/health</code>\t->\tGet Fabric health trend 📈. Usage <code>/health [15m|1h|1d:opt] </code></li>" +
+			"<li><code>/help</code>\t->\tChatbot Help ❔</li>" +
+			"<li><code>/iface</code>\t->\tGet Interface status and counters 🔌. Usage <code>/iface [node_id] [iface:opt] | top [count(1-10):opt] </code></li>" +
+			"<li><code>/info</code>\t->\tGet Fabric Information ℹ️</li>" +
+			"<li><code>/mention</code>\t->\tRequire a mention in this group room 🔔. Usage <code>/mention [on|off:opt] </code></li>" +
 			"<li><code>/neigh</code>\t->\tGet Fabric Topology Information 🔢. Usage <code>/neigh [node_id] </code></li>" +
-			"<li><code>/websocket</code>\t->\tSubscribe to Fabric events 📩</li><ul>"
+			"<li><code>/node</code>\t->\tGet Node health and hardware status 🖥️. Usage <code>/node [node_id] </code></li>" +
+			"<li><code>/query</code>\t->\tQuery any class or DN 🔍. Usage <code>/query class [class] [filter:opt] [attrs:opt] | dn [dn] [subtree:opt] </code></li>" +
+			"<li><code>/shut</code>\t->\tShut down an interface 🛑. Usage <code>/shut [node_id] [iface] </code></li>" +
+			"<li><code>/tenant</code>\t->\tCreate a tenant 🏢. Usage <code>/tenant create [name] </code></li>" +
+			"<li><code>/websocket</code>\t->\tSubscribe to Fabric events 📩</li></ul>"
 		equals(t, wmc.LastMsgSent, expectedMessage)
 	})
 }
@@ -728,15 +1200,28 @@ func TestWebHookHanlderInvalidCommand(t *testing.T) {
 		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
 		response := httptest.NewRecorder()
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusOK)
 		expectedMessage := "Hello , How can I help you?\n\n" +
-			"<ul><li><code>/cpu</code>\t->\tGet APIC CPU Information 💾</li>" +
-			"<li><code>/ep</code>\t->\tGet APIC Endpoint Information 💻. Usage <code>/ep [ep_mac] </code></li>" +
+			"<ul><li><code>/cache</code>\t->\tShow the APIC cache statistics or flush it 🗄️. Usage <code>/cache [flush:opt] </code></li>" +
+			"<li><code>/cancel</code>\t->\tCancel a configuration change 🗑️. Usage <code>/cancel [change_id] </code></li>" +
+			"<li><code>/confirm</code>\t->\tConfirm a configuration change ✅. Usage <code>/confirm [change_id] </code></li>" +
+			"<li><code>/cpu</code>\t->\tGet APIC CPU Information 💾</li>" +
+			"<li><code>/ep</code>\t->\tGet APIC Endpoint Information 💻. Usage <code>/ep [ep_mac] [history:opt] </code></li>" +
+			"<li><code>/epg</code>\t->\tAdd a static path to an EPG 🔗. Usage <code>/epg add-static-path [tenant/app/epg] [node_id] [iface] [vlan] </code></li>" +
 			"<li><code>/events</code>\t->\tGet Fabric latest events ❎.   Usage <code>/events [user:opt] [count(1-10):opt] </code></li>" +
-			"<li><code>/faults</code>\t->\tGet Fabric latest faults ⚠️. Usage <code>/faults [count(1-10):opt] </code></li>" +
-			"<li><code>/help</code>\t->\tChatbot Help ❔</li><li><code>/info</code>\t->\tGet Fabric Information ℹ️</li>" +
+			"<li><code>/faults</code>\t->\tGet Fabric latest faults ⚠️. Usage <code>/faults [count(1-10):opt] | ack [fault_dn] </code></li>" +
+			"<li><code>/health</code>\t->\tGet Fabric health trend 📈. Usage <code>/health [15m|1h|1d:opt] </code></li>" +
+			"<li><code>/help</code>\t->\tChatbot Help ❔</li>" +
+			"<li><code>/iface</code>\t->\tGet Interface status and counters 🔌. Usage <code>/iface [node_id] [iface:opt] | top [count(1-10):opt] </code></li>" +
+			"<li><code>/info</code>\t->\tGet Fabric Information ℹ️</li>" +
+			"<li><code>/mention</code>\t->\tRequire a mention in this group room 🔔. Usage <code>/mention [on|off:opt] </code></li>" +
 			"<li><code>/neigh</code>\t->\tGet Fabric Topology Information 🔢. Usage <code>/neigh [node_id] </code></li>" +
-			"<li><code>/websocket</code>\t->\tSubscribe to Fabric events 📩</li><ul>"
+			"<li><code>/node</code>\t->\tGet Node health and hardware status 🖥️. Usage <code>/node [node_id] </code></li>" +
+			"<li><code>/query</code>\t->\tQuery any class or DN 🔍. Usage <code>/query class [class] [filter:opt] [attrs:opt] | dn [dn] [subtree:opt] </code></li>" +
+			"<li><code>/shut</code>\t->\tShut down an interface 🛑. Usage <code>/shut [node_id] [iface] </code></li>" +
+			"<li><code>/tenant</code>\t->\tCreate a tenant 🏢. Usage <code>/tenant create [name] </code></li>" +
+			"<li><code>/websocket</code>\t->\tSubscribe to Fabric events 📩</li></ul>"
 		equals(t, wmc.LastMsgSent, expectedMessage)
 	})
 
@@ -748,6 +1233,7 @@ func TestWebHookHanlderInvalidCommand(t *testing.T) {
 		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
 		response := httptest.NewRecorder()
 		b.router.ServeHTTP(response, request)
+		b.workers.wait()
 		equals(t, response.Code, http.StatusOK)
 		expectedMessage := "Hi  🤖 \n I could not fully understand the input\n" +
 			" Please check the usage of the <code>/neigh</code> command:\n " +
@@ -756,6 +1242,15 @@ func TestWebHookHanlderInvalidCommand(t *testing.T) {
 	})
 }
 func TestUtils(t *testing.T) {
+	t.Run("sparkline - Increasing values", func(t *testing.T) {
+		equals(t, sparkline([]int{0, 50, 100}), "▁▄█")
+	})
+	t.Run("sparkline - Flat values", func(t *testing.T) {
+		equals(t, sparkline([]int{50, 50}), "▄▄")
+	})
+	t.Run("sparkline - No values", func(t *testing.T) {
+		equals(t, sparkline([]int{}), "")
+	})
 	t.Run("cleanCommand - No additional spaces", func(t *testing.T) {
 
 		s := cleanCommand("test-bot", "/ep AA:AA:AA:AA:AA:AA test-bot")
@@ -771,4 +1266,9 @@ func TestUtils(t *testing.T) {
 		s := cleanCommand("test-bot", "test-bot  /ep   AA:AA:AA:AA:AA:AA  ")
 		equals(t, s, "/ep AA:AA:AA:AA:AA:AA")
 	})
+	t.Run("cleanCommand - Name with several words", func(t *testing.T) {
+
+		s := cleanCommand("ACI Bot", "ACI Bot /events ACI 3")
+		equals(t, s, "/events ACI 3")
+	})
 }
@@ -0,0 +1,48 @@
+package bot
+
+import (
+	"aci-chatbot/apic"
+	"aci-chatbot/webex"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCacheCommand(t *testing.T) {
+	amc := apic.ApicMockClient
+	amc.SetDefaultFunctions()
+	wmc := webex.WebexMockClient
+	wm := ChatMessage{sender: "alice", personId: "P1", roomId: "R1", transport: webexTransport{&wmc}}
+	cacheCommand := cacheCommand(newAdmins("P1"))
+
+	t.Run("Cache disabled", func(t *testing.T) {
+		r := cacheCommand(context.Background(), &amc, Message{cmd: "/cache"}, wm)
+		equals(t, r.text, "Hi alice 🤖 !\n The APIC cache is disabled")
+	})
+	t.Run("Statistics", func(t *testing.T) {
+		cc := apic.NewCachedClient(&amc, time.Minute)
+		cc.GetFabricInformation(context.Background())
+		cc.GetFabricInformation(context.Background())
+		cc.GetFabricInformation(context.Background())
+		r := cacheCommand(context.Background(), cc, Message{cmd: "/cache"}, wm)
+		equals(t, strings.Contains(r.text, "<li><strong>Results</strong>: 1</li><li><strong>Hits</strong>: 2</li><li><strong>Misses</strong>: 1</li><li><strong>Hit ratio</strong>: 66.7%</li>"), true)
+		equals(t, r.data, apic.CacheStats{Entries: 1, Hits: 2, Misses: 1})
+	})
+	t.Run("Flush", func(t *testing.T) {
+		cc := apic.NewCachedClient(&amc, time.Minute)
+		cc.GetFabricInformation(context.Background())
+		cc.GetProcEntity(context.Background())
+		r := cacheCommand(context.Background(), cc, Message{cmd: "/cache flush"}, wm)
+		equals(t, r.text, "Hi alice 🤖 !\n APIC cache flushed 🧹. 2 results dropped")
+		equals(t, cc.Stats().Entries, 0)
+	})
+	t.Run("Flush restricted to the administrators", func(t *testing.T) {
+		cc := apic.NewCachedClient(&amc, time.Minute)
+		cc.GetFabricInformation(context.Background())
+		bob := ChatMessage{sender: "bob", personId: "P2", roomId: "R1", transport: webexTransport{&wmc}}
+		r := cacheCommand(context.Background(), cc, Message{cmd: "/cache flush"}, bob)
+		equals(t, r.text, "Hi bob 🤖 !\n Sorry... Only the administrators of the bot can flush the APIC cache")
+		equals(t, cc.Stats().Entries, 1)
+	})
+}
@@ -0,0 +1,259 @@
+package bot
+
+import (
+	"aci-chatbot/apic"
+	"aci-chatbot/webex"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Configuration change built from a chat command
+// Changes are only posted to the APIC once they are confirmed
+type pendingChange struct {
+	id          string
+	description string
+	dn          string // DN the payload is posted to
+	payload     []byte
+	requester   string
+	requesterId string
+	room        string // Room key. Changes are only confirmed from the room they were requested in
+	approvals   map[string]bool
+	expires     time.Time
+}
+
+// Changes waiting for a confirmation
+type changeStore struct {
+	mu             sync.Mutex
+	ttl            time.Duration
+	next           int
+	pending        map[string]*pendingChange
+	secondApprover bool    // A person other than the requester must confirm the changes as well
+	admins         *admins // Only the administrators of the bot request and confirm the changes. See SetAdmins()
+	audit          *auditLog
+}
+
+func newChangeStore(ttl time.Duration, adm *admins) *changeStore {
+	return &changeStore{ttl: ttl, pending: make(map[string]*pendingChange), admins: adm, audit: &auditLog{}}
+}
+
+// Require a second administrator of the room to confirm the configuration changes
+func SetSecondApprover() Option {
+	return func(b *Bot) {
+		b.changes.secondApprover = true
+	}
+}
+
+// Save a new change. Returns its ID
+func (cs *changeStore) add(c *pendingChange) string {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	now := time.Now()
+	// Purge the expired changes
+	for id, p := range cs.pending {
+		if now.After(p.expires) {
+			delete(cs.pending, id)
+		}
+	}
+	cs.next++
+	c.id = strconv.Itoa(cs.next)
+	c.approvals = make(map[string]bool)
+	c.expires = now.Add(cs.ttl)
+	cs.pending[c.id] = c
+	return c.id
+}
+
+// Get a pending change of a room
+func (cs *changeStore) get(id, room string) (*pendingChange, error) {
+	c, ok := cs.pending[id]
+	if !ok || c.room != room {
+		return nil, fmt.Errorf("there is no pending change #%s in this room", id)
+	}
+	if time.Now().After(c.expires) {
+		delete(cs.pending, id)
+		return nil, fmt.Errorf("the change #%s expired. Please request it again", id)
+	}
+	return c, nil
+}
+
+// Register the confirmation of a person
+// Returns the change and whether it has all the required confirmations. Complete changes are removed from the store
+func (cs *changeStore) approve(id, room, personId string) (*pendingChange, bool, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if !cs.admins.isAdmin(personId) {
+		return nil, false, fmt.Errorf("only the administrators of the bot can confirm the changes")
+	}
+	c, err := cs.get(id, room)
+	if err != nil {
+		return nil, false, err
+	}
+	if !cs.secondApprover && personId != c.requesterId {
+		return nil, false, fmt.Errorf("only %s can confirm the change #%s", c.requester, id)
+	}
+	c.approvals[personId] = true
+	if !c.approvals[c.requesterId] || (cs.secondApprover && len(c.approvals) < 2) {
+		return c, false, nil
+	}
+	delete(cs.pending, id)
+	return c, true, nil
+}
+
+// Discard a pending change
+func (cs *changeStore) cancel(id, room string) (*pendingChange, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	c, err := cs.get(id, room)
+	if err != nil {
+		return nil, err
+	}
+	delete(cs.pending, id)
+	return c, nil
+}
+
+// Build the JSON payload of a MO
+func moPayload(class string, attributes map[string]string) []byte {
+	p, _ := json.Marshal(map[string]interface{}{class: map[string]interface{}{"attributes": attributes}})
+	return p
+}
+
+// DN of the path of a physical interface
+func interfacePath(pod, node, iface string) string {
+	return fmt.Sprintf("topology/pod-%s/paths-%s/pathep-[%s]", pod, node, iface)
+}
+
+// Look up the pod of a node. Interface paths include it
+func nodePod(ctx context.Context, c apic.ApicInterface, node string) (string, error) {
+	info, err := c.GetNodeInformation(ctx, node)
+	if err != nil {
+		return "", err
+	}
+	if info.Pod == "" {
+		return "", fmt.Errorf("node %s not found", node)
+	}
+	return info.Pod, nil
+}
+
+// Save a change and build its preview
+// Only the administrators of the bot can request changes
+func requestChange(cs *changeStore, wm ChatMessage, description, dn string, payload []byte) Reply {
+	if !cs.admins.isAdmin(wm.personId) {
+		log.Printf("Change requested by %s rejected. Not an administrator of the bot", wm.sender)
+		return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n Sorry... Only the administrators of the bot can change the configuration", wm.sender)}
+	}
+	c := &pendingChange{description: description, dn: dn, payload: payload, requester: wm.sender, requesterId: wm.personId, room: wm.roomKey()}
+	id := cs.add(c)
+	cs.audit.record("requested", c, wm, nil)
+
+	var pretty bytes.Buffer
+	json.Indent(&pretty, payload, "", "  ")
+	approvers := fmt.Sprintf("%s must confirm it", wm.sender)
+	if cs.secondApprover {
+		approvers = fmt.Sprintf("%s and a second administrator of the room must confirm it", wm.sender)
+	}
+	text := fmt.Sprintf("Hi %s 🤖 !\n Change **#%s** ✏️: %s\n POST <code>%s</code>\n<pre>%s</pre>\n %s with <code>/confirm %s</code> or discard it with <code>/cancel %s</code>. It expires in %s",
+		wm.sender, id, description, dn, pretty.String(), approvers, id, id, cs.ttl)
+	card := webex.NewAdaptiveCard(
+		webex.CardTitle(fmt.Sprintf("Change #%s ✏️", id)),
+		webex.CardText(description),
+		webex.CardFacts(
+			webex.CardFact{Title: "Requested by", Value: wm.sender},
+			webex.CardFact{Title: "DN", Value: dn},
+			webex.CardFact{Title: "Expires in", Value: cs.ttl.String()},
+		),
+		webex.CardElement{Type: "TextBlock", Text: pretty.String(), FontType: "Monospace", Wrap: true},
+		webex.CardText(approvers),
+		webex.CardActions(
+			webex.CardSubmit("Confirm", map[string]string{"command": "/confirm " + id}),
+			webex.CardSubmit("Cancel", map[string]string{"command": "/cancel " + id}),
+		),
+	)
+	return Reply{text: text, data: map[string]string{"id": id, "dn": dn, "payload": string(payload)}, card: &card}
+}
+
+// /shut handler
+// Interfaces are shut down by disabling them in the fabric out-of-service policy
+func shutCommand(cs *changeStore) Callback {
+	return func(ctx context.Context, c apic.ApicInterface, m Message, wm ChatMessage) Reply {
+		s := splitShutCommand(m.cmd)
+		pod, err := nodePod(ctx, c, s["node"])
+		if err != nil {
+			log.Printf("Error while retrieving the node. Err: %s", err)
+			return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n Sorry... I could not find the node <code>%s</code>", wm.sender, s["node"])}
+		}
+		payload := moPayload("fabricRsOosPath", map[string]string{"tDn": interfacePath(pod, s["node"], s["iface"]), "lc": "blacklist"})
+		return requestChange(cs, wm, fmt.Sprintf("Shut down the interface %s of the node %s", s["iface"], s["node"]), "uni/fabric/outofsvc", payload)
+	}
+}
+
+// /epg handler
+func epgCommand(cs *changeStore) Callback {
+	return func(ctx context.Context, c apic.ApicInterface, m Message, wm ChatMessage) Reply {
+		s := splitStaticPathCommand(m.cmd)
+		if vlan, _ := strconv.Atoi(s["vlan"]); vlan < 1 || vlan > 4094 {
+			return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n The VLAN <code>%s</code> is not valid. Use a VLAN between 1 and 4094", wm.sender, s["vlan"])}
+		}
+		pod, err := nodePod(ctx, c, s["node"])
+		if err != nil {
+			log.Printf("Error while retrieving the node. Err: %s", err)
+			return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n Sorry... I could not find the node <code>%s</code>", wm.sender, s["node"])}
+		}
+		dn := fmt.Sprintf("uni/tn-%s/ap-%s/epg-%s", s["tenant"], s["app"], s["epg"])
+		payload := moPayload("fvRsPathAtt", map[string]string{"tDn": interfacePath(pod, s["node"], s["iface"]), "encap": "vlan-" + s["vlan"], "mode": "regular", "instrImedcy": "immediate"})
+		return requestChange(cs, wm, fmt.Sprintf("Add the static path %s of the node %s with the VLAN %s to the EPG %s", s["iface"], s["node"], s["vlan"], dn), dn, payload)
+	}
+}
+
+// /tenant handler
+func tenantCommand(cs *changeStore) Callback {
+	return func(ctx context.Context, c apic.ApicInterface, m Message, wm ChatMessage) Reply {
+		name := splitTenantCommand(m.cmd)["name"]
+		payload := moPayload("fvTenant", map[string]string{"dn": "uni/tn-" + name, "name": name, "status": "created"})
+		return requestChange(cs, wm, fmt.Sprintf("Create the tenant %s", name), "uni", payload)
+	}
+}
+
+// /confirm handler
+// The change is posted to the APIC once it has all the required confirmations
+func confirmCommand(cs *changeStore) Callback {
+	return func(ctx context.Context, c apic.ApicInterface, m Message, wm ChatMessage) Reply {
+		id := splitChangeCommand(m.cmd)["id"]
+		ch, complete, err := cs.approve(id, wm.roomKey(), wm.personId)
+		if err != nil {
+			return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n Sorry... %s", wm.sender, err)}
+		}
+		if !complete {
+			cs.audit.record("approved", ch, wm, nil)
+			if wm.personId == ch.requesterId {
+				return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n Change **#%s** confirmed. Waiting for a second administrator of the room to confirm it ⏳", wm.sender, id)}
+			}
+			return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n Change **#%s** confirmed. Waiting for %s to confirm it ⏳", wm.sender, id, ch.requester)}
+		}
+		if err := c.PostMo(ctx, ch.dn, ch.payload); err != nil {
+			log.Printf("Error while applying the change %s. Err: %s", id, err)
+			cs.audit.record("failed", ch, wm, err)
+			return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n Sorry... the APIC rejected the change **#%s** ❌", wm.sender, id)}
+		}
+		cs.audit.record("applied", ch, wm, nil)
+		return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n Change **#%s** applied ✅: %s", wm.sender, id, ch.description)}
+	}
+}
+
+// /cancel handler
+// Any person of the room may discard a pending change
+func cancelCommand(cs *changeStore) Callback {
+	return func(ctx context.Context, c apic.ApicInterface, m Message, wm ChatMessage) Reply {
+		id := splitChangeCommand(m.cmd)["id"]
+		ch, err := cs.cancel(id, wm.roomKey())
+		if err != nil {
+			return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n Sorry... %s", wm.sender, err)}
+		}
+		cs.audit.record("cancelled", ch, wm, nil)
+		return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n Change **#%s** cancelled 🗑️", wm.sender, id)}
+	}
+}
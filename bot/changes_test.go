@@ -0,0 +1,174 @@
+package bot
+
+import (
+	"aci-chatbot/apic"
+	"aci-chatbot/webex"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChangeCommands(t *testing.T) {
+	wmc := webex.WebexMockClient
+	wmc.SetDefaultFunctions()
+	amc := apic.ApicMockClient
+	amc.SetDefaultFunctions()
+	var postedDn, postedPayload string
+	amc.PostMoF = func(ctx context.Context, dn string, payload []byte) error {
+		postedDn, postedPayload = dn, string(payload)
+		return nil
+	}
+	audit := &bytes.Buffer{}
+	cs := newChangeStore(time.Minute, newAdmins("P1", "P2"))
+	cs.audit.w = audit
+	cmds := newCommands(NewWsDb(), newRoomSettings(), cs, cs.admins)
+	alice := ChatMessage{sender: "alice", personId: "P1", roomId: "R1", transport: webexTransport{&wmc}}
+	bob := ChatMessage{sender: "bob", personId: "P2", roomId: "R1", transport: webexTransport{&wmc}}
+	carol := ChatMessage{sender: "carol", personId: "P3", roomId: "R1", transport: webexTransport{&wmc}}
+	run := func(text string, wm ChatMessage) Reply {
+		cli, valid := findCommand(cmds, text)
+		equals(t, valid, true)
+		return cmds[cli].callback(context.Background(), &amc, Message{cmd: text}, wm)
+	}
+
+	t.Run("Preview of a tenant", func(t *testing.T) {
+		r := run("/tenant create myTenant", alice)
+		equals(t, r.data, map[string]string{"id": "1", "dn": "uni", "payload": `{"fvTenant":{"attributes":{"dn":"uni/tn-myTenant","name":"myTenant","status":"created"}}}`})
+		equals(t, r.card.Body[len(r.card.Body)-1].Actions[0].Data["command"], "/confirm 1")
+		equals(t, postedDn, "")
+	})
+	t.Run("Requested by a person who is not an administrator", func(t *testing.T) {
+		for _, cmd := range []string{"/tenant create otherTenant", "/shut 101 eth1/10", "/epg add-static-path myTenant/myApp/myEPG 101 eth1/1 100",
+			"/faults ack topology/pod-1/node-101/fault-F0532"} {
+			r := run(cmd, carol)
+			equals(t, r.text, "Hi carol 🤖 !\n Sorry... Only the administrators of the bot can change the configuration")
+			equals(t, r.data, nil)
+		}
+	})
+	t.Run("Confirmed by a person who is not an administrator", func(t *testing.T) {
+		r := run("/confirm 1", carol)
+		equals(t, r.text, "Hi carol 🤖 !\n Sorry... only the administrators of the bot can confirm the changes")
+		equals(t, postedDn, "")
+	})
+	t.Run("Confirmed by someone else", func(t *testing.T) {
+		r := run("/confirm 1", bob)
+		equals(t, r.text, "Hi bob 🤖 !\n Sorry... only alice can confirm the change #1")
+		equals(t, postedDn, "")
+	})
+	t.Run("Confirmed by the requester", func(t *testing.T) {
+		r := run("/confirm 1", alice)
+		equals(t, r.text, "Hi alice 🤖 !\n Change **#1** applied ✅: Create the tenant myTenant")
+		equals(t, postedDn, "uni")
+		equals(t, postedPayload, `{"fvTenant":{"attributes":{"dn":"uni/tn-myTenant","name":"myTenant","status":"created"}}}`)
+	})
+	t.Run("Applied only once", func(t *testing.T) {
+		r := run("/confirm 1", alice)
+		equals(t, r.text, "Hi alice 🤖 !\n Sorry... there is no pending change #1 in this room")
+	})
+	t.Run("Shut an interface", func(t *testing.T) {
+		r := run("/shut 101 eth1/10", alice)
+		equals(t, r.data.(map[string]string)["payload"], `{"fabricRsOosPath":{"attributes":{"lc":"blacklist","tDn":"topology/pod-1/paths-101/pathep-[eth1/10]"}}}`)
+		equals(t, r.data.(map[string]string)["dn"], "uni/fabric/outofsvc")
+	})
+	t.Run("Confirmed from another room", func(t *testing.T) {
+		other := alice
+		other.roomId = "R2"
+		r := run("/confirm 2", other)
+		equals(t, r.text, "Hi alice 🤖 !\n Sorry... there is no pending change #2 in this room")
+	})
+	t.Run("Cancel", func(t *testing.T) {
+		r := run("/cancel 2", bob)
+		equals(t, r.text, "Hi bob 🤖 !\n Change **#2** cancelled 🗑️")
+		r = run("/confirm 2", alice)
+		equals(t, r.text, "Hi alice 🤖 !\n Sorry... there is no pending change #2 in this room")
+	})
+	t.Run("Static path", func(t *testing.T) {
+		r := run("/epg add-static-path myTenant/myApp/myEPG 101 eth1/1 100", alice)
+		equals(t, r.data.(map[string]string)["dn"], "uni/tn-myTenant/ap-myApp/epg-myEPG")
+		equals(t, r.data.(map[string]string)["payload"], `{"fvRsPathAtt":{"attributes":{"encap":"vlan-100","instrImedcy":"immediate","mode":"regular","tDn":"topology/pod-1/paths-101/pathep-[eth1/1]"}}}`)
+	})
+	t.Run("Invalid VLAN", func(t *testing.T) {
+		r := run("/epg add-static-path myTenant/myApp/myEPG 101 eth1/1 4095", alice)
+		equals(t, r.data, nil)
+	})
+	t.Run("Rejected by the APIC", func(t *testing.T) {
+		amc.PostMoF = func(ctx context.Context, dn string, payload []byte) error {
+			return errors.New("Generic APIC Error")
+		}
+		r := run("/confirm 3", alice)
+		equals(t, r.text, "Hi alice 🤖 !\n Sorry... the APIC rejected the change **#3** ❌")
+	})
+	t.Run("Audit log", func(t *testing.T) {
+		var actions []string
+		for _, line := range strings.Split(strings.TrimSpace(audit.String()), "\n") {
+			e := auditEntry{}
+			equals(t, json.Unmarshal([]byte(line), &e), nil)
+			actions = append(actions, e.Change+":"+e.Action+":"+e.User)
+		}
+		equals(t, actions, []string{"1:requested:alice", "1:applied:alice", "2:requested:alice", "2:cancelled:bob", "3:requested:alice", "3:failed:alice"})
+	})
+}
+
+func TestChangeSecondApprover(t *testing.T) {
+	amc := apic.ApicMockClient
+	amc.SetDefaultFunctions()
+	posted := 0
+	amc.PostMoF = func(ctx context.Context, dn string, payload []byte) error {
+		posted++
+		return nil
+	}
+	cs := newChangeStore(time.Minute, newAdmins("P1", "P2"))
+	cs.audit.w = &bytes.Buffer{}
+	cs.secondApprover = true
+	cmds := newCommands(NewWsDb(), newRoomSettings(), cs, cs.admins)
+	wmc := webex.WebexMockClient
+	alice := ChatMessage{sender: "alice", personId: "P1", roomId: "R1", transport: webexTransport{&wmc}}
+	bob := ChatMessage{sender: "bob", personId: "P2", roomId: "R1", transport: webexTransport{&wmc}}
+	carol := ChatMessage{sender: "carol", personId: "P3", roomId: "R1", transport: webexTransport{&wmc}}
+	run := func(text string, wm ChatMessage) Reply {
+		cli, _ := findCommand(cmds, text)
+		return cmds[cli].callback(context.Background(), &amc, Message{cmd: text}, wm)
+	}
+
+	run("/tenant create myTenant", alice)
+	t.Run("Second approver first", func(t *testing.T) {
+		equals(t, run("/confirm 1", bob).text, "Hi bob 🤖 !\n Change **#1** confirmed. Waiting for alice to confirm it ⏳")
+		equals(t, posted, 0)
+	})
+	t.Run("Requester confirms twice", func(t *testing.T) {
+		run("/tenant create otherTenant", alice)
+		equals(t, run("/confirm 2", alice).text, "Hi alice 🤖 !\n Change **#2** confirmed. Waiting for a second administrator of the room to confirm it ⏳")
+		equals(t, run("/confirm 2", alice).text, "Hi alice 🤖 !\n Change **#2** confirmed. Waiting for a second administrator of the room to confirm it ⏳")
+		equals(t, posted, 0)
+	})
+	t.Run("Both confirmed", func(t *testing.T) {
+		equals(t, run("/confirm 1", alice).text, "Hi alice 🤖 !\n Change **#1** applied ✅: Create the tenant myTenant")
+		equals(t, run("/confirm 2", bob).text, "Hi bob 🤖 !\n Change **#2** applied ✅: Create the tenant otherTenant")
+		equals(t, posted, 2)
+	})
+	t.Run("Second approver not an administrator", func(t *testing.T) {
+		run("/tenant create carolTenant", alice)
+		equals(t, run("/confirm 3", alice).text, "Hi alice 🤖 !\n Change **#3** confirmed. Waiting for a second administrator of the room to confirm it ⏳")
+		equals(t, run("/confirm 3", carol).text, "Hi carol 🤖 !\n Sorry... only the administrators of the bot can confirm the changes")
+		equals(t, posted, 2)
+	})
+	t.Run("Expired change", func(t *testing.T) {
+		cs.ttl = -time.Second
+		run("/tenant create lateTenant", alice)
+		equals(t, run("/confirm 4", alice).text, "Hi alice 🤖 !\n Sorry... the change #4 expired. Please request it again")
+	})
+}
+
+func TestFindCommandPrefix(t *testing.T) {
+	cmds := newCommands(NewWsDb(), newRoomSettings(), newChangeStore(time.Minute, newAdmins()), newAdmins())
+	cli, valid := findCommand(cmds, "/epg add-static-path myTenant/myApp/myEPG 101 eth1/1 100")
+	equals(t, cli, "/epg")
+	equals(t, valid, true)
+	cli, valid = findCommand(cmds, "/epg remove")
+	equals(t, cli, "/epg")
+	equals(t, valid, false)
+}
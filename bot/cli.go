@@ -0,0 +1,168 @@
+package bot
+
+import (
+	"aci-chatbot/apic"
+	"aci-chatbot/webex"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const cliPrompt = "aci> "
+
+// Run the commands of the bot from a terminal, without any chat platform
+// The replies are printed as plain text, or as JSON
+type Cli struct {
+	apic     apic.ApicInterface
+	commands map[string]Command
+	changes  *changeStore
+	out      io.Writer
+	json     bool
+	user     string
+}
+
+// Type to define the CLI configuration option
+type CliOption func(*Cli)
+
+// Print the data behind the replies as JSON instead of text
+func SetCliJson() CliOption {
+	return func(c *Cli) {
+		c.json = true
+	}
+}
+
+// Write the audit log of the configuration changes to w instead of the standard logger
+func SetCliAuditLog(w io.Writer) CliOption {
+	return func(c *Cli) {
+		c.changes.audit.w = w
+	}
+}
+
+// Cli Generator. The replies are written to out
+func NewCli(ap apic.ApicInterface, out io.Writer, options ...CliOption) *Cli {
+	// The CLI is run by the operator of the bot, with the credentials of the APIC
+	cs := newChangeStore(changeTTL, &admins{everyone: true})
+	cmds := newCommands(NewWsDb(), newRoomSettings(), cs, cs.admins)
+	// Subscriptions and mentions are related to a room. There is none in the CLI
+	delete(cmds, "/websocket")
+	delete(cmds, "/mention")
+	c := &Cli{apic: ap, commands: cmds, changes: cs, out: out, user: os.Getenv("USER")}
+	if c.user == "" {
+		c.user = "cli"
+	}
+	for _, opt := range options {
+		opt(c)
+	}
+	return c
+}
+
+// Execute a command and print its reply. The leading slash of the command is optional
+// Returns an error if the command is unknown or its arguments are invalid
+func (c *Cli) Execute(ctx context.Context, line string) error {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "/") {
+		line = "/" + line
+	}
+	text, export := splitExportModifier(line)
+	cli, valid := findCommand(c.commands, text)
+	if cli == "" {
+		return fmt.Errorf("unknown command %s. Type help to list the commands", text)
+	}
+	if !valid {
+		return fmt.Errorf("invalid arguments for %s. %s", cli, plainText(c.commands[cli].help))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+	m := Message{cmd: text, export: export}
+	wm := ChatMessage{sender: c.user, roomId: "cli", transport: cliTransport{c.out}}
+	r, unavailable := unavailableReply(c.apic, m, wm)
+	if !unavailable {
+		r = c.commands[cli].callback(ctx, c.apic, m, wm)
+	}
+	if c.json && export == "" {
+		return c.printJson(r)
+	}
+	return sendReply(r, m, wm, wm.roomId)
+}
+
+// Read commands line by line and execute them until exit, quit or the end of the input
+func (c *Cli) Repl(ctx context.Context, in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	fmt.Fprint(c.out, cliPrompt)
+	for scanner.Scan() {
+		switch line := strings.TrimSpace(scanner.Text()); line {
+		case "":
+		case "exit", "quit":
+			return nil
+		default:
+			if err := c.Execute(ctx, line); err != nil {
+				fmt.Fprintln(c.out, err)
+			}
+		}
+		fmt.Fprint(c.out, cliPrompt)
+	}
+	return scanner.Err()
+}
+
+// Print the data of a reply. Replies without data are printed as a text attribute
+func (c *Cli) printJson(r Reply) error {
+	data := r.data
+	if data == nil {
+		data = map[string]string{"text": plainText(r.text)}
+	}
+	enc := json.NewEncoder(c.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(data)
+}
+
+// Terminal transport. Every reply is printed as plain text
+type cliTransport struct {
+	out io.Writer
+}
+
+func (t cliTransport) Name() string {
+	return "cli"
+}
+
+func (t cliTransport) SendText(text, roomId, parentId string) error {
+	_, err := fmt.Fprintln(t.out, plainText(text))
+	return err
+}
+
+// Cards are not rendered. Their text fallback is printed instead
+func (t cliTransport) SendCard(text string, card webex.AdaptiveCard, roomId, parentId string) error {
+	return t.SendText(text, roomId, parentId)
+}
+
+// Exported files are printed as is, to be redirected or piped
+func (t cliTransport) SendFile(text string, f webex.WebexFile, roomId, parentId string) error {
+	_, err := t.out.Write(f.Content)
+	return err
+}
+
+func (t cliTransport) SendPlaceholder(text, roomId, parentId string) (string, error) {
+	return "", nil
+}
+
+func (t cliTransport) EditText(id, text, roomId string) error {
+	return t.SendText(text, roomId, "")
+}
+
+func (t cliTransport) RoomName(roomId string) string {
+	return roomId
+}
+
+var htmlTags = regexp.MustCompile(`<[^>]+>`)
+var cliReplacer = strings.NewReplacer("**", "", "<li>", "\n  • ", "<br>", "\n")
+
+// Remove the markdown and HTML tags of a reply
+func plainText(s string) string {
+	return html.UnescapeString(htmlTags.ReplaceAllString(cliReplacer.Replace(s), ""))
+}
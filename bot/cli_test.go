@@ -0,0 +1,93 @@
+package bot
+
+import (
+	"aci-chatbot/apic"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCli(t *testing.T) {
+	amc := apic.ApicMockClient
+	amc.SetDefaultFunctions()
+	out := &bytes.Buffer{}
+	c := NewCli(&amc, out)
+	c.user = "tester"
+
+	t.Run("Plain text reply", func(t *testing.T) {
+		out.Reset()
+		equals(t, c.Execute(context.Background(), "/cpu"), nil)
+		equals(t, strings.Contains(out.String(), "<"), false)
+		equals(t, strings.Contains(out.String(), "tester"), true)
+	})
+	t.Run("Leading slash is optional", func(t *testing.T) {
+		out.Reset()
+		equals(t, c.Execute(context.Background(), "node 101"), nil)
+		equals(t, strings.Contains(out.String(), "LEAF1"), true)
+	})
+	t.Run("Export", func(t *testing.T) {
+		out.Reset()
+		equals(t, c.Execute(context.Background(), "/faults export:csv"), nil)
+		equals(t, strings.HasPrefix(out.String(), "ack,cause,code,"), true)
+	})
+	t.Run("Unknown command", func(t *testing.T) {
+		equals(t, c.Execute(context.Background(), "/reboot") != nil, true)
+	})
+	t.Run("Invalid arguments", func(t *testing.T) {
+		err := c.Execute(context.Background(), "/node abc")
+		equals(t, err.Error(), "invalid arguments for /node. Get Node health and hardware status 🖥️. Usage /node [node_id] ")
+	})
+	t.Run("APIC not reachable", func(t *testing.T) {
+		defer func() { amc.CheckAvailableF = func() error { return nil } }()
+		amc.CheckAvailableF = func() error { return apic.ErrUnavailable }
+		out.Reset()
+		equals(t, c.Execute(context.Background(), "/cpu"), nil)
+		equals(t, strings.Contains(out.String(), "not reachable"), true)
+		out.Reset()
+		equals(t, c.Execute(context.Background(), "/help"), nil)
+		equals(t, strings.Contains(out.String(), "/cpu"), true)
+	})
+	t.Run("Subscriptions are not available", func(t *testing.T) {
+		equals(t, c.Execute(context.Background(), "/websocket faultInst") != nil, true)
+	})
+}
+
+func TestCliJson(t *testing.T) {
+	amc := apic.ApicMockClient
+	amc.SetDefaultFunctions()
+	out := &bytes.Buffer{}
+	c := NewCli(&amc, out, SetCliJson())
+
+	t.Run("Data of the reply", func(t *testing.T) {
+		out.Reset()
+		equals(t, c.Execute(context.Background(), "/faults 1"), nil)
+		var faults []apic.FaultInst
+		equals(t, json.Unmarshal(out.Bytes(), &faults), nil)
+		equals(t, faults[0].Code, "F1451")
+	})
+	t.Run("Reply without data", func(t *testing.T) {
+		out.Reset()
+		equals(t, c.Execute(context.Background(), "/help"), nil)
+		var reply map[string]string
+		equals(t, json.Unmarshal(out.Bytes(), &reply), nil)
+		equals(t, strings.Contains(reply["text"], "/websocket"), false)
+		equals(t, strings.Contains(reply["text"], "/cpu"), true)
+	})
+}
+
+func TestCliRepl(t *testing.T) {
+	amc := apic.ApicMockClient
+	amc.SetDefaultFunctions()
+	out := &bytes.Buffer{}
+	c := NewCli(&amc, out)
+
+	err := c.Repl(context.Background(), strings.NewReader("info\n\n/bad\nexit\n/cpu\n"))
+	equals(t, err, nil)
+	equals(t, strings.Count(out.String(), cliPrompt), 4)
+	equals(t, strings.Contains(out.String(), "Test Fabric"), true)
+	equals(t, strings.Contains(out.String(), "unknown command /bad"), true)
+	// Commands after exit are not executed
+	equals(t, strings.Contains(out.String(), "cpuPct"), false)
+}
@@ -0,0 +1,44 @@
+package bot
+
+import (
+	"sync"
+	"time"
+)
+
+// TTL cache of the webhooks already processed
+// Webex may deliver the same webhook more than once, even concurrently
+type webhookCache struct {
+	mu   sync.Mutex
+	ttl  time.Duration
+	seen map[string]time.Time
+}
+
+// Create a new cache. Entries expire after the TTL
+func newWebhookCache(ttl time.Duration) *webhookCache {
+	return &webhookCache{ttl: ttl, seen: make(map[string]time.Time)}
+}
+
+// Register a webhook ID. Returns false if the ID was already registered and has not expired
+func (wc *webhookCache) firstSeen(id string) bool {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+	now := time.Now()
+	// Purge the expired entries
+	for k, t := range wc.seen {
+		if now.Sub(t) > wc.ttl {
+			delete(wc.seen, k)
+		}
+	}
+	if _, ok := wc.seen[id]; ok {
+		return false
+	}
+	wc.seen[id] = now
+	return true
+}
+
+// Remove a webhook ID. Used when the webhook could not be processed and a redelivery is expected
+func (wc *webhookCache) forget(id string) {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+	delete(wc.seen, id)
+}
@@ -0,0 +1,106 @@
+package bot
+
+import (
+	"aci-chatbot/apic"
+	"aci-chatbot/webex"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWebhookCache(t *testing.T) {
+	t.Run("Register a webhook once", func(t *testing.T) {
+		wc := newWebhookCache(time.Minute)
+		equals(t, wc.firstSeen("W1"), true)
+		equals(t, wc.firstSeen("W1"), false)
+		equals(t, wc.firstSeen("W2"), true)
+	})
+	t.Run("Expired webhook", func(t *testing.T) {
+		wc := newWebhookCache(time.Millisecond)
+		equals(t, wc.firstSeen("W1"), true)
+		time.Sleep(5 * time.Millisecond)
+		equals(t, wc.firstSeen("W1"), true)
+		equals(t, len(wc.seen), 1)
+	})
+	t.Run("Forget a webhook", func(t *testing.T) {
+		wc := newWebhookCache(time.Minute)
+		equals(t, wc.firstSeen("W1"), true)
+		wc.forget("W1")
+		equals(t, wc.firstSeen("W1"), true)
+	})
+}
+
+func TestWebHookHanlderDuplicates(t *testing.T) {
+	wmc := webex.WebexMockClient
+	wmc.SetDefaultFunctions()
+	amc := apic.ApicMockClient
+	amc.SetDefaultFunctions()
+	var calls int32
+	amc.GetHealthHistoryF = func(ctx context.Context, g string) (apic.HealthHistory, error) {
+		atomic.AddInt32(&calls, 1)
+		return apic.HealthHistory{}, errors.New("Generic APIC Error")
+	}
+	b, _ := NewBot(&wmc, &amc, "http://test_bot.com")
+
+	// Replay the same webhook concurrently
+	replay := func(uri string, id string, n int) []int {
+		jp, _ := json.Marshal(webex.WebexWebhook{Name: "test-bot", Data: &webex.WebexWebhookData{Id: id, RoomId: "AbC13"}})
+		codes := make([]int, n)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				request, _ := http.NewRequest(http.MethodPost, uri, bytes.NewBuffer(jp))
+				response := httptest.NewRecorder()
+				b.router.ServeHTTP(response, request)
+				codes[i] = response.Code
+			}(i)
+		}
+		wg.Wait()
+		b.workers.wait()
+		return codes
+	}
+
+	t.Run("Message webhook delivered in parallel", func(t *testing.T) {
+		wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
+			return webex.WebexMessage{Id: id, Text: "/health", RoomId: "AbC13"}, nil
+		}
+		atomic.StoreInt32(&calls, 0)
+		for _, code := range replay("/webhook", "W1", 20) {
+			equals(t, code, http.StatusOK)
+		}
+		equals(t, atomic.LoadInt32(&calls), int32(1))
+	})
+	t.Run("Card action webhook delivered in parallel", func(t *testing.T) {
+		wmc.GetAttachmentActionByIdF = func(id string) (webex.WebexAttachmentAction, error) {
+			return webex.WebexAttachmentAction{Id: id, RoomId: "AbC13", Inputs: map[string]interface{}{"command": "/health"}}, nil
+		}
+		atomic.StoreInt32(&calls, 0)
+		for _, code := range replay("/actions", "A1", 20) {
+			equals(t, code, http.StatusOK)
+		}
+		equals(t, atomic.LoadInt32(&calls), int32(1))
+	})
+	t.Run("Redelivery after a failure", func(t *testing.T) {
+		fail := true
+		wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
+			if fail {
+				return webex.WebexMessage{}, errors.New("Generic Webex Error")
+			}
+			return webex.WebexMessage{Id: id, Text: "/health", RoomId: "AbC13"}, nil
+		}
+		atomic.StoreInt32(&calls, 0)
+		equals(t, replay("/webhook", "W2", 1)[0], http.StatusInternalServerError)
+		fail = false
+		equals(t, replay("/webhook", "W2", 1)[0], http.StatusOK)
+		equals(t, atomic.LoadInt32(&calls), int32(1))
+	})
+}
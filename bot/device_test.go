@@ -0,0 +1,85 @@
+package bot
+
+import (
+	"aci-chatbot/apic"
+	"aci-chatbot/webex"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWebexDeviceMode(t *testing.T) {
+	wmc := webex.WebexMockClient
+	wmc.SetDefaultFunctions()
+	amc := apic.ApicMockClient
+	amc.SetDefaultFunctions()
+	webhooks := 0
+	wmc.CreateWebhookF = func(name, url, resource, event string) error {
+		webhooks++
+		return nil
+	}
+	wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
+		return webex.WebexMessage{Id: id, Text: "/cpu", RoomId: "AbC13", PersonId: "ARandomId"}, nil
+	}
+	b, err := NewBot(&wmc, &amc, "", SetWebexDeviceMode())
+	equals(t, err, nil)
+
+	t.Run("No webhooks in device mode", func(t *testing.T) {
+		equals(t, webhooks, 0)
+	})
+	t.Run("Dispatch the events received over the websocket", func(t *testing.T) {
+		sent := make(chan struct{})
+		wmc.ListenEventsF = func(ctx context.Context, d webex.WebexDevice, events chan<- webex.WebexEvent) error {
+			events <- webex.WebexEvent{Resource: "messages", Id: "M1"}
+			close(sent)
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		errs := make(chan error)
+		go func() { errs <- b.ListenWebexDevice(ctx) }()
+		<-sent
+		cancel()
+		equals(t, <-errs, context.Canceled)
+		b.workers.wait()
+		equals(t, wmc.LastParentId, "M1")
+		equals(t, wmc.LastCardSent != nil, true)
+	})
+	t.Run("Reconnect after a connection loss", func(t *testing.T) {
+		defer func(d time.Duration) { reconnectDelay = d }(reconnectDelay)
+		reconnectDelay = 0
+		wmc.LastMsgSent = ""
+		wmc.GetAttachmentActionByIdF = func(id string) (webex.WebexAttachmentAction, error) {
+			return webex.WebexAttachmentAction{Id: id, RoomId: "AbC13", Inputs: map[string]interface{}{"command": "/websocket list"}}, nil
+		}
+		sent := make(chan struct{})
+		connections := 0
+		wmc.ListenEventsF = func(ctx context.Context, d webex.WebexDevice, events chan<- webex.WebexEvent) error {
+			connections++
+			if connections == 1 {
+				return errors.New("connection reset by peer")
+			}
+			events <- webex.WebexEvent{Resource: "attachmentActions", Id: "A1"}
+			close(sent)
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		errs := make(chan error)
+		go func() { errs <- b.ListenWebexDevice(ctx) }()
+		<-sent
+		cancel()
+		equals(t, <-errs, context.Canceled)
+		b.workers.wait()
+		equals(t, connections, 2)
+		equals(t, wmc.LastMsgSent, "Hi  🤖 !\n You are no subscribed to any class")
+	})
+	t.Run("Device registration error", func(t *testing.T) {
+		wmc.RegisterDeviceF = func(name string) (webex.WebexDevice, error) {
+			return webex.WebexDevice{}, errors.New("Generic Webex Error")
+		}
+		err := b.ListenWebexDevice(context.Background())
+		equals(t, err != nil, true)
+	})
+}
@@ -0,0 +1,185 @@
+package bot
+
+import (
+	"aci-chatbot/apic"
+	"aci-chatbot/webex"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Supported export formats and their content type
+var exportTypes = map[string]string{"csv": "text/csv", "json": "application/json"}
+
+// Remove the export:<format> modifier from a command
+// Returns the command without the modifier and the requested format
+func splitExportModifier(s string) (string, string) {
+	var cleaned []string
+	format := ""
+	for _, w := range strings.Split(s, " ") {
+		if strings.HasPrefix(w, "export:") {
+			if _, ok := exportTypes[strings.TrimPrefix(w, "export:")]; ok {
+				format = strings.TrimPrefix(w, "export:")
+				continue
+			}
+		}
+		cleaned = append(cleaned, w)
+	}
+	return strings.Join(cleaned, " "), format
+}
+
+// Send the reply of a command to a room
+// The data of the reply is uploaded as a file if an export was requested
+// Otherwise the card of the reply is sent, with the text as fallback
+func sendReply(r Reply, m Message, wm ChatMessage, roomId string) error {
+	if m.export == "" || r.data == nil {
+		if r.card != nil {
+			return wm.transport.SendCard(r.text, *r.card, roomId, wm.parentId)
+		}
+		return wm.transport.SendText(r.text, roomId, wm.parentId)
+	}
+	content, err := exportData(r.data, m.export)
+	if err != nil {
+		return wm.transport.SendText(fmt.Sprintf("Hi %s 🤖 !\n Sorry... I could not export the result as <code>%s</code>", wm.sender, m.export), roomId, wm.parentId)
+	}
+	name := strings.TrimPrefix(strings.Split(m.cmd, " ")[0], "/")
+	file := webex.WebexFile{Name: fmt.Sprintf("%s.%s", name, m.export), ContentType: exportTypes[m.export], Content: content}
+	return wm.transport.SendFile(fmt.Sprintf("Hi %s 🤖 !\n\n Here is the result of <code>%s</code> as <code>%s</code> 📎", wm.sender, m.cmd, m.export), file, roomId, wm.parentId)
+}
+
+// Encode the data of a reply in the requested format
+func exportData(data interface{}, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		return json.MarshalIndent(data, "", "  ")
+	case "csv":
+		records, err := csvRecords(data)
+		if err != nil {
+			return nil, err
+		}
+		buf := &bytes.Buffer{}
+		w := csv.NewWriter(buf)
+		if err = w.WriteAll(records); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unsupported export format %s", format)
+}
+
+// Flatten the data of a reply into CSV records. The first record is the header
+func csvRecords(data interface{}) ([][]string, error) {
+	switch d := data.(type) {
+	case []apic.ApicMoAttributes:
+		seen := make(map[string]bool)
+		keys := []string{}
+		for _, mo := range d {
+			for k := range mo {
+				if !seen[k] {
+					seen[k] = true
+					keys = append(keys, k)
+				}
+			}
+		}
+		sort.Strings(keys)
+		records := [][]string{keys}
+		for _, mo := range d {
+			row := []string{}
+			for _, k := range keys {
+				row = append(row, mo[k])
+			}
+			records = append(records, row)
+		}
+		return records, nil
+	case map[string][]string:
+		keys := make([]string, 0, len(d))
+		for k := range d {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		records := [][]string{{"neighbor", "node", "interface"}}
+		for _, k := range keys {
+			for _, n := range d[k] {
+				ni := strings.SplitN(n, ":", 2)
+				records = append(records, []string{k, ni[0], strings.Trim(ni[len(ni)-1], "[]")})
+			}
+		}
+		return records, nil
+	case apic.FabricInformation:
+		records := [][]string{{"section", "name", "value"}}
+		records = append(records, []string{"fabric", "name", d.Name}, []string{"fabric", "url", d.Url}, []string{"fabric", "health", d.Health})
+		for _, p := range d.Pods {
+			records = append(records, []string{"pod", p["id"], p["type"]})
+		}
+		for _, n := range d.Apics {
+			records = append(records, []string{"controller", n["name"], n["version"]})
+		}
+		for _, n := range d.Spines {
+			records = append(records, []string{"spine", n["name"], n["version"]})
+		}
+		for _, n := range d.Leafs {
+			records = append(records, []string{"leaf", n["name"], n["version"]})
+		}
+		return records, nil
+	case []apic.EndpointInformation:
+		records := [][]string{{"mac", "ips", "tenant", "app", "epg", "pod", "nodes", "type", "port"}}
+		for _, ep := range d {
+			row := []string{ep.Mac, strings.Join(ep.Ips, ";"), ep.Tenant, ep.App, ep.Epg}
+			if len(ep.Location) == 0 {
+				records = append(records, append(row, "", "", "", ""))
+			}
+			for _, l := range ep.Location {
+				records = append(records, append(append([]string{}, row...), l["pod"], l["nodes"], l["type"], l["port"]))
+			}
+		}
+		return records, nil
+	case []apic.InterfaceInformation:
+		records := [][]string{{"node", "interface", "adminSt", "operSt", "speed", "lastLinkStChg", "crcErrors", "inputErrors", "outputErrors", "inputUtil", "outputUtil"}}
+		for _, i := range d {
+			records = append(records, []string{i.Node, i.Id, i.AdminSt, i.OperSt, i.Speed, i.LastLinkStChg, i.CrcErrors, i.InputErrors, i.OutputErrors, i.InputUtil, i.OutputUtil})
+		}
+		return records, nil
+	case apic.NodeInformation:
+		records := [][]string{{"section", "name", "value"}}
+		for _, kv := range [][]string{{"id", d.Id}, {"name", d.Name}, {"role", d.Role}, {"pod", d.Pod}, {"model", d.Model}, {"serial", d.Serial},
+			{"address", d.Address}, {"fabricSt", d.FabricSt}, {"firmware", d.Firmware}, {"uptime", d.Uptime}, {"health", d.Health}} {
+			records = append(records, []string{"node", kv[0], kv[1]})
+		}
+		for _, item := range d.Supervisors {
+			records = append(records, []string{"supervisor", item["id"], item["status"]})
+		}
+		for _, item := range d.Fans {
+			records = append(records, []string{"fan", item["id"], item["status"]})
+		}
+		for _, item := range d.Psus {
+			records = append(records, []string{"psu", item["id"], item["status"]})
+		}
+		for _, f := range d.Faults {
+			records = append(records, []string{"fault", f.Code, f.Severity})
+		}
+		return records, nil
+	case apic.HealthHistory:
+		records := [][]string{{"type", "name", "min", "max", "avg", "samples"}}
+		for _, group := range []struct {
+			name   string
+			series []apic.HealthSeries
+		}{{"fabric", []apic.HealthSeries{d.Fabric}}, {"pod", d.Pods}, {"tenant", d.Tenants}, {"node", d.Nodes}} {
+			for _, hs := range group.series {
+				samples := []string{}
+				for _, v := range hs.Samples {
+					samples = append(samples, fmt.Sprint(v))
+				}
+				records = append(records, []string{group.name, hs.Name, fmt.Sprint(hs.Min), fmt.Sprint(hs.Max), fmt.Sprintf("%.2f", hs.Avg), strings.Join(samples, ";")})
+			}
+		}
+		return records, nil
+	}
+	// Typed MOs, e.g. []apic.FaultInst, are exported as their attributes
+	if attributes, err := apic.MarshalMos(data); err == nil {
+		return csvRecords(attributes)
+	}
+	return nil, fmt.Errorf("data type %T can not be exported as csv", data)
+}
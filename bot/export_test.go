@@ -0,0 +1,126 @@
+package bot
+
+import (
+	"aci-chatbot/apic"
+	"aci-chatbot/webex"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSplitExportModifier(t *testing.T) {
+	t.Run("No modifier", func(t *testing.T) {
+		cmd, format := splitExportModifier("/faults 5")
+		equals(t, cmd, "/faults 5")
+		equals(t, format, "")
+	})
+	t.Run("CSV modifier at the end", func(t *testing.T) {
+		cmd, format := splitExportModifier("/faults 5 export:csv")
+		equals(t, cmd, "/faults 5")
+		equals(t, format, "csv")
+	})
+	t.Run("JSON modifier in the middle", func(t *testing.T) {
+		cmd, format := splitExportModifier("/neigh export:json 101")
+		equals(t, cmd, "/neigh 101")
+		equals(t, format, "json")
+	})
+	t.Run("Unsupported format", func(t *testing.T) {
+		cmd, format := splitExportModifier("/info export:xml")
+		equals(t, cmd, "/info export:xml")
+		equals(t, format, "")
+	})
+}
+
+func TestExportData(t *testing.T) {
+	t.Run("Managed Objects as CSV", func(t *testing.T) {
+		out, err := exportData([]apic.ApicMoAttributes{{"code": "F1", "dn": "uni/a"}, {"code": "F2", "severity": "minor"}}, "csv")
+		equals(t, err, nil)
+		equals(t, string(out), "code,dn,severity\nF1,uni/a,\nF2,,minor\n")
+	})
+	t.Run("Neighbors as CSV", func(t *testing.T) {
+		out, err := exportData(map[string][]string{"SW2": {"102:[eth1/2]"}, "SW1": {"101:[eth1/1]", "102:[eth1/1]"}}, "csv")
+		equals(t, err, nil)
+		equals(t, string(out), "neighbor,node,interface\nSW1,101,eth1/1\nSW1,102,eth1/1\nSW2,102,eth1/2\n")
+	})
+	t.Run("Endpoints as CSV", func(t *testing.T) {
+		eps := []apic.EndpointInformation{{Mac: "AA:AA:AA:AA:AA:AA", Ips: []string{"10.0.0.1", "10.0.0.2"}, Tenant: "t", App: "a", Epg: "e",
+			Location: []map[string]string{{"pod": "1", "nodes": "101", "type": "Access", "port": "[eth1/1]"}}}}
+		out, err := exportData(eps, "csv")
+		equals(t, err, nil)
+		equals(t, string(out), "mac,ips,tenant,app,epg,pod,nodes,type,port\nAA:AA:AA:AA:AA:AA,10.0.0.1;10.0.0.2,t,a,e,1,101,Access,[eth1/1]\n")
+	})
+	t.Run("Fabric Information as JSON", func(t *testing.T) {
+		info := apic.FabricInformation{Name: "Fabric", Health: "90"}
+		out, err := exportData(info, "json")
+		equals(t, err, nil)
+		var decoded apic.FabricInformation
+		json.Unmarshal(out, &decoded)
+		equals(t, decoded, info)
+	})
+	t.Run("Unsupported data", func(t *testing.T) {
+		_, err := exportData("a string", "csv")
+		equals(t, err != nil, true)
+	})
+}
+
+func TestWebHookHanlderExport(t *testing.T) {
+	wmc := webex.WebexMockClient
+	wmc.SetDefaultFunctions()
+	amc := apic.ApicMockClient
+	amc.SetDefaultFunctions()
+	b, _ := NewBot(&wmc, &amc, "http://test_bot.com")
+	reqB := webex.WebexWebhook{
+		Name: "test-bot",
+		Data: &webex.WebexWebhookData{
+			RoomId: "AbC13",
+		},
+	}
+
+	t.Run("Export faults as CSV", func(t *testing.T) {
+		wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
+			return webex.WebexMessage{Text: "/faults 1 export:csv"}, nil
+		}
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		b.workers.wait()
+		equals(t, response.Code, http.StatusOK)
+		equals(t, wmc.LastMsgSent, "Hi  🤖 !\n\n Here is the result of <code>/faults 1</code> as <code>csv</code> 📎")
+		equals(t, wmc.LastFileSent.Name, "faults.csv")
+		equals(t, wmc.LastFileSent.ContentType, "text/csv")
+		equals(t, string(wmc.LastFileSent.Content), "ack,cause,code,created,descr,dn,domain,lastTransition,lc,severity,subject,type\n"+
+			",,F1451,2021-09-07T13:20:13.645+01:00,Power supply shutdown. (serial number ABCDEF),topology/pod-1/node-202/sys/ch/psuslot-1/psu/fault-F1451,,,raised,minor,,environmental\n")
+	})
+	t.Run("Export neighbors as JSON", func(t *testing.T) {
+		wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
+			return webex.WebexMessage{Text: "/neigh export:json"}, nil
+		}
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		b.workers.wait()
+		equals(t, response.Code, http.StatusOK)
+		equals(t, wmc.LastFileSent.Name, "neigh.json")
+		var neigh map[string][]string
+		json.Unmarshal(wmc.LastFileSent.Content, &neigh)
+		equals(t, neigh["SW1"], []string{"101:[eth1/1]", "102:[eth1/2]"})
+	})
+	t.Run("Export without data", func(t *testing.T) {
+		wmc.SetDefaultFunctions()
+		wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
+			return webex.WebexMessage{Text: "/websocket list export:csv"}, nil
+		}
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		b.workers.wait()
+		equals(t, response.Code, http.StatusOK)
+		equals(t, wmc.LastMsgSent, "Hi  🤖 !\n You are no subscribed to any class")
+		equals(t, wmc.LastFileSent, webex.WebexFile{})
+	})
+}
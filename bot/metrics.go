@@ -0,0 +1,46 @@
+package bot
+
+import (
+	"aci-chatbot/metrics"
+	"crypto/subtle"
+	"net/http"
+)
+
+// Metrics of the bot. See metricsHandler()
+var (
+	commandsTotal          = metrics.NewCounter("aci_chatbot_commands_total", "Commands executed, by command and result (ok, error, timeout, unavailable, busy, invalid)", "command", "result")
+	commandDuration        = metrics.NewHistogram("aci_chatbot_command_duration_seconds", "Duration of the commands, by command", metrics.DefBuckets, "command")
+	websocketEvents        = metrics.NewCounter("aci_chatbot_apic_websocket_events_total", "Events received over the APIC WebSocket, by class", "class")
+	websocketSubscriptions = metrics.NewGauge("aci_chatbot_apic_websocket_subscriptions", "Active APIC WebSocket subscriptions")
+	tokenRefreshes         = metrics.NewCounter("aci_chatbot_apic_token_refreshes_total", "Refreshes of the APIC token, by result (ok, error)", "result")
+)
+
+// Only serve the metrics on /metrics to the scrapers sending the token as bearer token
+// Without a token, the metrics are served to anyone
+func SetMetricsToken(token string) Option {
+	return func(b *Bot) {
+		b.metricsToken = token
+	}
+}
+
+// Expose the metrics of the bot and of the APIC and Webex clients in the Prometheus text format
+// Requests without the bearer token are rejected, if a token is set
+func metricsHandler(wsSubs *webSocketDb, token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		websocketSubscriptions.Set(float64(len(wsSubs.getActiveSubscriptions())))
+		metrics.Handler().ServeHTTP(w, r)
+	}
+}
+
+// Result of a call used as metric label
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"aci-chatbot/apic"
+	"aci-chatbot/webex"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	wmc := webex.WebexMockClient
+	wmc.SetDefaultFunctions()
+	amc := apic.ApicMockClient
+	amc.SetDefaultFunctions()
+	b, _ := NewBot(&wmc, &amc, "http://test_bot.com", SetMetricsToken("s3cr3t"))
+	b.wsSubs.addSubcription("faultInst", "1234", "webex:AbC13")
+	var request *http.Request
+	// The commands are labelled with their registered name, never with the text sent
+	for _, text := range []string{"/cpu", "/cpu bogus", "/random-text-1234"} {
+		text := text
+		wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
+			return webex.WebexMessage{Text: text}, nil
+		}
+		jp, _ := json.Marshal(webex.WebexWebhook{Name: "test-bot", Data: &webex.WebexWebhookData{RoomId: "AbC13"}})
+		request, _ = http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
+		b.router.ServeHTTP(httptest.NewRecorder(), request)
+		b.workers.wait()
+	}
+
+	for _, auth := range []string{"", "Bearer wrong"} {
+		request, _ = http.NewRequest(http.MethodGet, "/metrics", nil)
+		request.Header.Set("Authorization", auth)
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		equals(t, response.Code, http.StatusUnauthorized)
+	}
+	request, _ = http.NewRequest(http.MethodGet, "/metrics", nil)
+	request.Header.Set("Authorization", "Bearer s3cr3t")
+	response := httptest.NewRecorder()
+	b.router.ServeHTTP(response, request)
+	equals(t, response.Code, http.StatusOK)
+	for _, l := range []string{
+		"\naci_chatbot_commands_total{command=\"/cpu\",result=\"ok\"} ",
+		"\naci_chatbot_command_duration_seconds_count{command=\"/cpu\"} ",
+		"\naci_chatbot_commands_total{command=\"unknown\",result=\"invalid\"} ",
+		"\naci_chatbot_apic_websocket_subscriptions 1\n",
+		"\n# TYPE aci_chatbot_apic_websocket_events_total counter\n",
+		"\n# TYPE aci_chatbot_apic_token_refreshes_total counter\n",
+		"\n# TYPE aci_chatbot_apic_request_duration_seconds histogram\n",
+		"\n# TYPE aci_chatbot_webex_rate_limited_total counter\n",
+	} {
+		equals(t, strings.Contains(response.Body.String(), l), true)
+	}
+	equals(t, strings.Contains(response.Body.String(), "random-text-1234"), false)
+
+	// Served to anyone without a token
+	b, _ = NewBot(&wmc, &amc, "http://test_bot.com")
+	request, _ = http.NewRequest(http.MethodGet, "/metrics", nil)
+	response = httptest.NewRecorder()
+	b.router.ServeHTTP(response, request)
+	equals(t, response.Code, http.StatusOK)
+	equals(t, strings.Contains(response.Body.String(), "\n# TYPE aci_chatbot_commands_total counter\n"), true)
+}
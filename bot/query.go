@@ -0,0 +1,266 @@
+package bot
+
+import (
+	"aci-chatbot/apic"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// /query limits
+const (
+	queryDefaultSize = 20   // MOs per page unless size: is set
+	queryMaxSize     = 100  // Largest page. Larger results are paged or exported
+	queryMaxCell     = 48   // Longer values are truncated in the table
+	queryMaxTable    = 6000 // Bytes of the table. Webex rejects messages longer than 7439 bytes
+)
+
+// Values accepted by the /query options, by option
+var queryOptionValues = map[string][]string{
+	"target":  {"self", "children", "subtree"},
+	"subtree": {"no", "children", "full"},
+	"include": {"all", "naming-only", "config-only"},
+}
+
+var queryAttrs = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*(,[A-Za-z][A-Za-z0-9]*)*$`)
+var queryOrder = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9]*\.)?[A-Za-z][A-Za-z0-9]*(\|(asc|desc))?$`)
+
+// Webex clients may replace the quotes of a filter with typographic ones
+var quoteReplacer = strings.NewReplacer("“", `"`, "”", `"`, "‘", "'", "’", "'")
+
+// Parsed /query command
+type queryRequest struct {
+	kind    string // class or dn
+	target  string // Class name or DN
+	filter  string
+	attrs   []string          // Columns of the table. Default columns are used if empty
+	options map[string]string // target, subtree, include, order, page and size options
+}
+
+// Parse a /query command
+// /query class <class> [filter:opt] [attrs:opt] [options:opt]
+// /query dn <dn> [children|subtree:opt] [attrs:opt] [options:opt]
+// Options are key:value pairs. Filters are APIC filter expressions, e.g. eq(fvBD.name,"myBD")
+func parseQueryCommand(s string) (queryRequest, error) {
+	w := strings.Fields(quoteReplacer.Replace(s))
+	q := queryRequest{kind: w[1], target: w[2], options: make(map[string]string)}
+	for _, arg := range w[3:] {
+		if kv := strings.SplitN(arg, ":", 2); len(kv) == 2 && isQueryOption(kv[0]) {
+			if _, dup := q.options[kv[0]]; dup {
+				return q, fmt.Errorf("the option %s is set twice", kv[0])
+			}
+			q.options[kv[0]] = kv[1]
+			continue
+		}
+		switch {
+		case strings.Contains(arg, "(") && q.kind == "class" && q.filter == "":
+			q.filter = arg
+		case (arg == "children" || arg == "subtree") && q.kind == "dn" && q.options["target"] == "":
+			q.options["target"] = arg
+		case queryAttrs.MatchString(arg) && q.attrs == nil:
+			q.attrs = strings.Split(arg, ",")
+		default:
+			return q, fmt.Errorf("unexpected argument %s", arg)
+		}
+	}
+	return q, q.validate()
+}
+
+func isQueryOption(k string) bool {
+	switch k {
+	case "target", "subtree", "include", "order", "page", "size":
+		return true
+	}
+	return false
+}
+
+// Check the values of the options
+func (q queryRequest) validate() error {
+	for k, values := range queryOptionValues {
+		if v, ok := q.options[k]; ok && !contains(values, v) {
+			return fmt.Errorf("%s must be one of %s", k, strings.Join(values, ", "))
+		}
+	}
+	if v, ok := q.options["order"]; ok && !queryOrder.MatchString(v) {
+		return errors.New("order must be an attribute, optionally followed by |asc or |desc")
+	}
+	if v, ok := q.options["page"]; ok {
+		if p, err := strconv.Atoi(v); err != nil || p < 0 {
+			return errors.New("page must be a positive number")
+		}
+	}
+	if v, ok := q.options["size"]; ok {
+		if p, err := strconv.Atoi(v); err != nil || p < 1 || p > queryMaxSize {
+			return fmt.Errorf("size must be between 1 and %d", queryMaxSize)
+		}
+	}
+	return nil
+}
+
+// Build the APIC query
+func (q queryRequest) query() *apic.Query {
+	query := apic.DnQuery(q.target)
+	if q.kind == "class" {
+		query = apic.ClassQuery(q.target)
+	}
+	size, err := strconv.Atoi(q.options["size"])
+	if err != nil {
+		size = queryDefaultSize
+	}
+	query.PageSize(size)
+	if p, err := strconv.Atoi(q.options["page"]); err == nil {
+		query.Page(p)
+	}
+	if q.filter != "" {
+		query.Filter(apic.Filter(q.filter))
+	}
+	if o, ok := q.options["order"]; ok {
+		s := strings.SplitN(o, "|", 2)
+		// Attributes are qualified with the queried class by default
+		if !strings.Contains(s[0], ".") && q.kind == "class" {
+			s[0] = q.target + "." + s[0]
+		}
+		order := apic.Asc
+		if len(s) == 2 {
+			order = s[1]
+		}
+		query.OrderBy(s[0], order)
+	}
+	if v, ok := q.options["target"]; ok {
+		query.Target(v)
+	}
+	if v, ok := q.options["subtree"]; ok {
+		query.Subtree(v)
+	}
+	if v, ok := q.options["include"]; ok {
+		query.PropInclude(v)
+	}
+	return query
+}
+
+// /query handler
+func queryCommand(ctx context.Context, c apic.ApicInterface, m Message, wm ChatMessage) Reply {
+	q, err := parseQueryCommand(m.cmd)
+	if err != nil {
+		return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n Sorry... %s. Usage <code>/query class [class] [filter:opt] [attrs:opt]</code> or <code>/query dn [dn] [subtree:opt]</code>", wm.sender, err)}
+	}
+	res, err := c.Query(ctx, q.query())
+	if err != nil {
+		log.Printf("Error while querying the APIC. Err: %s", err)
+		return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n Sorry... I could not run the query. Please check the %s and the options", wm.sender, q.kind)}
+	}
+	rows := flattenMos(res.Mos, 0)
+	if len(rows) == 0 {
+		return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n No objects found for <code>%s</code>", wm.sender, html.EscapeString(q.target)), data: []apic.ApicMoAttributes{}}
+	}
+	table, shown := queryTable(rows, queryColumns(q, rows), queryMaxTable)
+
+	page, _ := strconv.Atoi(q.options["page"])
+	size, err := strconv.Atoi(q.options["size"])
+	if err != nil {
+		size = queryDefaultSize
+	}
+	text := fmt.Sprintf("Hi %s 🤖 !\n **%d** objects of <code>%s</code> (page %d, %d in total):\n<pre>%s</pre>", wm.sender, len(res.Mos), html.EscapeString(q.target), page, res.TotalCount, table)
+	if shown < len(rows) {
+		text += fmt.Sprintf("\n Only the first %d of %d rows are shown. Use <code>attrs</code>, <code>size:</code> and <code>page:</code>, or <code>export:csv</code> ✂️", shown, len(rows))
+	} else if res.TotalCount > (page+1)*size {
+		text += fmt.Sprintf("\n More objects are available. Use <code>page:%d</code> 📄", page+1)
+	}
+	data := make([]apic.ApicMoAttributes, 0, len(rows))
+	for _, r := range rows {
+		data = append(data, r.attributes)
+	}
+	return Reply{text: text, data: data}
+}
+
+// Row of the /query table
+type queryRow struct {
+	depth      int // Children are indented
+	attributes apic.ApicMoAttributes
+}
+
+// Flatten the MOs and their children, depth first. The class of the MO is added to the attributes
+func flattenMos(mos []apic.ApicMo, depth int) []queryRow {
+	rows := []queryRow{}
+	for _, mo := range mos {
+		att := apic.ApicMoAttributes{"class": mo.Class}
+		for k, v := range mo.Attributes {
+			att[k] = v
+		}
+		rows = append(rows, queryRow{depth: depth, attributes: att})
+		rows = append(rows, flattenMos(mo.Children, depth+1)...)
+	}
+	return rows
+}
+
+// Columns of the table. The class is shown if the rows are of several classes
+func queryColumns(q queryRequest, rows []queryRow) []string {
+	cols := q.attrs
+	if len(cols) == 0 {
+		cols = []string{"dn"}
+		for _, r := range rows {
+			if _, ok := r.attributes["name"]; ok {
+				cols = append(cols, "name")
+				break
+			}
+		}
+	}
+	for _, r := range rows {
+		if r.attributes["class"] != rows[0].attributes["class"] {
+			return append([]string{"class"}, cols...)
+		}
+	}
+	return cols
+}
+
+// Render the rows as a text table, escaped for HTML
+// Rows are added while the table fits in max bytes. Returns the table and the number of rows rendered
+func queryTable(rows []queryRow, cols []string, max int) (string, int) {
+	cells := make([][]string, len(rows))
+	width := make([]int, len(cols))
+	for i, c := range cols {
+		width[i] = len([]rune(c))
+	}
+	for r, row := range rows {
+		for i, c := range cols {
+			v := row.attributes[c]
+			if c == cols[0] {
+				v = strings.Repeat("  ", row.depth) + v
+			}
+			if t := []rune(v); len(t) > queryMaxCell {
+				v = string(t[:queryMaxCell-1]) + "…"
+			}
+			cells[r] = append(cells[r], v)
+			if l := len([]rune(v)); l > width[i] {
+				width[i] = l
+			}
+		}
+	}
+	line := func(values []string) string {
+		padded := make([]string, len(values))
+		for i, v := range values {
+			padded[i] = v + strings.Repeat(" ", width[i]-len([]rune(v)))
+		}
+		return html.EscapeString(strings.TrimRight(strings.Join(padded, "  "), " ")) + "\n"
+	}
+	sep := make([]string, len(cols))
+	for i := range cols {
+		sep[i] = strings.Repeat("-", width[i])
+	}
+	table := line(cols) + line(sep)
+	shown := 0
+	for _, c := range cells {
+		l := line(c)
+		if len(table)+len(l) > max {
+			break
+		}
+		table += l
+		shown++
+	}
+	return strings.TrimRight(table, "\n"), shown
+}
@@ -0,0 +1,70 @@
+package bot
+
+import (
+	"aci-chatbot/apic"
+	"aci-chatbot/webex"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestQueryCommand(t *testing.T) {
+	amc := apic.ApicMockClient
+	amc.SetDefaultFunctions()
+	wmc := webex.WebexMockClient
+	wm := ChatMessage{sender: "alice", roomId: "R1", transport: webexTransport{&wmc}}
+	var query string
+	defaultQuery := amc.QueryF
+	amc.QueryF = func(ctx context.Context, q *apic.Query) (apic.QueryResult, error) {
+		query = q.String()
+		return defaultQuery(ctx, q)
+	}
+
+	t.Run("Class with filter and options", func(t *testing.T) {
+		r := queryCommand(context.Background(), &amc, Message{cmd: `/query class fvBD eq(fvBD.name,“myBD”) name,unicastRoute order:name|desc size:5 page:1 include:config-only`}, wm)
+		equals(t, query, "/api/node/class/fvBD.json?page-size=5&page=1&query-target-filter=eq%28fvBD.name%2C%22myBD%22%29&order-by=fvBD.name%7Cdesc&rsp-prop-include=config-only")
+		equals(t, strings.Contains(r.text, "<pre>name     unicastRoute\n-------  ------------\nmyBD     yes\ndefault  no</pre>"), true)
+		equals(t, len(r.data.([]apic.ApicMoAttributes)), 2)
+	})
+	t.Run("DN with subtree", func(t *testing.T) {
+		r := queryCommand(context.Background(), &amc, Message{cmd: "/query dn uni/tn-myTenant children subtree:children"}, wm)
+		equals(t, query, "/api/node/mo/uni/tn-myTenant.json?page-size=20&query-target=children&rsp-subtree=children")
+		equals(t, strings.Contains(r.text, "fvTenant  uni/tn-myTenant           myTenant\n  fvAp    uni/tn-myTenant/ap-myApp  myApp</pre>"), true)
+		equals(t, r.data.([]apic.ApicMoAttributes)[1]["class"], "fvAp")
+	})
+	t.Run("Target escaped", func(t *testing.T) {
+		r := queryCommand(context.Background(), &amc, Message{cmd: "/query dn uni/tn-<b>x</b>"}, wm)
+		equals(t, strings.Contains(r.text, "objects of <code>uni/tn-&lt;b&gt;x&lt;/b&gt;</code>"), true)
+	})
+	t.Run("Invalid option", func(t *testing.T) {
+		r := queryCommand(context.Background(), &amc, Message{cmd: "/query class fvBD size:500"}, wm)
+		equals(t, strings.HasPrefix(r.text, "Hi alice 🤖 !\n Sorry... size must be between 1 and 100."), true)
+		r = queryCommand(context.Background(), &amc, Message{cmd: "/query class fvBD target:everything"}, wm)
+		equals(t, strings.HasPrefix(r.text, "Hi alice 🤖 !\n Sorry... target must be one of self, children, subtree."), true)
+	})
+	t.Run("More pages", func(t *testing.T) {
+		amc.QueryF = func(ctx context.Context, q *apic.Query) (apic.QueryResult, error) {
+			c := q.Class()
+			return apic.QueryResult{TotalCount: 30, Mos: []apic.ApicMo{{Class: c, Attributes: apic.ApicMoAttributes{"dn": "uni/tn-a"}}}}, nil
+		}
+		r := queryCommand(context.Background(), &amc, Message{cmd: "/query class fvTenant size:1"}, wm)
+		equals(t, strings.HasSuffix(r.text, "More objects are available. Use <code>page:1</code> 📄"), true)
+	})
+	t.Run("Truncated table", func(t *testing.T) {
+		amc.QueryF = func(ctx context.Context, q *apic.Query) (apic.QueryResult, error) {
+			c := q.Class()
+			mos := []apic.ApicMo{}
+			for i := 0; i < 100; i++ {
+				mos = append(mos, apic.ApicMo{Class: c, Attributes: apic.ApicMoAttributes{"dn": fmt.Sprintf("uni/tn-t%d/%s", i, strings.Repeat("<x>", 30))}})
+			}
+			return apic.QueryResult{TotalCount: 100, Mos: mos}, nil
+		}
+		r := queryCommand(context.Background(), &amc, Message{cmd: "/query class fvTenant size:100"}, wm)
+		equals(t, len(r.text) < 7439, true)
+		equals(t, strings.Contains(r.text, "<x>"), false)
+		equals(t, strings.Contains(r.text, "…"), true)
+		equals(t, strings.Contains(r.text, "Only the first"), true)
+		equals(t, len(r.data.([]apic.ApicMoAttributes)), 100)
+	})
+}
@@ -0,0 +1,164 @@
+package bot
+
+import (
+	"aci-chatbot/apic"
+	"aci-chatbot/webex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Emojis used to render the status of faults and events
+var (
+	sevMap = map[string]string{"critical": "📛", "major": "☢️", "minor": "⚠️", "warning": "🌀", "cleared": "❎"}
+	lcMap  = map[string]string{"soaking": "♻️", "retaining": "✅", "raised": "❌", "soaking-clearing": "♻️", "raised-clearing": "♻️"}
+	indMap = map[string]string{"creation": "❇️", "modification": "🔄", "deletion": "🗑"}
+)
+
+// Adaptive Card templates. Each card renders the typed result of a command
+
+// /info card
+func infoCard(info apic.FabricInformation) *webex.AdaptiveCard {
+	apics := []webex.CardFact{}
+	for _, item := range info.Apics {
+		apics = append(apics, webex.CardFact{Title: item["name"], Value: item["version"]})
+	}
+	pods := []webex.CardFact{}
+	for _, item := range info.Pods {
+		pods = append(pods, webex.CardFact{Title: "Pod" + item["id"], Value: item["type"]})
+	}
+	card := webex.NewAdaptiveCard(
+		webex.CardTitle(fmt.Sprintf("Fabric %s ℹ️", info.Name)),
+		webex.CardElement{Type: "TextBlock", Text: info.Url, IsSubtle: true, Wrap: true},
+		webex.CardFacts(
+			webex.CardFact{Title: "Health Score", Value: info.Health},
+			webex.CardFact{Title: "Spines", Value: strconv.Itoa(len(info.Spines))},
+			webex.CardFact{Title: "Leafs", Value: strconv.Itoa(len(info.Leafs))},
+		),
+		webex.CardContainer(webex.CardText("**APIC Controllers**"), webex.CardFacts(apics...)),
+		webex.CardContainer(webex.CardText("**Pods**"), webex.CardFacts(pods...)),
+	)
+	return &card
+}
+
+// /ep card
+func endpointCard(mac string, info []apic.EndpointInformation) *webex.AdaptiveCard {
+	card := webex.NewAdaptiveCard(webex.CardTitle(fmt.Sprintf("Endpoint %s 💻", mac)))
+	for _, item := range info {
+		facts := []webex.CardFact{
+			{Title: "Tenant", Value: item.Tenant},
+			{Title: "Application Profile", Value: item.App},
+			{Title: "EPG", Value: item.Epg},
+		}
+		if len(item.Ips) > 0 {
+			facts = append(facts, webex.CardFact{Title: "IPs", Value: strings.Join(item.Ips, ", ")})
+		}
+		for idx, path := range item.Location {
+			facts = append(facts, webex.CardFact{
+				Title: fmt.Sprintf("Location %d", idx+1),
+				Value: fmt.Sprintf("Pod %s · Node %s · %s · %s", path["pod"], path["nodes"], path["type"], path["port"]),
+			})
+		}
+		card.Body = append(card.Body, webex.CardContainer(webex.CardFacts(facts...)))
+	}
+	card.Body = append(card.Body, webex.CardActions(
+		webex.CardSubmit("Show history", map[string]string{"command": fmt.Sprintf("/ep %s history", mac)}),
+	))
+	return &card
+}
+
+// /faults card
+func faultsCard(info []apic.FaultInst) *webex.AdaptiveCard {
+	card := webex.NewAdaptiveCard(webex.CardTitle(fmt.Sprintf("Latest %d faults ⚠️", len(info))))
+	for _, f := range info {
+		card.Body = append(card.Body, webex.CardContainer(
+			webex.CardText(fmt.Sprintf("**%s** %s %s", f.Code, f.Severity, sevMap[f.Severity])),
+			webex.CardElement{Type: "TextBlock", Text: f.Dn, IsSubtle: true, Wrap: true},
+			webex.CardText(f.Descr),
+			webex.CardFacts(
+				webex.CardFact{Title: "Lifecycle", Value: fmt.Sprintf("%s %s", f.Lc, lcMap[f.Lc])},
+				webex.CardFact{Title: "Type", Value: f.Type},
+				webex.CardFact{Title: "Created", Value: f.Created},
+			),
+			webex.CardActions(
+				webex.CardSubmit("Acknowledge", map[string]string{"command": fmt.Sprintf("/faults ack %s", f.Dn)}),
+			),
+		))
+	}
+	return &card
+}
+
+// /events card
+func eventsCard(info []apic.AaaModLR) *webex.AdaptiveCard {
+	card := webex.NewAdaptiveCard(webex.CardTitle(fmt.Sprintf("Latest %d events ❎", len(info))))
+	for _, f := range info {
+		card.Body = append(card.Body, webex.CardContainer(
+			webex.CardText(fmt.Sprintf("**%s** %s %s", f.Code, f.Ind, indMap[f.Ind])),
+			webex.CardElement{Type: "TextBlock", Text: f.Affected, IsSubtle: true, Wrap: true},
+			webex.CardText(f.Descr),
+			webex.CardFacts(
+				webex.CardFact{Title: "User", Value: f.User},
+				webex.CardFact{Title: "Created", Value: f.Created},
+			),
+		))
+	}
+	return &card
+}
+
+// /node card
+func nodeCard(info apic.NodeInformation) *webex.AdaptiveCard {
+	card := webex.NewAdaptiveCard(
+		webex.CardTitle(fmt.Sprintf("Node %s - %s 🖥️", info.Id, info.Name)),
+		webex.CardFacts(
+			webex.CardFact{Title: "Role", Value: info.Role},
+			webex.CardFact{Title: "Pod", Value: info.Pod},
+			webex.CardFact{Title: "Model", Value: info.Model},
+			webex.CardFact{Title: "Serial", Value: info.Serial},
+			webex.CardFact{Title: "Address", Value: info.Address},
+			webex.CardFact{Title: "Fabric State", Value: info.FabricSt},
+			webex.CardFact{Title: "Firmware", Value: info.Firmware},
+			webex.CardFact{Title: "Uptime", Value: info.Uptime},
+			webex.CardFact{Title: "Health Score", Value: info.Health},
+			webex.CardFact{Title: "Active Faults", Value: strconv.Itoa(len(info.Faults))},
+		),
+		webex.CardActions(
+			webex.CardSubmit("Subscribe", map[string]string{"command": fmt.Sprintf("/websocket topology/pod-%s/node-%s", info.Pod, info.Id)}),
+		),
+	)
+	return &card
+}
+
+// /cpu card
+func cpuCard(cpu []apic.ProcEntity) *webex.AdaptiveCard {
+	facts := []webex.CardFact{}
+	for _, item := range cpu {
+		memFree, _ := strconv.ParseFloat(item.MemFree, 32)
+		memMax, _ := strconv.ParseFloat(item.MaxMemAlloc, 32)
+		facts = append(facts, webex.CardFact{
+			Title: fmt.Sprintf("APIC %s", apic.GetRn(item.Dn, "node")),
+			Value: fmt.Sprintf("💻 CPU %s%% · 💾 Memory %.1f%%", item.CpuPct, 100.0*memFree/memMax),
+		})
+	}
+	card := webex.NewAdaptiveCard(webex.CardTitle("APIC CPU & Memory 💾"), webex.CardFacts(facts...))
+	return &card
+}
+
+// /neigh card
+func neighCard(node string, info map[string][]string) *webex.AdaptiveCard {
+	keys := make([]string, 0, len(info))
+	for k := range info {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	facts := []webex.CardFact{}
+	for _, k := range keys {
+		facts = append(facts, webex.CardFact{Title: k, Value: strings.Join(info[k], " ")})
+	}
+	title := "Fabric Topology 🔢"
+	if node != "all" {
+		title = fmt.Sprintf("Neighbors of Node %s 🔢", node)
+	}
+	card := webex.NewAdaptiveCard(webex.CardTitle(title), webex.CardFacts(facts...))
+	return &card
+}
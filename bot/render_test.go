@@ -0,0 +1,201 @@
+package bot
+
+import (
+	"aci-chatbot/apic"
+	"aci-chatbot/webex"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Regenerate the golden files with: go test ./bot -run TestCards -update
+var update = flag.Bool("update", false, "update the golden files of the card templates")
+
+// Compare the JSON of a card with its golden file in testdata/cards
+func golden(tb testing.TB, name string, card *webex.AdaptiveCard) {
+	act, err := json.MarshalIndent(card, "", "  ")
+	equals(tb, err, nil)
+	path := filepath.Join("testdata", "cards", name+".json")
+	if *update {
+		equals(tb, ioutil.WriteFile(path, append(act, '\n'), 0644), nil)
+	}
+	exp, err := ioutil.ReadFile(path)
+	equals(tb, err, nil)
+	equals(tb, string(act)+"\n", string(exp))
+}
+
+func TestCards(t *testing.T) {
+	amc := apic.ApicMockClient
+	amc.SetDefaultFunctions()
+
+	t.Run("/info card", func(t *testing.T) {
+		info, _ := amc.GetFabricInformation(context.Background())
+		golden(t, "info", infoCard(info))
+	})
+	t.Run("/ep card", func(t *testing.T) {
+		info, _ := amc.GetEndpointInformation(context.Background(), "AA:AA:AA:BB:BB:CC")
+		golden(t, "ep", endpointCard("AA:AA:AA:BB:BB:CC", info))
+	})
+	t.Run("/faults card", func(t *testing.T) {
+		info, _ := amc.GetLatestFaults(context.Background(), "10")
+		golden(t, "faults", faultsCard(info))
+	})
+	t.Run("/events card", func(t *testing.T) {
+		info, _ := amc.GetLatestEvents(context.Background(), "10")
+		golden(t, "events", eventsCard(info))
+	})
+	t.Run("/cpu card", func(t *testing.T) {
+		info, _ := amc.GetProcEntity(context.Background())
+		golden(t, "cpu", cpuCard(info))
+	})
+	t.Run("/neigh card", func(t *testing.T) {
+		info, _ := amc.GetFabricNeighbors(context.Background(), "all")
+		golden(t, "neigh", neighCard("all", info))
+	})
+	t.Run("/node card", func(t *testing.T) {
+		info, _ := amc.GetNodeInformation(context.Background(), "101")
+		golden(t, "node", nodeCard(info))
+	})
+}
+
+func TestActionsHandler(t *testing.T) {
+	wmc := webex.WebexMockClient
+	wmc.SetDefaultFunctions()
+	amc := apic.ApicMockClient
+	amc.SetDefaultFunctions()
+	b, _ := NewBot(&wmc, &amc, "http://test_bot.com", SetAdmins("ARandomId"))
+	reqB := webex.WebexWebhook{
+		Name:     "test-bot",
+		Resource: "attachmentActions",
+		Data: &webex.WebexWebhookData{
+			Id:     "Action1",
+			RoomId: "AbC13",
+		},
+	}
+
+	t.Run("Acknowledge fault", func(t *testing.T) {
+		posted := ""
+		amc.PostMoF = func(ctx context.Context, dn string, payload []byte) error {
+			posted = dn + " " + string(payload)
+			return nil
+		}
+		command := "/faults ack topology/pod-1/node-101/fault-F0532"
+		wmc.GetAttachmentActionByIdF = func(id string) (webex.WebexAttachmentAction, error) {
+			return webex.WebexAttachmentAction{Id: id, PersonId: "ARandomId", RoomId: "AbC13",
+				Inputs: map[string]interface{}{"command": command}}, nil
+		}
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/actions", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		b.workers.wait()
+		equals(t, response.Code, http.StatusOK)
+		// The acknowledgement is only posted once confirmed
+		equals(t, posted, "")
+		equals(t, strings.Contains(wmc.LastMsgSent, "Acknowledge the fault topology/pod-1/node-101/fault-F0532"), true)
+
+		command = "/confirm 1"
+		reqB.Data.Id = "Action1-confirm"
+		jp, _ = json.Marshal(reqB)
+		request, _ = http.NewRequest(http.MethodPost, "/actions", bytes.NewBuffer(jp))
+		b.router.ServeHTTP(httptest.NewRecorder(), request)
+		b.workers.wait()
+		equals(t, posted, `topology/pod-1/node-101/fault-F0532 {"faultInst":{"attributes":{"ack":"yes","dn":"topology/pod-1/node-101/fault-F0532"}}}`)
+	})
+	t.Run("Action without command", func(t *testing.T) {
+		reqB.Data.Id = "Action2"
+		wmc.GetAttachmentActionByIdF = func(id string) (webex.WebexAttachmentAction, error) {
+			return webex.WebexAttachmentAction{Id: id, Inputs: map[string]interface{}{}}, nil
+		}
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/actions", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		b.workers.wait()
+		equals(t, response.Code, http.StatusBadRequest)
+	})
+	t.Run("Action not found", func(t *testing.T) {
+		reqB.Data.Id = "Action3"
+		wmc.GetAttachmentActionByIdF = func(id string) (webex.WebexAttachmentAction, error) {
+			return webex.WebexAttachmentAction{}, errors.New("Generic Webex Error")
+		}
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/actions", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		b.workers.wait()
+		equals(t, response.Code, http.StatusInternalServerError)
+	})
+	t.Run("Action submitted by the bot", func(t *testing.T) {
+		reqB.Data.Id = "Action4"
+		wmc.LastMsgSent = ""
+		wmc.GetAttachmentActionByIdF = func(id string) (webex.WebexAttachmentAction, error) {
+			return webex.WebexAttachmentAction{Id: id, PersonId: "ABC123", RoomId: "AbC13",
+				Inputs: map[string]interface{}{"command": "/info"}}, nil
+		}
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/actions", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		b.workers.wait()
+		equals(t, response.Code, http.StatusOK)
+		equals(t, wmc.LastMsgSent, "")
+	})
+	t.Run("Action without data", func(t *testing.T) {
+		jp, _ := json.Marshal(webex.WebexWebhook{Name: "test-bot", Resource: "attachmentActions"})
+		request, _ := http.NewRequest(http.MethodPost, "/actions", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		equals(t, response.Code, http.StatusBadRequest)
+	})
+}
+
+func TestWebHookHanlderCard(t *testing.T) {
+	wmc := webex.WebexMockClient
+	wmc.SetDefaultFunctions()
+	amc := apic.ApicMockClient
+	amc.SetDefaultFunctions()
+	wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
+		return webex.WebexMessage{Text: "/info"}, nil
+	}
+	b, _ := NewBot(&wmc, &amc, "http://test_bot.com")
+	reqB := webex.WebexWebhook{
+		Name: "test-bot",
+		Data: &webex.WebexWebhookData{
+			RoomId: "AbC13",
+		},
+	}
+
+	t.Run("Card with markdown fallback", func(t *testing.T) {
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		b.workers.wait()
+		equals(t, response.Code, http.StatusOK)
+		info, _ := amc.GetFabricInformation(context.Background())
+		equals(t, wmc.LastCardSent, infoCard(info))
+		equals(t, wmc.LastMsgSent != "", true)
+	})
+	t.Run("No card on errors", func(t *testing.T) {
+		wmc.LastCardSent = nil
+		amc.GetFabricInformationF = func(ctx context.Context) (apic.FabricInformation, error) {
+			return apic.FabricInformation{}, errors.New("Generic APIC Error")
+		}
+		jp, _ := json.Marshal(reqB)
+		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		b.workers.wait()
+		equals(t, response.Code, http.StatusOK)
+		equals(t, wmc.LastCardSent == nil, true)
+	})
+}
@@ -0,0 +1,61 @@
+package bot
+
+import (
+	"strings"
+	"sync"
+)
+
+// Settings of the rooms, changed from the chat
+type roomSettings struct {
+	mu             sync.RWMutex
+	withoutMention map[string]bool // Group rooms where every message is answered, by room key
+}
+
+func newRoomSettings() *roomSettings {
+	return &roomSettings{withoutMention: make(map[string]bool)}
+}
+
+// Whether the bot answers the messages of a group room which do not mention it
+func (rs *roomSettings) answersWithoutMention(room string) bool {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	return rs.withoutMention[room]
+}
+
+func (rs *roomSettings) setAnswersWithoutMention(room string, v bool) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if v {
+		rs.withoutMention[room] = true
+	} else {
+		delete(rs.withoutMention, room)
+	}
+}
+
+// People allowed to run the administration commands (e.g. /cache flush), by person ID
+// The IDs are the ones of the chat platforms: Webex person IDs, Slack user IDs or Teams user IDs
+type admins struct {
+	everyone bool // Everyone is an administrator. Used by the CLI, run by the operator of the bot
+	ids      map[string]bool
+}
+
+func newAdmins(ids ...string) *admins {
+	a := &admins{ids: make(map[string]bool)}
+	for _, id := range ids {
+		if id = strings.TrimSpace(id); id != "" {
+			a.ids[id] = true
+		}
+	}
+	return a
+}
+
+// Allow the people to run the administration commands
+func SetAdmins(ids ...string) Option {
+	return func(b *Bot) {
+		*b.admins = *newAdmins(ids...)
+	}
+}
+
+func (a *admins) isAdmin(personId string) bool {
+	return a.everyone || a.ids[personId]
+}
@@ -0,0 +1,108 @@
+package bot
+
+import (
+	"aci-chatbot/apic"
+	"aci-chatbot/webex"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebHookHanlderGroupRooms(t *testing.T) {
+	wmc := webex.WebexMockClient
+	wmc.SetDefaultFunctions()
+	amc := apic.ApicMockClient
+	amc.SetDefaultFunctions()
+	b, _ := NewBot(&wmc, &amc, "http://test_bot.com")
+	wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
+		return webex.WebexMessage{Id: id, Text: "/cpu", RoomId: "G1", RoomType: "group"}, nil
+	}
+	send := func(data webex.WebexWebhookData) int {
+		jp, _ := json.Marshal(webex.WebexWebhook{Name: "test-bot", Data: &data})
+		request, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBuffer(jp))
+		response := httptest.NewRecorder()
+		b.router.ServeHTTP(response, request)
+		b.workers.wait()
+		return response.Code
+	}
+
+	t.Run("Message without mention", func(t *testing.T) {
+		wmc.LastMsgSent = ""
+		equals(t, send(webex.WebexWebhookData{Id: "W1", RoomId: "G1", RoomType: "group"}), http.StatusOK)
+		equals(t, wmc.LastMsgSent, "")
+	})
+	t.Run("Message mentioning the bot", func(t *testing.T) {
+		equals(t, send(webex.WebexWebhookData{Id: "W2", RoomId: "G1", RoomType: "group", MentionedPeople: []string{"ABC123"}}), http.StatusOK)
+		equals(t, wmc.LastMsgSent != "", true)
+	})
+	t.Run("Message mentioning everyone", func(t *testing.T) {
+		wmc.LastMsgSent = ""
+		equals(t, send(webex.WebexWebhookData{Id: "W3", RoomId: "G1", RoomType: "group", MentionedGroups: []string{"all"}}), http.StatusOK)
+		equals(t, wmc.LastMsgSent != "", true)
+	})
+	t.Run("Direct message", func(t *testing.T) {
+		wmc.LastMsgSent = ""
+		equals(t, send(webex.WebexWebhookData{Id: "W4", RoomId: "D1", RoomType: "direct"}), http.StatusOK)
+		equals(t, wmc.LastMsgSent != "", true)
+	})
+	t.Run("Mentions turned off", func(t *testing.T) {
+		wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
+			return webex.WebexMessage{Id: id, Text: "Test-Bot /mention off", RoomId: "G1", RoomType: "group"}, nil
+		}
+		send(webex.WebexWebhookData{Id: "W5", RoomId: "G1", RoomType: "group", MentionedPeople: []string{"ABC123"}})
+		equals(t, wmc.LastMsgSent, "Hi  🤖 !\n I answer every message of this room, even without a mention 🔕")
+		wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
+			return webex.WebexMessage{Id: id, Text: "/mention", RoomId: "G1", RoomType: "group"}, nil
+		}
+		wmc.LastMsgSent = ""
+		send(webex.WebexWebhookData{Id: "W6", RoomId: "G1", RoomType: "group"})
+		equals(t, wmc.LastMsgSent, "Hi  🤖 !\n I answer every message of this room, even without a mention 🔕")
+	})
+	t.Run("Mentions turned on", func(t *testing.T) {
+		wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
+			return webex.WebexMessage{Id: id, Text: "/mention on", RoomId: "G1", RoomType: "group"}, nil
+		}
+		send(webex.WebexWebhookData{Id: "W7", RoomId: "G1", RoomType: "group"})
+		equals(t, wmc.LastMsgSent, "Hi  🤖 !\n I only answer the messages of this room which mention me 🔔")
+		wmc.LastMsgSent = ""
+		send(webex.WebexWebhookData{Id: "W8", RoomId: "G1", RoomType: "group"})
+		equals(t, wmc.LastMsgSent, "")
+	})
+	t.Run("Room type read from the message", func(t *testing.T) {
+		wmc.GetMessageByIdF = func(id string) (webex.WebexMessage, error) {
+			return webex.WebexMessage{Id: id, Text: "/cpu", RoomId: "G1", RoomType: "group"}, nil
+		}
+		wmc.LastMsgSent = ""
+		equals(t, send(webex.WebexWebhookData{Id: "W9"}), http.StatusOK)
+		equals(t, wmc.LastMsgSent, "")
+	})
+}
+
+func TestMessageCommand(t *testing.T) {
+	b := webex.WebexPeople{Id: "Y2lzY29zcGFyazovL3VzL1BFT1BMRS9hYmMtMTIz", DisplayName: "ACI Bot", Emails: []string{"aci@webex.bot"}}
+
+	t.Run("HTML mention", func(t *testing.T) {
+		m := webex.WebexMessage{
+			Text: "ACI /ep AA:AA:AA:BB:BB:CC",
+			Html: `<p><spark-mention data-object-type="person" data-object-id="abc-123">ACI</spark-mention> /ep AA:AA:AA:BB:BB:CC</p>`,
+		}
+		equals(t, messageCommand(b, m), "/ep AA:AA:AA:BB:BB:CC")
+	})
+	t.Run("HTML mention of someone else", func(t *testing.T) {
+		m := webex.WebexMessage{Html: `<p><spark-mention data-object-type="person" data-object-id="other">Bob</spark-mention> /events Bob</p>`}
+		equals(t, messageCommand(b, m), "Bob /events Bob")
+	})
+	t.Run("Markdown mention", func(t *testing.T) {
+		m := webex.WebexMessage{Markdown: "<@personEmail:aci@webex.bot|ACI> /faults 3"}
+		equals(t, messageCommand(b, m), "/faults 3")
+	})
+	t.Run("Plain text", func(t *testing.T) {
+		equals(t, messageCommand(b, webex.WebexMessage{Text: "ACI Bot /cpu"}), "/cpu")
+	})
+	t.Run("Mentioned by ID", func(t *testing.T) {
+		equals(t, isMentioned(b, []string{"abc-123"}, nil), true)
+		equals(t, isMentioned(b, []string{"other"}, []string{}), false)
+	})
+}
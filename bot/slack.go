@@ -0,0 +1,307 @@
+package bot
+
+import (
+	"aci-chatbot/apic"
+	"aci-chatbot/slack"
+	"aci-chatbot/webex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Slack limits
+const (
+	slackMaxBlocks = 50 // Blocks of a message
+	slackMaxFields = 10 // Fields of a section block
+	slackMaxHeader = 150
+	slackMaxBody   = 1 << 20 // Size of the Events API and interactivity requests
+)
+
+// Connect the bot to a Slack workspace in addition to Webex
+// The signing secret is used to verify the requests sent by Slack to /slack/events and /slack/actions
+func SetSlack(sl slack.SlackInterface, signingSecret string) Option {
+	return func(b *Bot) {
+		b.slack = sl
+		b.slackSecret = signingSecret
+		b.transports["slack"] = slackTransport{sl}
+	}
+}
+
+// Slack transport
+type slackTransport struct {
+	sl slack.SlackInterface
+}
+
+func (t slackTransport) Name() string {
+	return "slack"
+}
+
+func (t slackTransport) SendText(text, roomId, parentId string) error {
+	_, err := t.sl.PostMessage(slack.SlackMessage{Channel: roomId, ThreadTs: parentId, Text: slackMarkdown(text)})
+	return err
+}
+
+// The text is the notification and fallback of the Block Kit message
+func (t slackTransport) SendCard(text string, card webex.AdaptiveCard, roomId, parentId string) error {
+	_, err := t.sl.PostMessage(slack.SlackMessage{Channel: roomId, ThreadTs: parentId, Text: slackMarkdown(text), Blocks: slackBlocks(card)})
+	return err
+}
+
+func (t slackTransport) SendFile(text string, f webex.WebexFile, roomId, parentId string) error {
+	return t.sl.UploadFile(slack.SlackFile{Name: f.Name, Content: f.Content, Channel: roomId, ThreadTs: parentId, Comment: slackMarkdown(text)})
+}
+
+// Send a message which is edited afterwards. Returns its timestamp, which identifies it in the channel
+func (t slackTransport) SendPlaceholder(text, roomId, parentId string) (string, error) {
+	msg, err := t.sl.PostMessage(slack.SlackMessage{Channel: roomId, ThreadTs: parentId, Text: slackMarkdown(text)})
+	if err != nil {
+		return "", err
+	}
+	return msg.Ts, nil
+}
+
+func (t slackTransport) EditText(id, text, roomId string) error {
+	return t.sl.UpdateMessage(slack.SlackMessage{Channel: roomId, Ts: id, Text: slackMarkdown(text)})
+}
+
+func (t slackTransport) RoomName(roomId string) string {
+	ch, _ := t.sl.GetConversationInfo(roomId)
+	return ch.Name
+}
+
+// Replies are written with the Webex markdown and HTML tags. Slack uses its own mrkdwn format
+var slackReplacer = strings.NewReplacer(
+	"**", "*",
+	"<strong>", "*", "</strong>", "*",
+	"<b>", "*", "</b>", "*",
+	"<em>", "_", "</em>", "_",
+	"<i>", "_", "</i>", "_",
+	"<code>", "`", "</code>", "`",
+	"<pre>", "```", "</pre>", "```",
+	"<br>", "\n", "<br/>", "\n",
+	"<li>", "\n• ", "</li>", "",
+	"<ul>", "", "</ul>", "\n",
+)
+
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// Convert a Webex reply to Slack mrkdwn
+func slackMarkdown(s string) string {
+	return slackEscaper.Replace(html.UnescapeString(slackReplacer.Replace(s)))
+}
+
+// Render an Adaptive Card as Block Kit blocks
+// Titles become headers, facts become section fields, containers are separated by dividers and submit buttons carry their command as value
+func slackBlocks(card webex.AdaptiveCard) []slack.SlackBlock {
+	blocks := []slack.SlackBlock{}
+	for _, e := range card.Body {
+		blocks = appendSlackBlocks(blocks, e)
+	}
+	if len(blocks) > slackMaxBlocks {
+		blocks = blocks[:slackMaxBlocks]
+	}
+	return blocks
+}
+
+func appendSlackBlocks(blocks []slack.SlackBlock, e webex.CardElement) []slack.SlackBlock {
+	switch e.Type {
+	case "TextBlock":
+		if e.Text == "" {
+			return blocks
+		}
+		if e.Weight == "Bolder" {
+			t := []rune(e.Text)
+			if len(t) > slackMaxHeader {
+				t = t[:slackMaxHeader]
+			}
+			return append(blocks, slack.SlackBlock{Type: "header", Text: &slack.SlackText{Type: "plain_text", Text: string(t)}})
+		}
+		if e.FontType == "Monospace" {
+			return append(blocks, slack.SlackBlock{Type: "section", Text: &slack.SlackText{Type: "mrkdwn", Text: "```" + slackMarkdown(e.Text) + "```"}})
+		}
+		return append(blocks, slack.SlackBlock{Type: "section", Text: &slack.SlackText{Type: "mrkdwn", Text: slackMarkdown(e.Text)}})
+	case "FactSet":
+		for i := 0; i < len(e.Facts); i += slackMaxFields {
+			end := i + slackMaxFields
+			if end > len(e.Facts) {
+				end = len(e.Facts)
+			}
+			block := slack.SlackBlock{Type: "section"}
+			for _, f := range e.Facts[i:end] {
+				block.Fields = append(block.Fields, slack.SlackText{Type: "mrkdwn", Text: fmt.Sprintf("*%s*\n%s", slackMarkdown(f.Title), slackMarkdown(f.Value))})
+			}
+			blocks = append(blocks, block)
+		}
+	case "ActionSet":
+		block := slack.SlackBlock{Type: "actions"}
+		for _, a := range e.Actions {
+			if a.Data["command"] == "" {
+				continue
+			}
+			block.Elements = append(block.Elements, slack.SlackElement{
+				Type:     "button",
+				Text:     &slack.SlackText{Type: "plain_text", Text: a.Title},
+				Value:    a.Data["command"],
+				ActionId: fmt.Sprintf("command-%d-%d", len(blocks), len(block.Elements)),
+			})
+		}
+		if len(block.Elements) > 0 {
+			blocks = append(blocks, block)
+		}
+	case "Container":
+		if e.Separator {
+			blocks = append(blocks, slack.SlackBlock{Type: "divider"})
+		}
+		for _, i := range e.Items {
+			blocks = appendSlackBlocks(blocks, i)
+		}
+	case "ColumnSet":
+		for _, c := range e.Columns {
+			for _, i := range c.Items {
+				blocks = appendSlackBlocks(blocks, i)
+			}
+		}
+	}
+	return blocks
+}
+
+// Endpoint Handlers
+// /slack/events handler
+// Receives the Events API requests: app mentions in channels and direct messages
+func slackEventsHandler(sl slack.SlackInterface, secret string, me slack.SlackAuth, ap apic.ApicInterface, cmd map[string]Command, wp *workerPool, wc *webhookCache) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("Receiving %s on /slack/events URI", r.Method)
+		body, err := readSlackRequest(secret, r)
+		if err != nil {
+			log.Printf("rejecting Slack request. Error %s", err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		ev := slack.SlackEventCallback{}
+		if err := json.Unmarshal(body, &ev); err != nil {
+			log.Printf("failed to parse incoming Slack event. Error %s", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch ev.Type {
+		case "url_verification":
+			// Slack checks the URL when the Events API is enabled
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(ev.Challenge))
+		case "event_callback":
+			w.WriteHeader(processSlackEvent(sl, me, ap, cmd, wp, wc, ev))
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	})
+}
+
+// /slack/actions handler
+// Block Kit buttons submit the command to execute in their value
+func slackActionsHandler(sl slack.SlackInterface, secret string, ap apic.ApicInterface, cmd map[string]Command, wp *workerPool) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("Receiving %s on /slack/actions URI", r.Method)
+		body, err := readSlackRequest(secret, r)
+		if err != nil {
+			log.Printf("rejecting Slack request. Error %s", err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			log.Printf("failed to parse incoming Slack action. Error %s", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		action := slack.SlackInteraction{}
+		if err := json.Unmarshal([]byte(form.Get("payload")), &action); err != nil {
+			log.Printf("failed to parse incoming Slack action. Error %s", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if action.Type != "block_actions" || len(action.Actions) == 0 || action.Actions[0].Value == "" {
+			log.Printf("Slack action %s does not carry any command", action.Type)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// The command is executed on behalf of the user who clicked. Reply in the thread of the message
+		user, _ := sl.GetUserInfo(action.User.Id)
+		parent := action.Message.ThreadTs
+		if parent == "" {
+			parent = action.Message.Ts
+		}
+		wm := ChatMessage{sender: slackUserName(user), personId: action.User.Id, roomId: action.Channel.Id, parentId: parent, transport: slackTransport{sl}}
+		dispatchCommand(ap, cmd, wp, action.Actions[0].Value, wm)
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// Read the body of a request and verify it has been signed by Slack
+func readSlackRequest(secret string, r *http.Request) ([]byte, error) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, slackMaxBody))
+	if err != nil {
+		return nil, err
+	}
+	if err := slack.VerifySignature(secret, r.Header, body, time.Now()); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// Process a message event. Returns the HTTP status code used to answer Slack
+func processSlackEvent(sl slack.SlackInterface, me slack.SlackAuth, ap apic.ApicInterface, cmd map[string]Command, wp *workerPool, wc *webhookCache, ev slack.SlackEventCallback) int {
+	// Slack retries the events which are not acknowledged in time. Each one is processed once
+	if ev.EventId != "" && !wc.firstSeen(ev.EventId) {
+		log.Printf("Slack event %s already processed. Ignoring it", ev.EventId)
+		return http.StatusOK
+	}
+	e := ev.Event
+	// Mentions in channels arrive as app_mention. Only direct messages are processed as message events
+	if e.Type != "app_mention" && !(e.Type == "message" && e.ChannelType == "im") {
+		return http.StatusOK
+	}
+	// Edits, joins and the messages of bots, including its own, are not commands
+	if e.BotId != "" || e.Subtype != "" || e.User == me.UserId {
+		return http.StatusAccepted
+	}
+	user, _ := sl.GetUserInfo(e.User)
+	parent := e.ThreadTs
+	if parent == "" {
+		parent = e.Ts
+	}
+	wm := ChatMessage{sender: slackUserName(user), personId: e.User, roomId: e.Channel, parentId: parent, transport: slackTransport{sl}}
+	dispatchCommand(ap, cmd, wp, cleanSlackCommand(me.UserId, e.Text), wm)
+	return http.StatusOK
+}
+
+var slackMention = regexp.MustCompile(`<@[A-Z0-9]+(\|[^>]*)?>`)
+var slackUnescaper = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">")
+
+// Remove the mention of the bot from the message text
+func cleanSlackCommand(botId, text string) string {
+	text = slackMention.ReplaceAllStringFunc(text, func(m string) string {
+		if strings.HasPrefix(m, "<@"+botId) {
+			return ""
+		}
+		return m
+	})
+	return strings.TrimSpace(slackUnescaper.Replace(text))
+}
+
+// Name used to greet a Slack user
+func slackUserName(u slack.SlackUser) string {
+	if u.Profile.DisplayName != "" {
+		return u.Profile.DisplayName
+	}
+	if u.RealName != "" {
+		return u.RealName
+	}
+	return u.Name
+}
@@ -0,0 +1,168 @@
+package bot
+
+import (
+	"aci-chatbot/apic"
+	"aci-chatbot/slack"
+	"aci-chatbot/webex"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSigningSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+// Build a request signed like Slack does
+func signedSlackRequest(path, contentType, body string) *http.Request {
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(testSigningSecret))
+	mac.Write([]byte("v0:" + ts + ":" + body))
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Slack-Request-Timestamp", ts)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func TestSlackEventsHandler(t *testing.T) {
+	wmc := webex.WebexMockClient
+	wmc.SetDefaultFunctions()
+	smc := slack.SlackMockClient
+	smc.SetDefaultFunctions()
+	amc := apic.ApicMockClient
+	amc.SetDefaultFunctions()
+	b, err := NewBot(&wmc, &amc, "http://test_bot.com", SetSlack(&smc, testSigningSecret))
+	equals(t, err, nil)
+
+	t.Run("URL verification", func(t *testing.T) {
+		req := signedSlackRequest("/slack/events", "application/json", `{"type": "url_verification", "challenge": "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P"}`)
+		rr := httptest.NewRecorder()
+		b.router.ServeHTTP(rr, req)
+		equals(t, rr.Code, http.StatusOK)
+		equals(t, rr.Body.String(), "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P")
+	})
+	t.Run("Invalid signature", func(t *testing.T) {
+		req := signedSlackRequest("/slack/events", "application/json", `{"type": "url_verification", "challenge": "abc"}`)
+		req.Header.Set("X-Slack-Signature", "v0=0123456789abcdef")
+		rr := httptest.NewRecorder()
+		b.router.ServeHTTP(rr, req)
+		equals(t, rr.Code, http.StatusUnauthorized)
+	})
+	t.Run("Mention in a channel", func(t *testing.T) {
+		req := signedSlackRequest("/slack/events", "application/json", `{"type": "event_callback", "event_id": "Ev1", "event": {"type": "app_mention", "user": "U123", "text": "<@UBOT123> /faults", "channel": "C123", "ts": "1600000000.000100"}}`)
+		rr := httptest.NewRecorder()
+		b.router.ServeHTTP(rr, req)
+		b.workers.wait()
+		equals(t, rr.Code, http.StatusOK)
+		equals(t, smc.LastThreadTs, "1600000000.000100")
+		equals(t, smc.LastBlocksSent[0].Type, "header")
+		equals(t, strings.Contains(smc.LastMsgSent, "*F1451*"), true)
+	})
+	t.Run("Redelivered event", func(t *testing.T) {
+		smc.LastMsgSent = ""
+		req := signedSlackRequest("/slack/events", "application/json", `{"type": "event_callback", "event_id": "Ev1", "event": {"type": "app_mention", "user": "U123", "text": "<@UBOT123> /faults", "channel": "C123", "ts": "1600000000.000100"}}`)
+		rr := httptest.NewRecorder()
+		b.router.ServeHTTP(rr, req)
+		b.workers.wait()
+		equals(t, rr.Code, http.StatusOK)
+		equals(t, smc.LastMsgSent, "")
+	})
+	t.Run("Direct message in a thread", func(t *testing.T) {
+		req := signedSlackRequest("/slack/events", "application/json", `{"type": "event_callback", "event_id": "Ev2", "event": {"type": "message", "channel_type": "im", "user": "U123", "text": "/help", "channel": "D123", "ts": "1600000000.000300", "thread_ts": "1600000000.000200"}}`)
+		rr := httptest.NewRecorder()
+		b.router.ServeHTTP(rr, req)
+		b.workers.wait()
+		equals(t, rr.Code, http.StatusOK)
+		equals(t, smc.LastThreadTs, "1600000000.000200")
+		equals(t, strings.HasPrefix(smc.LastMsgSent, "Hello ARandomPerson"), true)
+		equals(t, strings.Contains(smc.LastMsgSent, "<code>"), false)
+	})
+	t.Run("Own message", func(t *testing.T) {
+		req := signedSlackRequest("/slack/events", "application/json", `{"type": "event_callback", "event_id": "Ev3", "event": {"type": "message", "channel_type": "im", "user": "UBOT123", "bot_id": "BBOT123", "text": "Hi", "channel": "D123", "ts": "1600000000.000400"}}`)
+		rr := httptest.NewRecorder()
+		b.router.ServeHTTP(rr, req)
+		equals(t, rr.Code, http.StatusAccepted)
+	})
+}
+
+func TestSlackActionsHandler(t *testing.T) {
+	wmc := webex.WebexMockClient
+	wmc.SetDefaultFunctions()
+	smc := slack.SlackMockClient
+	smc.SetDefaultFunctions()
+	amc := apic.ApicMockClient
+	amc.SetDefaultFunctions()
+	b, _ := NewBot(&wmc, &amc, "http://test_bot.com", SetSlack(&smc, testSigningSecret), SetAdmins("U123"))
+
+	t.Run("Button click", func(t *testing.T) {
+		posted := ""
+		amc.PostMoF = func(ctx context.Context, dn string, payload []byte) error {
+			posted = dn
+			return nil
+		}
+		for _, command := range []string{"/faults ack topology/pod-1/node-202/sys/ch/psuslot-1/psu/fault-F1451", "/confirm 1"} {
+			payload := `{"type": "block_actions", "user": {"id": "U123"}, "channel": {"id": "C123"}, "message": {"ts": "1600000000.000500"}, "actions": [{"action_id": "command-1-0", "value": "` + command + `"}]}`
+			req := signedSlackRequest("/slack/actions", "application/x-www-form-urlencoded", url.Values{"payload": {payload}}.Encode())
+			rr := httptest.NewRecorder()
+			b.router.ServeHTTP(rr, req)
+			b.workers.wait()
+			equals(t, rr.Code, http.StatusOK)
+		}
+		equals(t, posted, "topology/pod-1/node-202/sys/ch/psuslot-1/psu/fault-F1451")
+		equals(t, smc.LastThreadTs, "1600000000.000500")
+	})
+	t.Run("Action without command", func(t *testing.T) {
+		payload := `{"type": "block_actions", "user": {"id": "U123"}, "channel": {"id": "C123"}, "actions": []}`
+		req := signedSlackRequest("/slack/actions", "application/x-www-form-urlencoded", url.Values{"payload": {payload}}.Encode())
+		rr := httptest.NewRecorder()
+		b.router.ServeHTTP(rr, req)
+		equals(t, rr.Code, http.StatusBadRequest)
+	})
+}
+
+func TestSlackBotCreation(t *testing.T) {
+	wmc := webex.WebexMockClient
+	wmc.SetDefaultFunctions()
+	smc := slack.SlackMockClient
+	smc.SetDefaultFunctions()
+	smc.AuthTestF = func() (slack.SlackAuth, error) {
+		return slack.SlackAuth{}, errors.New("invalid_auth")
+	}
+	_, err := NewBot(&wmc, nil, "http://test_bot.com", SetSlack(&smc, testSigningSecret))
+	equals(t, err != nil, true)
+}
+
+func TestSlackRendering(t *testing.T) {
+	t.Run("Markdown", func(t *testing.T) {
+		equals(t, slackMarkdown("Hi **Bob** 🤖\n <ul><li>Use <code>/cpu</code> & co</li></ul>"), "Hi *Bob* 🤖\n \n• Use `/cpu` &amp; co\n")
+	})
+	t.Run("Mention", func(t *testing.T) {
+		equals(t, cleanSlackCommand("UBOT123", "<@UBOT123> /ep AA:AA:AA:BB:BB:CC history"), "/ep AA:AA:AA:BB:BB:CC history")
+		equals(t, cleanSlackCommand("UBOT123", "/events <@U999|bob> &gt; 3"), "/events <@U999|bob> > 3")
+	})
+	t.Run("Blocks", func(t *testing.T) {
+		blocks := slackBlocks(*faultsCard([]apic.FaultInst{{Code: "F1451", Dn: "topology/pod-1/node-202/fault-F1451", Descr: "Power supply shutdown.", Severity: "minor"}}))
+		types := []string{}
+		for _, b := range blocks {
+			types = append(types, b.Type)
+		}
+		equals(t, types, []string{"header", "divider", "section", "section", "section", "section", "actions"})
+		equals(t, blocks[2].Text.Text, "*F1451* minor ⚠️")
+		equals(t, blocks[6].Elements[0].Value, "/faults ack topology/pod-1/node-202/fault-F1451")
+	})
+	t.Run("Facts split in sections of 10 fields", func(t *testing.T) {
+		facts := make([]webex.CardFact, 12)
+		blocks := slackBlocks(webex.NewAdaptiveCard(webex.CardFacts(facts...)))
+		equals(t, len(blocks), 2)
+		equals(t, len(blocks[0].Fields), 10)
+		equals(t, len(blocks[1].Fields), 2)
+	})
+}
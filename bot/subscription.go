@@ -1,9 +1,13 @@
 package bot
 
+import "sync"
+
 // struct to represent a DB storing the active subscription
 // This could be improved by storgin the information in a external DB
-//  This would make the application stateless
+//
+//	This would make the application stateless
 type webSocketDb struct {
+	mu  sync.RWMutex // Commands are executed concurrently
 	wss map[string]SocketSubscription
 }
 
@@ -22,6 +26,8 @@ func NewWsDb() *webSocketDb {
 
 // Get the Class/MO Name based on the SubscriptionID
 func (wsDb *webSocketDb) getClassNamebySubId(subId string) string {
+	wsDb.mu.RLock()
+	defer wsDb.mu.RUnlock()
 	className := ""
 	for class, sub := range wsDb.wss {
 		if sub.SubscriptionID == subId {
@@ -33,11 +39,16 @@ func (wsDb *webSocketDb) getClassNamebySubId(subId string) string {
 
 // Get the Subscribed RoomsID by Class/MO name
 func (wsDb *webSocketDb) getRoomsIdbyClass(class string) []string {
-	return wsDb.wss[class].RoomsId
+	wsDb.mu.RLock()
+	defer wsDb.mu.RUnlock()
+	// Copy made under the lock, as the rooms are updated by the other subscriptions
+	return append([]string(nil), wsDb.wss[class].RoomsId...)
 }
 
 // Get the list of Claas/MO : SubscriptionId
 func (wsDb *webSocketDb) getActiveSubscriptions() map[string]string {
+	wsDb.mu.RLock()
+	defer wsDb.mu.RUnlock()
 	subs := make(map[string]string)
 	for class, sub := range wsDb.wss {
 		subs[class] = sub.SubscriptionID
@@ -47,6 +58,8 @@ func (wsDb *webSocketDb) getActiveSubscriptions() map[string]string {
 
 // Add a new subscription to a room
 func (wsDb *webSocketDb) addSubcription(class string, subId string, roomId string) {
+	wsDb.mu.Lock()
+	defer wsDb.mu.Unlock()
 	if entry, ok := wsDb.wss[class]; !ok {
 		wsDb.wss[class] = SocketSubscription{SubscriptionID: subId, RoomsId: []string{roomId}}
 	} else {
@@ -57,7 +70,8 @@ func (wsDb *webSocketDb) addSubcription(class string, subId string, roomId strin
 
 // Remove a new subscription from a room
 func (wsDb *webSocketDb) removeSubcription(class string, roomId string) {
-
+	wsDb.mu.Lock()
+	defer wsDb.mu.Unlock()
 	if entry, ok := wsDb.wss[class]; ok {
 		for idx, room := range entry.RoomsId {
 			if room == roomId {
@@ -75,6 +89,8 @@ func (wsDb *webSocketDb) removeSubcription(class string, roomId string) {
 
 // Get the classes subscribed in a Room
 func (wsDb *webSocketDb) getClassesbyRoomId(roomId string) []string {
+	wsDb.mu.RLock()
+	defer wsDb.mu.RUnlock()
 	classes := []string{}
 	for class, sub := range wsDb.wss {
 		for _, room := range sub.RoomsId {
@@ -88,6 +104,8 @@ func (wsDb *webSocketDb) getClassesbyRoomId(roomId string) []string {
 
 // Get the classes subscribed in a Room
 func (wsDb *webSocketDb) checkSubsciption(class string, roomId string) bool {
+	wsDb.mu.RLock()
+	defer wsDb.mu.RUnlock()
 	for _, room := range wsDb.wss[class].RoomsId {
 		if room == roomId {
 			return true
@@ -86,6 +86,9 @@ func TestGetFunctions(t *testing.T) {
 		rooms = db.getRoomsIdbyClass("fvTenant")
 		equals(t, len(rooms), 1)
 		equals(t, rooms[0], "ABC123")
+		// The rooms returned are a copy
+		rooms[0] = "changed"
+		equals(t, db.getRoomsIdbyClass("fvTenant")[0], "ABC123")
 	})
 	t.Run("getClassNamebySubId", func(t *testing.T) {
 		class := db.getClassNamebySubId("44")
@@ -0,0 +1,201 @@
+package bot
+
+import (
+	"aci-chatbot/apic"
+	"aci-chatbot/teams"
+	"aci-chatbot/webex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Teams limits
+const (
+	teamsMaxBody       = 1 << 20 // Size of an incoming activity
+	teamsMaxInlineFile = 20000   // Exported files are sent inline. Larger ones are truncated
+)
+
+// Connect the bot to Microsoft Teams in addition to Webex
+// The Bot Framework sends the activities to /api/messages
+func SetTeams(tm teams.TeamsInterface) Option {
+	return func(b *Bot) {
+		b.teams = tm
+		b.teamsRefs = newTeamsConversations()
+		b.transports["teams"] = teamsTransport{tm, b.teamsRefs}
+	}
+}
+
+// Conversations the bot has received activities from
+// The Bot Framework service URL of a conversation is required to send messages to it
+type teamsConversations struct {
+	mu   sync.RWMutex
+	refs map[string]teams.TeamsConversation
+	urls map[string]string
+}
+
+func newTeamsConversations() *teamsConversations {
+	return &teamsConversations{refs: make(map[string]teams.TeamsConversation), urls: make(map[string]string)}
+}
+
+// Save the reference of the conversation of an activity
+func (tc *teamsConversations) remember(a teams.TeamsActivity) {
+	if a.Conversation == nil || a.ServiceUrl == "" {
+		return
+	}
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.refs[a.Conversation.Id] = *a.Conversation
+	tc.urls[a.Conversation.Id] = a.ServiceUrl
+}
+
+// Get the service URL and the details of a conversation
+func (tc *teamsConversations) get(id string) (string, teams.TeamsConversation, bool) {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
+	u, ok := tc.urls[id]
+	return u, tc.refs[id], ok
+}
+
+// Teams transport
+type teamsTransport struct {
+	tm   teams.TeamsInterface
+	refs *teamsConversations
+}
+
+func (t teamsTransport) Name() string {
+	return "teams"
+}
+
+func (t teamsTransport) SendText(text, roomId, parentId string) error {
+	_, err := t.send(roomId, teams.TeamsActivity{Type: "message", TextFormat: "xml", Text: teamsMarkup(text), ReplyToId: parentId})
+	return err
+}
+
+// Webex and Teams cards are both Adaptive Cards. Submit buttons send their data back as the value of a message activity
+func (t teamsTransport) SendCard(text string, card webex.AdaptiveCard, roomId, parentId string) error {
+	a := teams.TeamsActivity{
+		Type:        "message",
+		ReplyToId:   parentId,
+		Attachments: []teams.TeamsAttachment{{ContentType: webex.AdaptiveCardContentType, Content: card}},
+	}
+	_, err := t.send(roomId, a)
+	return err
+}
+
+// The Bot Framework does not upload files to channels. The content is sent inline instead
+func (t teamsTransport) SendFile(text string, f webex.WebexFile, roomId, parentId string) error {
+	content := string(f.Content)
+	if len(content) > teamsMaxInlineFile {
+		content = content[:teamsMaxInlineFile] + "\n…"
+	}
+	msg := fmt.Sprintf("%s<br><b>%s</b><pre>%s</pre>", teamsMarkup(text), html.EscapeString(f.Name), html.EscapeString(content))
+	_, err := t.send(roomId, teams.TeamsActivity{Type: "message", TextFormat: "xml", Text: msg, ReplyToId: parentId})
+	return err
+}
+
+// Send a message which is edited afterwards. Returns the ID of the activity
+func (t teamsTransport) SendPlaceholder(text, roomId, parentId string) (string, error) {
+	return t.send(roomId, teams.TeamsActivity{Type: "message", TextFormat: "xml", Text: teamsMarkup(text), ReplyToId: parentId})
+}
+
+func (t teamsTransport) EditText(id, text, roomId string) error {
+	serviceUrl, _, ok := t.refs.get(roomId)
+	if !ok {
+		return errors.New("unknown Teams conversation " + roomId)
+	}
+	return t.tm.UpdateActivity(serviceUrl, roomId, id, teams.TeamsActivity{Type: "message", Id: id, TextFormat: "xml", Text: teamsMarkup(text)})
+}
+
+func (t teamsTransport) RoomName(roomId string) string {
+	_, conv, _ := t.refs.get(roomId)
+	return conv.Name
+}
+
+func (t teamsTransport) send(roomId string, a teams.TeamsActivity) (string, error) {
+	serviceUrl, _, ok := t.refs.get(roomId)
+	if !ok {
+		return "", errors.New("unknown Teams conversation " + roomId)
+	}
+	return t.tm.SendActivity(serviceUrl, roomId, a)
+}
+
+var teamsBold = regexp.MustCompile(`\*\*(.+?)\*\*`)
+
+// Replies are written with the Webex markdown and HTML tags. Teams renders the HTML tags of xml messages
+func teamsMarkup(s string) string {
+	return strings.ReplaceAll(teamsBold.ReplaceAllString(s, "<b>$1</b>"), "\n", "<br>")
+}
+
+// Endpoint Handlers
+// /api/messages handler
+// Receives the Bot Framework activities: messages and card submissions
+func teamsHandler(tm teams.TeamsInterface, refs *teamsConversations, ap apic.ApicInterface, cmd map[string]Command, wp *workerPool, wc *webhookCache) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("Receiving %s on /api/messages URI", r.Method)
+		body, err := ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, teamsMaxBody))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		a := teams.TeamsActivity{}
+		if err := json.Unmarshal(body, &a); err != nil {
+			log.Printf("failed to parse incoming Teams activity. Error %s", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if err := tm.ValidateToken(r.Header.Get("Authorization"), a); err != nil {
+			log.Printf("rejecting Teams activity. Error %s", err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(processTeamsActivity(tm, refs, ap, cmd, wp, wc, a))
+	})
+}
+
+// Process an activity. Returns the HTTP status code used to answer the Bot Framework
+func processTeamsActivity(tm teams.TeamsInterface, refs *teamsConversations, ap apic.ApicInterface, cmd map[string]Command, wp *workerPool, wc *webhookCache, a teams.TeamsActivity) int {
+	// The Bot Framework retries the activities which are not acknowledged in time. Each one is processed once
+	if a.Id != "" && !wc.firstSeen(a.Id) {
+		log.Printf("Teams activity %s already processed. Ignoring it", a.Id)
+		return http.StatusOK
+	}
+	refs.remember(a)
+	// Conversation updates, reactions and typing indicators are not commands
+	if a.Type != "message" || a.From == nil || a.Conversation == nil {
+		return http.StatusOK
+	}
+	if a.Recipient != nil && a.From.Id == a.Recipient.Id {
+		return http.StatusAccepted
+	}
+	// Card submissions carry the command in their value. Replies go to the thread of the card
+	text := cleanTeamsCommand(a)
+	parent := a.Id
+	if command, ok := a.Value["command"].(string); ok {
+		text = command
+		if a.ReplyToId != "" {
+			parent = a.ReplyToId
+		}
+	}
+	wm := ChatMessage{sender: a.From.Name, personId: a.From.Id, roomId: a.Conversation.Id, parentId: parent, transport: teamsTransport{tm, refs}}
+	dispatchCommand(ap, cmd, wp, text, wm)
+	return http.StatusOK
+}
+
+// Remove the mention of the bot and the HTML of the message text
+func cleanTeamsCommand(a teams.TeamsActivity) string {
+	text := a.Text
+	for _, e := range a.Entities {
+		if e.Type == "mention" && e.Mentioned != nil && a.Recipient != nil && e.Mentioned.Id == a.Recipient.Id && e.Text != "" {
+			text = strings.ReplaceAll(text, e.Text, "")
+		}
+	}
+	text = html.UnescapeString(htmlTags.ReplaceAllString(text, ""))
+	return strings.TrimSpace(strings.ReplaceAll(text, " ", " "))
+}
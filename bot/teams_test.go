@@ -0,0 +1,96 @@
+package bot
+
+import (
+	"aci-chatbot/apic"
+	"aci-chatbot/teams"
+	"aci-chatbot/webex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Build an activity request sent by the Bot Framework
+func teamsRequest(body, token string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/messages", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+func TestTeamsHandler(t *testing.T) {
+	wmc := webex.WebexMockClient
+	wmc.SetDefaultFunctions()
+	tmc := teams.TeamsMockClient
+	tmc.SetDefaultFunctions()
+	amc := apic.ApicMockClient
+	amc.SetDefaultFunctions()
+	b, err := NewBot(&wmc, &amc, "http://test_bot.com", SetTeams(&tmc))
+	equals(t, err, nil)
+
+	t.Run("Invalid token", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		b.router.ServeHTTP(rr, teamsRequest(`{"type": "message", "id": "A0", "text": "/cpu"}`, "Forged"))
+		equals(t, rr.Code, http.StatusUnauthorized)
+	})
+	t.Run("Mention in a channel", func(t *testing.T) {
+		body := `{"type": "message", "id": "A1", "serviceUrl": "https://smba.trafficmanager.net/emea/", "channelId": "msteams",
+			"from": {"id": "29:user", "name": "ARandomPerson"}, "recipient": {"id": "28:bot", "name": "ACI Bot"},
+			"conversation": {"id": "19:abc@thread.tacv2;messageid=A1", "name": "NOC", "isGroup": true},
+			"text": "<at>ACI Bot</at> /ep AA:AA:AA:BB:BB:CC",
+			"entities": [{"type": "mention", "text": "<at>ACI Bot</at>", "mentioned": {"id": "28:bot", "name": "ACI Bot"}}]}`
+		rr := httptest.NewRecorder()
+		b.router.ServeHTTP(rr, teamsRequest(body, "MockToken"))
+		b.workers.wait()
+		equals(t, rr.Code, http.StatusOK)
+		equals(t, tmc.LastServiceUrl, "https://smba.trafficmanager.net/emea/")
+		equals(t, tmc.LastActivitySent.ReplyToId, "A1")
+		equals(t, tmc.LastActivitySent.Attachments[0].ContentType, webex.AdaptiveCardContentType)
+	})
+	t.Run("Redelivered activity", func(t *testing.T) {
+		tmc.LastActivitySent = teams.TeamsActivity{}
+		body := `{"type": "message", "id": "A1", "serviceUrl": "https://smba.trafficmanager.net/emea/", "from": {"id": "29:user"}, "conversation": {"id": "19:abc"}, "text": "/cpu"}`
+		rr := httptest.NewRecorder()
+		b.router.ServeHTTP(rr, teamsRequest(body, "MockToken"))
+		b.workers.wait()
+		equals(t, rr.Code, http.StatusOK)
+		equals(t, tmc.LastActivitySent.Type, "")
+	})
+	t.Run("Card submission", func(t *testing.T) {
+		body := `{"type": "message", "id": "A2", "replyToId": "A1", "serviceUrl": "https://smba.trafficmanager.net/emea/", "channelId": "msteams",
+			"from": {"id": "29:user", "name": "ARandomPerson"}, "recipient": {"id": "28:bot"},
+			"conversation": {"id": "a:personal"}, "value": {"command": "/help"}}`
+		rr := httptest.NewRecorder()
+		b.router.ServeHTTP(rr, teamsRequest(body, "MockToken"))
+		b.workers.wait()
+		equals(t, rr.Code, http.StatusOK)
+		equals(t, tmc.LastActivitySent.ReplyToId, "A1")
+		equals(t, tmc.LastActivitySent.TextFormat, "xml")
+		equals(t, strings.HasPrefix(tmc.LastActivitySent.Text, "Hello ARandomPerson, How can I help you?<br>"), true)
+	})
+	t.Run("Conversation update", func(t *testing.T) {
+		body := `{"type": "conversationUpdate", "id": "A3", "serviceUrl": "https://smba.trafficmanager.net/emea/", "conversation": {"id": "19:new"}}`
+		rr := httptest.NewRecorder()
+		b.router.ServeHTTP(rr, teamsRequest(body, "MockToken"))
+		equals(t, rr.Code, http.StatusOK)
+		// The conversation is remembered to send notifications to it
+		equals(t, b.transports["teams"].SendText("Hi", "19:new", ""), nil)
+	})
+	t.Run("Unknown conversation", func(t *testing.T) {
+		equals(t, b.transports["teams"].SendText("Hi", "19:unknown", "") != nil, true)
+	})
+}
+
+func TestTeamsRendering(t *testing.T) {
+	t.Run("Markup", func(t *testing.T) {
+		equals(t, teamsMarkup("Hi **Bob** 🤖\n <code>/cpu</code>"), "Hi <b>Bob</b> 🤖<br> <code>/cpu</code>")
+	})
+	t.Run("Mention", func(t *testing.T) {
+		a := teams.TeamsActivity{
+			Text:      "<at>ACI Bot</at>&nbsp;/events admin&nbsp;3",
+			Recipient: &teams.TeamsAccount{Id: "28:bot"},
+			Entities:  []teams.TeamsEntity{{Type: "mention", Text: "<at>ACI Bot</at>", Mentioned: &teams.TeamsAccount{Id: "28:bot"}}},
+		}
+		equals(t, cleanTeamsCommand(a), "/events admin 3")
+	})
+}
@@ -0,0 +1,56 @@
+package bot
+
+import (
+	"aci-chatbot/webex"
+)
+
+// Chat platform the bot is connected to. The commands are independent of the platform
+// Cards and files are described with the Webex types. Each transport renders them in its own format
+type Transport interface {
+	Name() string
+	SendText(text, roomId, parentId string) error
+	SendCard(text string, card webex.AdaptiveCard, roomId, parentId string) error
+	SendFile(text string, f webex.WebexFile, roomId, parentId string) error
+	SendPlaceholder(text, roomId, parentId string) (string, error)
+	EditText(id, text, roomId string) error
+	RoomName(roomId string) string
+}
+
+// Webex transport
+type webexTransport struct {
+	wbx webex.WebexInterface
+}
+
+func (t webexTransport) Name() string {
+	return "webex"
+}
+
+func (t webexTransport) SendText(text, roomId, parentId string) error {
+	return t.wbx.SendMessageToRoom(text, roomId, parentId)
+}
+
+func (t webexTransport) SendCard(text string, card webex.AdaptiveCard, roomId, parentId string) error {
+	return t.wbx.SendCardToRoom(text, card, roomId, parentId)
+}
+
+func (t webexTransport) SendFile(text string, f webex.WebexFile, roomId, parentId string) error {
+	return t.wbx.SendFileToRoom(text, f, roomId, parentId)
+}
+
+// Send a message which is edited afterwards. Returns the ID of the message
+func (t webexTransport) SendPlaceholder(text, roomId, parentId string) (string, error) {
+	msg, err := t.wbx.SendMessage(webex.WebexMessage{RoomId: roomId, ParentId: parentId, Markdown: text})
+	if err != nil {
+		return "", err
+	}
+	return msg.Id, nil
+}
+
+func (t webexTransport) EditText(id, text, roomId string) error {
+	return t.wbx.EditMessage(id, text, roomId)
+}
+
+func (t webexTransport) RoomName(roomId string) string {
+	room, _ := t.wbx.GetRoomById(roomId)
+	return room.Title
+}
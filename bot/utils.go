@@ -1,9 +1,13 @@
 package bot
 
 import (
+	"aci-chatbot/apic"
 	"aci-chatbot/webex"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"html"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -30,6 +34,9 @@ func splitWebsocketCommand(s string) map[string]string {
 
 func splitEpCommand(s string) map[string]string {
 	w := strings.Split(s, " ")
+	if len(w) == 3 {
+		return map[string]string{"mac": w[1], "history": w[2]}
+	}
 	return map[string]string{"mac": w[1]}
 }
 
@@ -42,6 +49,41 @@ func splitNeighCommand(s string) map[string]string {
 	}
 }
 
+func splitHealthCommand(s string) map[string]string {
+	w := strings.Split(s, " ")
+	if len(w) == 1 {
+		return map[string]string{"interval": "1h"}
+	}
+	return map[string]string{"interval": w[1]}
+}
+
+func splitNodeCommand(s string) map[string]string {
+	w := strings.Split(s, " ")
+	return map[string]string{"node": w[1]}
+}
+
+func splitIfaceCommand(s string) map[string]string {
+	w := strings.Split(s, " ")
+	if w[1] == "top" {
+		if len(w) == 3 {
+			return map[string]string{"top": w[2]}
+		}
+		return map[string]string{"top": "10"}
+	}
+	if len(w) == 3 {
+		return map[string]string{"node": w[1], "iface": w[2]}
+	}
+	return map[string]string{"node": w[1], "iface": ""}
+}
+
+func splitFaultAckCommand(s string) map[string]string {
+	w := strings.Split(s, " ")
+	if len(w) == 3 && w[1] == "ack" {
+		return map[string]string{"ack": w[2]}
+	}
+	return map[string]string{}
+}
+
 func splitFaultsAndEnvents(s string) map[string]string {
 	w := strings.Split(s, " ")
 	switch len(w) {
@@ -59,6 +101,43 @@ func splitFaultsAndEnvents(s string) map[string]string {
 	return map[string]string{}
 }
 
+func splitMentionCommand(s string) map[string]string {
+	w := strings.Fields(s)
+	if len(w) == 2 {
+		return map[string]string{"mode": w[1]}
+	}
+	return map[string]string{"mode": ""}
+}
+
+func splitCacheCommand(s string) map[string]string {
+	w := strings.Fields(s)
+	if len(w) == 2 {
+		return map[string]string{"op": w[1]}
+	}
+	return map[string]string{"op": ""}
+}
+
+func splitShutCommand(s string) map[string]string {
+	w := strings.Split(s, " ")
+	return map[string]string{"node": w[1], "iface": w[2]}
+}
+
+func splitStaticPathCommand(s string) map[string]string {
+	w := strings.Split(s, " ")
+	epg := strings.Split(w[2], "/")
+	return map[string]string{"tenant": epg[0], "app": epg[1], "epg": epg[2], "node": w[3], "iface": w[4], "vlan": w[5]}
+}
+
+func splitTenantCommand(s string) map[string]string {
+	w := strings.Split(s, " ")
+	return map[string]string{"name": w[2]}
+}
+
+func splitChangeCommand(s string) map[string]string {
+	w := strings.Split(s, " ")
+	return map[string]string{"id": w[1]}
+}
+
 func parseWebHook(wh *webex.WebexWebhook, r *http.Request) error {
 	body, err := ioutil.ReadAll(r.Body)
 	log.Printf("Parsing Webhook Payload\n")
@@ -74,12 +153,151 @@ func parseWebHook(wh *webex.WebexWebhook, r *http.Request) error {
 	return nil
 }
 
+// Reply sent when a command exceeds its deadline
+func timeoutReply(m Message, wm ChatMessage) Reply {
+	return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n Sorry... <code>%s</code> took too long and was cancelled ⏱️", wm.sender, m.cmd)}
+}
+
+// Commands answered without querying the APIC. They run even while the APIC is not reachable
+var offlineCommands = []string{"/help", "/mention", "/cache", "/cancel"}
+
+// Reply sent instead of running a command while the APIC is not reachable, so that it fails fast
+func unavailableReply(ap apic.ApicInterface, m Message, wm ChatMessage) (Reply, bool) {
+	if w := strings.Fields(m.cmd); len(w) == 0 || contains(offlineCommands, w[0]) {
+		return Reply{}, false
+	}
+	if err := ap.CheckAvailable(); err != nil {
+		return Reply{text: fmt.Sprintf("Hi %s 🤖 !\n Sorry... %s ⛔", wm.sender, err)}, true
+	}
+	return Reply{}, false
+}
+
+// Split a room key into the transport name and the room ID. See ChatMessage.roomKey()
+func splitRoomKey(k string) (string, string) {
+	w := strings.SplitN(k, ":", 2)
+	if len(w) < 2 {
+		return "", k
+	}
+	return w[0], w[1]
+}
+
+// Get the ID of the thread a message belongs to
+// Webex does not support nested threads, replies to a reply are threaded under its parent
+func threadId(m webex.WebexMessage) string {
+	if m.ParentId != "" {
+		return m.ParentId
+	}
+	return m.Id
+}
+
+// Remove the name of the bot from the text of a message
+// The name may be made of several words
 func cleanCommand(name string, text string) string {
+	words := strings.Fields(text)
+	nameWords := strings.Fields(name)
 	var cleaned []string
-	for _, w := range strings.Split(strings.TrimSpace(text), " ") {
-		if w != name && w != "" {
-			cleaned = append(cleaned, w)
+	for i := 0; i < len(words); i++ {
+		if len(nameWords) > 0 && i+len(nameWords) <= len(words) && strings.Join(words[i:i+len(nameWords)], " ") == strings.Join(nameWords, " ") {
+			i += len(nameWords) - 1
+			continue
 		}
+		cleaned = append(cleaned, words[i])
 	}
 	return strings.Join(cleaned, " ")
 }
+
+var sparkMention = regexp.MustCompile(`(?s)<spark-mention[^>]*data-object-id="([^"]*)"[^>]*>.*?</spark-mention>`)
+var markdownMention = regexp.MustCompile(`<@(personId|personEmail):([^|>]+)(\|[^>]*)?>`)
+
+// Get the command of a message, without the mention of the bot
+// The mentions are identified in the HTML or the markdown of the message. Clients may shorten the name of the bot in a mention
+// The name of the bot is removed from the plain text otherwise
+func messageCommand(b webex.WebexPeople, m webex.WebexMessage) string {
+	if m.Html != "" {
+		text := sparkMention.ReplaceAllStringFunc(m.Html, func(s string) string {
+			if sameWebexId(sparkMention.FindStringSubmatch(s)[1], b.Id) {
+				return " "
+			}
+			return s
+		})
+		return strings.Join(strings.Fields(html.UnescapeString(htmlTags.ReplaceAllString(text, " "))), " ")
+	}
+	if markdownMention.MatchString(m.Markdown) {
+		text := markdownMention.ReplaceAllStringFunc(m.Markdown, func(s string) string {
+			id := markdownMention.FindStringSubmatch(s)[2]
+			if sameWebexId(id, b.Id) || contains(b.Emails, id) {
+				return " "
+			}
+			return s
+		})
+		return strings.Join(strings.Fields(text), " ")
+	}
+	return cleanCommand(b.DisplayName, m.Text)
+}
+
+// Whether the bot is mentioned, either directly or with @All
+func isMentioned(b webex.WebexPeople, people []string, groups []string) bool {
+	for _, p := range people {
+		if sameWebexId(p, b.Id) {
+			return true
+		}
+	}
+	return contains(groups, "all")
+}
+
+// Compare two Webex IDs. Webex IDs are base64 encoded URIs ending with a UUID. Either form may be used
+func sameWebexId(a, b string) bool {
+	return a == b || webexUuid(a) == webexUuid(b)
+}
+
+func webexUuid(id string) string {
+	for _, enc := range []*base64.Encoding{base64.RawStdEncoding, base64.StdEncoding, base64.RawURLEncoding} {
+		if d, err := enc.DecodeString(id); err == nil && strings.HasPrefix(string(d), "ciscospark://") {
+			return string(d)[strings.LastIndex(string(d), "/")+1:]
+		}
+	}
+	return id
+}
+
+func contains(l []string, s string) bool {
+	for _, v := range l {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Render a series of values as a Unicode sparkline, scaled between its min and max values
+// Flat series are scaled based on the health score (0-100)
+func sparkline(values []int) string {
+	bars := []rune("▁▂▃▄▅▆▇█")
+	if len(values) == 0 {
+		return ""
+	}
+	min, max := values[0], values[0]
+	for _, v := range values {
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+	}
+	line := ""
+	for _, v := range values {
+		var idx int
+		if max == min {
+			idx = v * (len(bars) - 1) / 100
+		} else {
+			idx = (v - min) * (len(bars) - 1) / (max - min)
+		}
+		line += string(bars[idx])
+	}
+	return line
+}
+
+// Summary of a health series: sparkline, min, max and average
+func healthTrend(hs apic.HealthSeries) string {
+	return fmt.Sprintf("<code>%s</code> min <strong>%d</strong> / max <strong>%d</strong> / avg <strong>%.1f</strong>", sparkline(hs.Samples), hs.Min, hs.Max, hs.Avg)
+}
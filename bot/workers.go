@@ -0,0 +1,44 @@
+package bot
+
+import "sync"
+
+// Bounded pool of workers executing the commands asynchronously
+type workerPool struct {
+	jobs    chan func()
+	pending sync.WaitGroup
+}
+
+// Create a new pool and start its workers
+// The queue stores the jobs submitted while all the workers are busy
+func newWorkerPool(workers, queue int) *workerPool {
+	wp := workerPool{jobs: make(chan func(), queue)}
+	for i := 0; i < workers; i++ {
+		go wp.work()
+	}
+	return &wp
+}
+
+// Execute the submitted jobs
+func (wp *workerPool) work() {
+	for job := range wp.jobs {
+		job()
+		wp.pending.Done()
+	}
+}
+
+// Queue a job. Returns false if the queue is full
+func (wp *workerPool) submit(job func()) bool {
+	wp.pending.Add(1)
+	select {
+	case wp.jobs <- job:
+		return true
+	default:
+		wp.pending.Done()
+		return false
+	}
+}
+
+// Wait until all the submitted jobs are completed
+func (wp *workerPool) wait() {
+	wp.pending.Wait()
+}
@@ -0,0 +1,29 @@
+package bot
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestWorkerPool(t *testing.T) {
+	t.Run("Execute all the submitted jobs", func(t *testing.T) {
+		wp := newWorkerPool(4, 16)
+		var count int32
+		for i := 0; i < 16; i++ {
+			equals(t, wp.submit(func() { atomic.AddInt32(&count, 1) }), true)
+		}
+		wp.wait()
+		equals(t, atomic.LoadInt32(&count), int32(16))
+	})
+	t.Run("Reject jobs when the queue is full", func(t *testing.T) {
+		wp := newWorkerPool(1, 1)
+		block := make(chan struct{})
+		started := make(chan struct{})
+		equals(t, wp.submit(func() { close(started); <-block }), true)
+		<-started
+		equals(t, wp.submit(func() {}), true)
+		equals(t, wp.submit(func() {}), false)
+		close(block)
+		wp.wait()
+	})
+}
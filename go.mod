@@ -2,4 +2,4 @@ module aci-chatbot
 
 go 1.15
 
-require github.com/gorilla/websocket v1.4.2 // indirect
+require github.com/gorilla/websocket v1.4.2
@@ -3,18 +3,45 @@ package main
 import (
 	"aci-chatbot/apic"
 	"aci-chatbot/bot"
+	"aci-chatbot/proxy"
+	"aci-chatbot/slack"
+	"aci-chatbot/teams"
 	"aci-chatbot/webex"
+	"context"
+	"crypto/tls"
 	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
+const defaultCacheTTL = 30 * time.Second
+
 type Requirements struct {
-	webexToken string
-	botUrl     string
-	apicUrl    string
-	apicUsr    string
-	apicPsw    string
+	webexToken   string
+	webexMode    string
+	botUrl       string
+	apicUrl      string
+	apicUsr      string
+	apicPsw      string
+	slackToken   string
+	slackSecret  string
+	teamsAppId   string
+	teamsPsw     string
+	auditLog     string
+	approvers    string
+	admins       []string
+	metricsToken string
+	cacheTTL     time.Duration
+	apicRetries  int
+	apicTLS      *tls.Config
+	apicProxy    proxy.Func
+	webexProxy   proxy.Func
 }
 
 func checkRequirements() (*Requirements, error) {
@@ -23,43 +50,231 @@ func checkRequirements() (*Requirements, error) {
 		return nil, errors.New("WEBEX_TOKEN not set")
 	}
 	r.webexToken = os.Getenv("WEBEX_TOKEN")
-	if os.Getenv("BOT_URL") == "" {
+	// The bot receives the messages over webhooks (default) or over the Webex WebSocket
+	r.webexMode = os.Getenv("WEBEX_MODE")
+	if r.webexMode != "" && r.webexMode != "webhook" && r.webexMode != "websocket" {
+		return nil, errors.New("WEBEX_MODE must be webhook or websocket")
+	}
+	// BOT_URL is only required to receive webhooks
+	if os.Getenv("BOT_URL") == "" && r.webexMode != "websocket" {
 		return nil, errors.New("BOT_URL not set")
 	}
 	r.botUrl = os.Getenv("BOT_URL")
+	// Webex is reached through WEBEX_PROXY if set, except the hosts of WEBEX_NO_PROXY
+	// Without it, the standard HTTP_PROXY, HTTPS_PROXY and NO_PROXY variables apply
+	p, err := proxy.New(os.Getenv("WEBEX_PROXY"), os.Getenv("WEBEX_NO_PROXY"))
+	if err != nil {
+		return nil, fmt.Errorf("WEBEX_PROXY: %s", err)
+	}
+	r.webexProxy = p
+	if err := checkApicRequirements(&r); err != nil {
+		return nil, err
+	}
+	// Slack is optional. The signing secret is required to verify the requests sent by Slack
+	r.slackToken = os.Getenv("SLACK_BOT_TOKEN")
+	r.slackSecret = os.Getenv("SLACK_SIGNING_SECRET")
+	if r.slackToken != "" && r.slackSecret == "" {
+		return nil, errors.New("SLACK_SIGNING_SECRET not set")
+	}
+	// Teams is optional. The credentials are the ones of the Azure Bot registration
+	r.teamsAppId = os.Getenv("MICROSOFT_APP_ID")
+	r.teamsPsw = os.Getenv("MICROSOFT_APP_PASSWORD")
+	if r.teamsAppId != "" && r.teamsPsw == "" {
+		return nil, errors.New("MICROSOFT_APP_PASSWORD not set")
+	}
+	// The configuration changes are confirmed by the requester, and optionally by a second administrator of the room
+	r.approvers = os.Getenv("CHANGE_APPROVERS")
+	if r.approvers != "" && r.approvers != "1" && r.approvers != "2" {
+		return nil, errors.New("CHANGE_APPROVERS must be 1 or 2")
+	}
+	r.metricsToken = os.Getenv("METRICS_TOKEN")
+	if r.metricsToken == "" {
+		log.Println("WARNING: METRICS_TOKEN not set. The metrics on /metrics are served to anyone reaching the bot")
+	}
+	// Only the administrators request and confirm the configuration changes and flush the cache
+	if a := os.Getenv("BOT_ADMINS"); a != "" {
+		r.admins = strings.Split(a, ",")
+	} else {
+		log.Println("BOT_ADMINS not set. The configuration changes and /cache flush are disabled in the chat")
+	}
+	// The results of the APIC queries are cached for 30 seconds unless APIC_CACHE_TTL is set. 0 disables the cache
+	r.cacheTTL = defaultCacheTTL
+	if ttl := os.Getenv("APIC_CACHE_TTL"); ttl != "" {
+		s, err := strconv.Atoi(ttl)
+		if err != nil || s < 0 {
+			return nil, errors.New("APIC_CACHE_TTL must be a number of seconds")
+		}
+		r.cacheTTL = time.Duration(s) * time.Second
+	}
+	return &r, nil
+}
+
+// The APIC settings are required by the bot and by the CLI
+func checkApicRequirements(r *Requirements) error {
 	if os.Getenv("APIC_URL") == "" {
-		return nil, errors.New("APIC_URL not set")
+		return errors.New("APIC_URL not set")
 	}
 	r.apicUrl = os.Getenv("APIC_URL")
 	if os.Getenv("APIC_USERNAME") == "" {
-		return nil, errors.New("APIC_USERNAME not set")
+		return errors.New("APIC_USERNAME not set")
 	}
 	r.apicUsr = os.Getenv("APIC_USERNAME")
 	if os.Getenv("APIC_PASSWORD") == "" {
-		return nil, errors.New("APIC_PASSWORD not set")
+		return errors.New("APIC_PASSWORD not set")
 	}
 	r.apicPsw = os.Getenv("APIC_PASSWORD")
-	return &r, nil
+	// The configuration changes are written to the standard logger unless AUDIT_LOG is set
+	r.auditLog = os.Getenv("AUDIT_LOG")
+	// Throttled and failed APIC queries are retried twice unless APIC_RETRIES is set. 0 disables the retries
+	r.apicRetries = -1
+	if n := os.Getenv("APIC_RETRIES"); n != "" {
+		retries, err := strconv.Atoi(n)
+		if err != nil || retries < 0 {
+			return errors.New("APIC_RETRIES must be a number of retries")
+		}
+		r.apicRetries = retries
+	}
+	// The certificate of the APIC is verified against the system CAs, or the CAs of APIC_CA_FILE
+	// APIC_CERT_PINS lists the SHA-256 fingerprints of trusted certificates. APIC_INSECURE=true skips the verification
+	var pins []string
+	if p := os.Getenv("APIC_CERT_PINS"); p != "" {
+		pins = strings.Split(p, ",")
+	}
+	insecure := os.Getenv("APIC_INSECURE")
+	if insecure != "" && insecure != "true" && insecure != "false" {
+		return errors.New("APIC_INSECURE must be true or false")
+	}
+	cfg, err := apic.NewTLSConfig(os.Getenv("APIC_CA_FILE"), pins, insecure == "true")
+	if err != nil {
+		return err
+	}
+	r.apicTLS = cfg
+	// The APIC is reached through APIC_PROXY if set, except the hosts of APIC_NO_PROXY
+	// Without it, the APIC is always reached directly. The standard HTTP_PROXY, HTTPS_PROXY and NO_PROXY variables are ignored
+	p, err := proxy.New(os.Getenv("APIC_PROXY"), os.Getenv("APIC_NO_PROXY"))
+	if err != nil {
+		return fmt.Errorf("APIC_PROXY: %s", err)
+	}
+	r.apicProxy = p
+	return nil
+}
+
+// Options of the APIC client
+func apicOptions(r *Requirements) []apic.Option {
+	options := []apic.Option{apic.SetTimeout(10), apic.SetTLSConfig(r.apicTLS), apic.SetProxy(r.apicProxy)}
+	if r.apicRetries >= 0 {
+		options = append(options, apic.SetRetries(r.apicRetries, 0))
+	}
+	return options
+}
+
+// Open the audit log file. Entries are appended to it
+func openAuditLog(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+}
+
+// Execute the bot commands from the terminal against the APIC. Neither Webex nor a webhook are required
+// The command is read from the arguments. Without arguments, an interactive shell is started
+func runCli(args []string) error {
+	fs := flag.NewFlagSet("cli", flag.ExitOnError)
+	asJson := fs.Bool("json", false, "print the data of the replies as JSON")
+	verbose := fs.Bool("v", false, "print the logs")
+	fs.Parse(args)
+
+	r := Requirements{}
+	if err := checkApicRequirements(&r); err != nil {
+		return err
+	}
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+	}
+	client, err := apic.NewApicClient(r.apicUrl, r.apicUsr, r.apicPsw, apicOptions(&r)...)
+	if err != nil {
+		return fmt.Errorf("APIC connection failed. %s", err)
+	}
+	var options []bot.CliOption
+	if *asJson {
+		options = append(options, bot.SetCliJson())
+	}
+	if r.auditLog != "" {
+		f, err := openAuditLog(r.auditLog)
+		if err != nil {
+			return fmt.Errorf("could not open the audit log. %s", err)
+		}
+		defer f.Close()
+		options = append(options, bot.SetCliAuditLog(f))
+	}
+	c := bot.NewCli(client, os.Stdout, options...)
+	if fs.NArg() > 0 {
+		return c.Execute(context.Background(), strings.Join(fs.Args(), " "))
+	}
+	return c.Repl(context.Background(), os.Stdin)
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "cli" {
+		if err := runCli(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 	// Check requirements
 	r, err := checkRequirements()
 	if err != nil {
 		panic(err)
 	}
 	// Set up Webex Client
-	wbx := webex.NewWebexClient(r.webexToken)
+	wbx := webex.NewWebexClient(r.webexToken, webex.SetProxy(r.webexProxy))
 	//	Set up APIC Client
-	apic, err := apic.NewApicClient(r.apicUrl, r.apicUsr, r.apicPsw, apic.SetTimeout(10))
+	apicClient, err := apic.NewApicClient(r.apicUrl, r.apicUsr, r.apicPsw, apicOptions(r)...)
 	if err != nil {
 		panic("APIC connection failed")
 	}
+	var client apic.ApicInterface = apicClient
+	if r.cacheTTL > 0 {
+		client = apic.NewCachedClient(apicClient, r.cacheTTL)
+	}
 	// Configure and start Bot server
-	b, err := bot.NewBot(&wbx, apic, r.botUrl)
+	var options []bot.Option
+	if r.webexMode == "websocket" {
+		options = append(options, bot.SetWebexDeviceMode())
+	}
+	if r.slackToken != "" {
+		sl := slack.NewSlackClient(r.slackToken)
+		options = append(options, bot.SetSlack(&sl, r.slackSecret))
+	}
+	if r.teamsAppId != "" {
+		options = append(options, bot.SetTeams(teams.NewTeamsClient(r.teamsAppId, r.teamsPsw)))
+	}
+	if r.approvers == "2" {
+		options = append(options, bot.SetSecondApprover())
+	}
+	if len(r.admins) > 0 {
+		options = append(options, bot.SetAdmins(r.admins...))
+	}
+	if r.metricsToken != "" {
+		options = append(options, bot.SetMetricsToken(r.metricsToken))
+	}
+	if r.auditLog != "" {
+		f, err := openAuditLog(r.auditLog)
+		if err != nil {
+			panic("Could not open the audit log")
+		}
+		defer f.Close()
+		options = append(options, bot.SetAuditLog(f))
+	}
+	b, err := bot.NewBot(&wbx, client, r.botUrl, options...)
 	if err != nil {
 		panic("Bot failed to start. Could not contact Webex API")
 	}
+	if r.webexMode == "websocket" {
+		go func() {
+			if err := b.ListenWebexDevice(context.Background()); err != nil {
+				log.Printf("Bot stopped listening to Webex events. Error %s", err)
+			}
+		}()
+	}
 	if err = b.SetupWebSocket(); err != nil {
 		log.Println("Bot failed to start. Error setting up the Websocket client")
 	}
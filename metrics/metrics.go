@@ -0,0 +1,210 @@
+package metrics
+
+// Package metrics exposes counters, gauges and histograms in the Prometheus text format
+// The metrics are registered once, as package variables, and served by Handler()
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Default buckets of the histograms, in seconds. Suited to the latency of API calls
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Metric with the samples of every combination of label values
+type family struct {
+	name    string
+	help    string
+	kind    string // counter, gauge or histogram
+	labels  []string
+	buckets []float64 // Upper bounds of the histogram buckets, without +Inf
+	mu      sync.Mutex
+	series  map[string]*series
+}
+
+// Samples of a combination of label values
+type series struct {
+	values []string
+	value  float64  // Value of a counter or a gauge. Sum of a histogram
+	counts []uint64 // Observations per bucket of a histogram. The last one is +Inf
+	count  uint64
+}
+
+var registry = struct {
+	mu       sync.Mutex
+	families map[string]*family
+}{families: make(map[string]*family)}
+
+func register(f *family) *family {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if _, ok := registry.families[f.name]; ok {
+		panic(fmt.Sprintf("metric %s registered twice", f.name))
+	}
+	f.series = make(map[string]*series)
+	registry.families[f.name] = f
+	return f
+}
+
+// Get the series of the label values. Missing values are empty
+func (f *family) get(values []string) *series {
+	v := make([]string, len(f.labels))
+	copy(v, values)
+	key := strings.Join(v, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{values: v}
+		if f.kind == "histogram" {
+			s.counts = make([]uint64, len(f.buckets)+1)
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// Counter only going up, e.g. the number of requests
+type Counter struct{ f *family }
+
+// Register a counter with its label names
+func NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{register(&family{name: name, help: help, kind: "counter", labels: labels})}
+}
+
+// Increment the counter of the label values
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add a positive value to the counter of the label values
+func (c *Counter) Add(v float64, values ...string) {
+	if v < 0 {
+		return
+	}
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	c.f.get(values).value += v
+}
+
+// Gauge going up and down, e.g. the number of subscriptions
+type Gauge struct{ f *family }
+
+// Register a gauge with its label names
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{register(&family{name: name, help: help, kind: "gauge", labels: labels})}
+}
+
+// Set the gauge of the label values
+func (g *Gauge) Set(v float64, values ...string) {
+	g.f.mu.Lock()
+	defer g.f.mu.Unlock()
+	g.f.get(values).value = v
+}
+
+// Histogram counting the observations, e.g. latencies, in buckets
+type Histogram struct{ f *family }
+
+// Register a histogram with the upper bounds of its buckets, in increasing order, and its label names
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return &Histogram{register(&family{name: name, help: help, kind: "histogram", labels: labels, buckets: buckets})}
+}
+
+// Add an observation to the histogram of the label values
+func (h *Histogram) Observe(v float64, values ...string) {
+	h.f.mu.Lock()
+	defer h.f.mu.Unlock()
+	s := h.f.get(values)
+	i := sort.SearchFloat64s(h.f.buckets, v)
+	s.counts[i]++
+	s.value += v
+	s.count++
+}
+
+// Write all the metrics in the Prometheus text format, sorted by name
+func WriteTo(w io.Writer) error {
+	registry.mu.Lock()
+	families := make([]*family, 0, len(registry.families))
+	for _, f := range registry.families {
+		families = append(families, f)
+	}
+	registry.mu.Unlock()
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	var b strings.Builder
+	for _, f := range families {
+		f.write(&b)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// HTTP handler of the /metrics endpoint
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WriteTo(w)
+	})
+}
+
+func (f *family) write(b *strings.Builder) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fmt.Fprintf(b, "# HELP %s %s\n", f.name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(f.help))
+	fmt.Fprintf(b, "# TYPE %s %s\n", f.name, f.kind)
+	keys := make([]string, 0, len(f.series))
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := f.series[k]
+		if f.kind != "histogram" {
+			fmt.Fprintf(b, "%s%s %s\n", f.name, f.labelPairs(s.values, ""), formatFloat(s.value))
+			continue
+		}
+		var cumulative uint64
+		for i, c := range s.counts {
+			cumulative += c
+			le := math.Inf(1)
+			if i < len(f.buckets) {
+				le = f.buckets[i]
+			}
+			fmt.Fprintf(b, "%s_bucket%s %d\n", f.name, f.labelPairs(s.values, formatFloat(le)), cumulative)
+		}
+		fmt.Fprintf(b, "%s_sum%s %s\n", f.name, f.labelPairs(s.values, ""), formatFloat(s.value))
+		fmt.Fprintf(b, "%s_count%s %d\n", f.name, f.labelPairs(s.values, ""), s.count)
+	}
+}
+
+// Labels of a sample, e.g. {class="fvBD",le="0.5"}. le is the bound of a histogram bucket
+func (f *family) labelPairs(values []string, le string) string {
+	pairs := []string{}
+	for i, l := range f.labels {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, l, escapeLabel(values[i])))
+	}
+	if le != "" {
+		pairs = append(pairs, fmt.Sprintf(`le="%s"`, le))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
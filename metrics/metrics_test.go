@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func equals(tb testing.TB, exp, act interface{}) {
+	if !reflect.DeepEqual(exp, act) {
+		_, file, line, _ := runtime.Caller(1)
+		fmt.Printf("\033[31m%s:%d:\n\n\texp: %#v\n\n\tgot: %#v\033[39m\n\n", filepath.Base(file), line, exp, act)
+		tb.FailNow()
+	}
+}
+
+// Lines of the output of a metric
+func lines(name string) []string {
+	var b bytes.Buffer
+	WriteTo(&b)
+	var l []string
+	for _, line := range strings.Split(b.String(), "\n") {
+		if strings.HasPrefix(line, name) || strings.HasPrefix(line, "# HELP "+name+" ") || strings.HasPrefix(line, "# TYPE "+name+" ") {
+			l = append(l, line)
+		}
+	}
+	return l
+}
+
+func TestMetrics(t *testing.T) {
+	t.Run("Counter", func(t *testing.T) {
+		c := NewCounter("test_commands_total", "Commands executed", "command", "result")
+		c.Inc("/faults", "ok")
+		c.Inc("/faults", "ok")
+		c.Add(0.5, "/ep", "timeout")
+		c.Add(-1, "/ep", "timeout")
+		equals(t, []string{
+			"# HELP test_commands_total Commands executed",
+			"# TYPE test_commands_total counter",
+			`test_commands_total{command="/ep",result="timeout"} 0.5`,
+			`test_commands_total{command="/faults",result="ok"} 2`,
+		}, lines("test_commands_total"))
+	})
+	t.Run("Gauge without labels", func(t *testing.T) {
+		g := NewGauge("test_subscriptions", "Active subscriptions")
+		g.Set(3)
+		g.Set(2)
+		equals(t, []string{
+			"# HELP test_subscriptions Active subscriptions",
+			"# TYPE test_subscriptions gauge",
+			"test_subscriptions 2",
+		}, lines("test_subscriptions"))
+	})
+	t.Run("Histogram", func(t *testing.T) {
+		h := NewHistogram("test_duration_seconds", "Duration", []float64{0.1, 1}, "class")
+		h.Observe(0.05, "fvBD")
+		h.Observe(0.1, "fvBD")
+		h.Observe(0.5, "fvBD")
+		h.Observe(3, "fvBD")
+		equals(t, []string{
+			"# HELP test_duration_seconds Duration",
+			"# TYPE test_duration_seconds histogram",
+			`test_duration_seconds_bucket{class="fvBD",le="0.1"} 2`,
+			`test_duration_seconds_bucket{class="fvBD",le="1"} 3`,
+			`test_duration_seconds_bucket{class="fvBD",le="+Inf"} 4`,
+			`test_duration_seconds_sum{class="fvBD"} 3.65`,
+			`test_duration_seconds_count{class="fvBD"} 4`,
+		}, lines("test_duration_seconds"))
+	})
+	t.Run("Label values escaped", func(t *testing.T) {
+		c := NewCounter("test_escaped_total", "Escaped", "dn")
+		c.Inc("uni/tn-\"a\"\\b\nc")
+		equals(t, `test_escaped_total{dn="uni/tn-\"a\"\\b\nc"} 1`, lines("test_escaped_total")[2])
+	})
+	t.Run("Registered twice", func(t *testing.T) {
+		defer func() { equals(t, true, recover() != nil) }()
+		NewCounter("test_commands_total", "Commands executed")
+	})
+	t.Run("Handler", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		Handler().ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		equals(t, http.StatusOK, response.Code)
+		equals(t, "text/plain; version=0.0.4; charset=utf-8", response.Header().Get("Content-Type"))
+		equals(t, true, strings.Contains(response.Body.String(), "# TYPE test_subscriptions gauge\n"))
+	})
+}
@@ -0,0 +1,263 @@
+package slack
+
+// Package slack uses httptest to execute unit tests
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Maximum age of a signed request. Older requests are rejected to prevent replays
+const signatureMaxAge = 5 * time.Minute
+
+// Slack interface. Implemented by SlackClient and SlackClientMocks
+type SlackInterface interface {
+	AuthTest() (SlackAuth, error)
+	PostMessage(m SlackMessage) (SlackMessage, error)
+	UpdateMessage(m SlackMessage) error
+	UploadFile(f SlackFile) error
+	GetUserInfo(id string) (SlackUser, error)
+	GetConversationInfo(id string) (SlackChannel, error)
+}
+
+// Slack Client struct
+type SlackClient struct {
+	httpClient *http.Client
+	tkn        string
+	baseURL    string
+}
+
+// Create a new Slack Client. The token is a bot token (xoxb-)
+func NewSlackClient(tkn string) SlackClient {
+	return SlackClient{
+		tkn: tkn,
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		baseURL: "https://slack.com/api",
+	}
+}
+
+// Get client base URL
+func (sl *SlackClient) GetBaseUrl() string {
+	return sl.baseURL
+}
+
+// Get the identity of the bot
+func (sl *SlackClient) AuthTest() (SlackAuth, error) {
+	var result SlackAuth
+
+	err := sl.processMessage(http.MethodPost, "/auth.test", nil, &result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// Post a message to a channel and return it with its timestamp. The timestamp is required to edit it afterwards
+// The message is threaded under ThreadTs, if any
+func (sl *SlackClient) PostMessage(m SlackMessage) (SlackMessage, error) {
+	var result SlackMessageReply
+
+	err := sl.processMessage(http.MethodPost, "/chat.postMessage", m, &result)
+	if err != nil {
+		return m, err
+	}
+
+	m.Ts = result.Ts
+	return m, nil
+}
+
+// Replace the text and blocks of an existing message
+func (sl *SlackClient) UpdateMessage(m SlackMessage) error {
+
+	err := sl.processMessage(http.MethodPost, "/chat.update", m, nil)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Upload a file to a channel
+// The file is sent to an upload URL provided by Slack, then shared to the channel
+func (sl *SlackClient) UploadFile(f SlackFile) error {
+	var upload SlackUploadUrlReply
+
+	q := url.Values{"filename": {f.Name}, "length": {strconv.Itoa(len(f.Content))}}
+	err := sl.processMessage(http.MethodGet, "/files.getUploadURLExternal?"+q.Encode(), nil, &upload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, upload.UploadUrl, bytes.NewReader(f.Content))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Content-Type", "application/octet-stream")
+	resp, err := sl.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error uploading file %s\n HTTP status %d", f.Name, resp.StatusCode)
+	}
+
+	complete := map[string]interface{}{
+		"files":           []map[string]string{{"id": upload.FileId, "title": f.Name}},
+		"channel_id":      f.Channel,
+		"initial_comment": f.Comment,
+	}
+	if f.ThreadTs != "" {
+		complete["thread_ts"] = f.ThreadTs
+	}
+	err = sl.processMessage(http.MethodPost, "/files.completeUploadExternal", complete, nil)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Get user information by ID
+func (sl *SlackClient) GetUserInfo(id string) (SlackUser, error) {
+	var result SlackUserReply
+
+	err := sl.processMessage(http.MethodGet, "/users.info?user="+url.QueryEscape(id), nil, &result)
+	if err != nil {
+		return result.User, err
+	}
+
+	return result.User, nil
+}
+
+// Get channel information by ID
+func (sl *SlackClient) GetConversationInfo(id string) (SlackChannel, error) {
+	var result SlackChannelReply
+
+	err := sl.processMessage(http.MethodGet, "/conversations.info?channel="+url.QueryEscape(id), nil, &result)
+	if err != nil {
+		return result.Channel, err
+	}
+
+	return result.Channel, nil
+}
+
+// Create and execute an HTTP request
+func (sl *SlackClient) processMessage(method, url string, payload interface{}, response interface{}) error {
+
+	req, err := sl.makeCall(method, url, payload)
+	if err != nil {
+		return err
+	}
+
+	err = sl.doCall(req, response)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Create a HTTP request
+func (sl *SlackClient) makeCall(m, url string, p interface{}) (*http.Request, error) {
+
+	var body *bytes.Buffer
+	if p != nil {
+		jp, err := json.Marshal(p)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewBuffer(jp)
+	} else {
+		body = &bytes.Buffer{}
+	}
+
+	req, err := http.NewRequest(m, sl.baseURL+url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Accept", "application/json")
+	if p != nil {
+		req.Header.Add("Content-Type", "application/json; charset=utf-8")
+	}
+	req.Header.Add("Authorization", "Bearer "+sl.tkn)
+
+	return req, nil
+}
+
+// Execute a HTTP request
+// Slack replies with HTTP 200 to failed calls, the error is in the body
+func (sl *SlackClient) doCall(req *http.Request, res interface{}) error {
+
+	resp, err := sl.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error processing this request %s\n API message %s", req.URL, body)
+	}
+
+	var status SlackResponse
+	err = json.Unmarshal(body, &status)
+	if err != nil {
+		return err
+	}
+	if !status.Ok {
+		return fmt.Errorf("error processing this request %s\n API error %s", req.URL.Path, status.Error)
+	}
+	if res != nil {
+		err = json.Unmarshal(body, res)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Verify the signature of a request sent by Slack
+// See https://api.slack.com/authentication/verifying-requests-from-slack
+func VerifySignature(secret string, h http.Header, body []byte, now time.Time) error {
+
+	ts := h.Get("X-Slack-Request-Timestamp")
+	sig := h.Get("X-Slack-Signature")
+	if ts == "" || sig == "" {
+		return errors.New("missing Slack signature headers")
+	}
+
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid Slack request timestamp %s", ts)
+	}
+	age := now.Sub(time.Unix(sec, 0))
+	if age > signatureMaxAge || age < -signatureMaxAge {
+		return fmt.Errorf("slack request timestamp %s is too old", ts)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + ts + ":"))
+	mac.Write(body)
+	exp := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(exp), []byte(strings.TrimSpace(sig))) {
+		return errors.New("invalid Slack signature")
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+//go:build !slack
+// +build !slack
+
+package slack
+
+import "log"
+
+type SlackClientMocks struct {
+	LastMsgSent          string
+	LastThreadTs         string
+	LastUpdatedTs        string
+	LastBlocksSent       []SlackBlock
+	LastFileSent         SlackFile
+	AuthTestF            func() (SlackAuth, error)
+	PostMessageF         func(m SlackMessage) (SlackMessage, error)
+	UpdateMessageF       func(m SlackMessage) error
+	UploadFileF          func(f SlackFile) error
+	GetUserInfoF         func(id string) (SlackUser, error)
+	GetConversationInfoF func(id string) (SlackChannel, error)
+}
+
+var (
+	SlackMockClient SlackClientMocks
+)
+
+// Mock functions default values
+func (sl *SlackClientMocks) SetDefaultFunctions() {
+	sl.LastMsgSent = ""
+	sl.LastThreadTs = ""
+	sl.LastUpdatedTs = ""
+	sl.LastBlocksSent = nil
+	sl.LastFileSent = SlackFile{}
+
+	sl.AuthTestF = func() (SlackAuth, error) {
+		return SlackAuth{UserId: "UBOT123", User: "test-bot", BotId: "BBOT123", TeamId: "T123"}, nil
+	}
+
+	sl.PostMessageF = func(m SlackMessage) (SlackMessage, error) {
+		log.Printf("Mock: Posting Message to Slack Channel %s\n%s\n", m.Channel, m.Text)
+		sl.LastMsgSent = m.Text
+		sl.LastThreadTs = m.ThreadTs
+		sl.LastBlocksSent = m.Blocks
+		m.Ts = "1600000000.000200"
+		return m, nil
+	}
+
+	sl.UpdateMessageF = func(m SlackMessage) error {
+		log.Printf("Mock: Updating Message %s in Slack Channel %s\n%s\n", m.Ts, m.Channel, m.Text)
+		sl.LastMsgSent = m.Text
+		sl.LastUpdatedTs = m.Ts
+		return nil
+	}
+
+	sl.UploadFileF = func(f SlackFile) error {
+		log.Printf("Mock: Uploading File %s to Slack Channel %s\n%s\n", f.Name, f.Channel, f.Comment)
+		sl.LastMsgSent = f.Comment
+		sl.LastThreadTs = f.ThreadTs
+		sl.LastFileSent = f
+		return nil
+	}
+
+	sl.GetUserInfoF = func(id string) (SlackUser, error) {
+		return SlackUser{Id: id, Name: "arandomperson", RealName: "ARandomPerson"}, nil
+	}
+
+	sl.GetConversationInfoF = func(id string) (SlackChannel, error) {
+		return SlackChannel{Id: id, Name: "aci-ops"}, nil
+	}
+}
+
+func (sl *SlackClientMocks) AuthTest() (SlackAuth, error) {
+	return sl.AuthTestF()
+}
+
+func (sl *SlackClientMocks) PostMessage(m SlackMessage) (SlackMessage, error) {
+	return sl.PostMessageF(m)
+}
+
+func (sl *SlackClientMocks) UpdateMessage(m SlackMessage) error {
+	return sl.UpdateMessageF(m)
+}
+
+func (sl *SlackClientMocks) UploadFile(f SlackFile) error {
+	return sl.UploadFileF(f)
+}
+
+func (sl *SlackClientMocks) GetUserInfo(id string) (SlackUser, error) {
+	return sl.GetUserInfoF(id)
+}
+
+func (sl *SlackClientMocks) GetConversationInfo(id string) (SlackChannel, error) {
+	return sl.GetConversationInfoF(id)
+}
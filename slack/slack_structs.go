@@ -0,0 +1,132 @@
+package slack
+
+// Generic reply of the Slack Web API
+type SlackResponse struct {
+	Ok    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// auth.test URI
+type SlackAuth struct {
+	UserId string `json:"user_id,omitempty"`
+	User   string `json:"user,omitempty"`
+	BotId  string `json:"bot_id,omitempty"`
+	TeamId string `json:"team_id,omitempty"`
+}
+
+// chat.postMessage and chat.update URIs
+type SlackMessage struct {
+	Channel  string       `json:"channel,omitempty"`
+	Text     string       `json:"text,omitempty"`
+	Ts       string       `json:"ts,omitempty"`
+	ThreadTs string       `json:"thread_ts,omitempty"`
+	Blocks   []SlackBlock `json:"blocks,omitempty"`
+}
+
+type SlackMessageReply struct {
+	Channel string `json:"channel,omitempty"`
+	Ts      string `json:"ts,omitempty"`
+}
+
+// Block Kit layout block
+// Supported types: header, section, divider and actions
+type SlackBlock struct {
+	Type     string         `json:"type"`
+	Text     *SlackText     `json:"text,omitempty"`
+	Fields   []SlackText    `json:"fields,omitempty"`
+	Elements []SlackElement `json:"elements,omitempty"`
+}
+
+// Block Kit text object. Type is either plain_text or mrkdwn
+type SlackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// Block Kit interactive element. Only buttons are used
+type SlackElement struct {
+	Type     string     `json:"type"`
+	Text     *SlackText `json:"text,omitempty"`
+	Value    string     `json:"value,omitempty"`
+	ActionId string     `json:"action_id,omitempty"`
+}
+
+// File uploaded to a channel
+type SlackFile struct {
+	Name     string
+	Content  []byte
+	Channel  string
+	ThreadTs string
+	Comment  string
+}
+
+type SlackUploadUrlReply struct {
+	UploadUrl string `json:"upload_url,omitempty"`
+	FileId    string `json:"file_id,omitempty"`
+}
+
+// users.info URI
+type SlackUserReply struct {
+	User SlackUser `json:"user"`
+}
+
+type SlackUser struct {
+	Id       string `json:"id,omitempty"`
+	Name     string `json:"name,omitempty"`
+	RealName string `json:"real_name,omitempty"`
+	Profile  struct {
+		DisplayName string `json:"display_name,omitempty"`
+		RealName    string `json:"real_name,omitempty"`
+	} `json:"profile"`
+}
+
+// conversations.info URI
+type SlackChannelReply struct {
+	Channel SlackChannel `json:"channel"`
+}
+
+type SlackChannel struct {
+	Id   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+	IsIm bool   `json:"is_im,omitempty"`
+}
+
+// Events API request
+type SlackEventCallback struct {
+	Type      string     `json:"type"`
+	Challenge string     `json:"challenge,omitempty"`
+	TeamId    string     `json:"team_id,omitempty"`
+	EventId   string     `json:"event_id,omitempty"`
+	Event     SlackEvent `json:"event"`
+}
+
+type SlackEvent struct {
+	Type        string `json:"type"`
+	Subtype     string `json:"subtype,omitempty"`
+	User        string `json:"user,omitempty"`
+	BotId       string `json:"bot_id,omitempty"`
+	Text        string `json:"text,omitempty"`
+	Channel     string `json:"channel,omitempty"`
+	ChannelType string `json:"channel_type,omitempty"`
+	Ts          string `json:"ts,omitempty"`
+	ThreadTs    string `json:"thread_ts,omitempty"`
+}
+
+// Interactivity request. Sent when a button is clicked
+type SlackInteraction struct {
+	Type string `json:"type"`
+	User struct {
+		Id string `json:"id"`
+	} `json:"user"`
+	Channel struct {
+		Id string `json:"id"`
+	} `json:"channel"`
+	Message struct {
+		Ts       string `json:"ts"`
+		ThreadTs string `json:"thread_ts,omitempty"`
+	} `json:"message"`
+	Actions []struct {
+		ActionId string `json:"action_id"`
+		Value    string `json:"value"`
+	} `json:"actions"`
+}
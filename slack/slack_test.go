@@ -0,0 +1,230 @@
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func notOk(tb testing.TB, err error) {
+	if err == nil {
+		_, file, line, _ := runtime.Caller(1)
+		fmt.Printf("\033[31m%s:%d: expected an error\033[39m\n\n", filepath.Base(file), line)
+		tb.FailNow()
+	}
+}
+
+func ok(tb testing.TB, err error) {
+	if err != nil {
+		_, file, line, _ := runtime.Caller(1)
+		fmt.Printf("\033[31m%s:%d: unexpected error: %s\033[39m\n\n", filepath.Base(file), line, err.Error())
+		tb.FailNow()
+	}
+}
+
+func equals(tb testing.TB, exp, act interface{}) {
+	if !reflect.DeepEqual(exp, act) {
+		_, file, line, _ := runtime.Caller(1)
+		fmt.Printf("\033[31m%s:%d:\n\n\texp: %#v\n\n\tgot: %#v\033[39m\n\n", filepath.Base(file), line, exp, act)
+		tb.FailNow()
+	}
+}
+
+func newTestClient(server *httptest.Server) SlackClient {
+	client := NewSlackClient("xoxb-FAKETOKEN")
+	client.httpClient = server.Client()
+	client.baseURL = server.URL
+	return client
+}
+
+// Test creation of the client
+func TestClient(t *testing.T) {
+	sl := NewSlackClient("xoxb-FAKETOKEN")
+
+	equals(t, "https://slack.com/api", sl.GetBaseUrl())
+}
+
+// Test posting and updating messages
+func TestMessages(t *testing.T) {
+	var posted SlackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		equals(t, "Bearer xoxb-FAKETOKEN", req.Header.Get("Authorization"))
+		body, _ := ioutil.ReadAll(req.Body)
+		json.Unmarshal(body, &posted)
+		switch req.URL.Path {
+		case "/chat.postMessage":
+			rw.Write([]byte(`{"ok": true, "channel": "C123", "ts": "1600000000.000100"}`))
+		case "/chat.update":
+			rw.Write([]byte(`{"ok": true, "channel": "C123", "ts": "1600000000.000100"}`))
+		default:
+			rw.Write([]byte(`{"ok": false, "error": "unknown_method"}`))
+		}
+	}))
+	defer server.Close()
+	client := newTestClient(server)
+
+	t.Run("Post Message", func(t *testing.T) {
+		blocks := []SlackBlock{{Type: "section", Text: &SlackText{Type: "mrkdwn", Text: "*Hello*"}}}
+		m, err := client.PostMessage(SlackMessage{Channel: "C123", Text: "Hello", ThreadTs: "1500000000.000100", Blocks: blocks})
+		ok(t, err)
+		equals(t, "1600000000.000100", m.Ts)
+		equals(t, "1500000000.000100", posted.ThreadTs)
+		equals(t, blocks, posted.Blocks)
+	})
+
+	t.Run("Update Message", func(t *testing.T) {
+		err := client.UpdateMessage(SlackMessage{Channel: "C123", Ts: "1600000000.000100", Text: "Done"})
+		ok(t, err)
+		equals(t, "1600000000.000100", posted.Ts)
+		equals(t, "Done", posted.Text)
+	})
+}
+
+// Slack replies with HTTP 200 and ok false to failed calls
+func TestErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/users.info":
+			rw.Write([]byte(`{"ok": false, "error": "user_not_found"}`))
+		case "/conversations.info":
+			rw.WriteHeader(http.StatusTooManyRequests)
+		default:
+			rw.Write([]byte(`{"ok": true,`))
+		}
+	}))
+	defer server.Close()
+	client := newTestClient(server)
+
+	t.Run("API Error", func(t *testing.T) {
+		_, err := client.GetUserInfo("U123")
+		notOk(t, err)
+	})
+
+	t.Run("HTTP Error", func(t *testing.T) {
+		_, err := client.GetConversationInfo("C123")
+		notOk(t, err)
+	})
+
+	t.Run("Bad Reply", func(t *testing.T) {
+		_, err := client.AuthTest()
+		notOk(t, err)
+	})
+}
+
+// Test the functions reading users, channels and the bot identity
+func TestInformation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/auth.test":
+			rw.Write([]byte(`{"ok": true, "user_id": "UBOT", "user": "aci-bot", "bot_id": "BBOT", "team_id": "T1"}`))
+		case "/users.info":
+			equals(t, "U123", req.URL.Query().Get("user"))
+			rw.Write([]byte(`{"ok": true, "user": {"id": "U123", "name": "jdoe", "real_name": "John Doe"}}`))
+		case "/conversations.info":
+			equals(t, "C123", req.URL.Query().Get("channel"))
+			rw.Write([]byte(`{"ok": true, "channel": {"id": "C123", "name": "aci-ops"}}`))
+		}
+	}))
+	defer server.Close()
+	client := newTestClient(server)
+
+	t.Run("Auth Test", func(t *testing.T) {
+		a, err := client.AuthTest()
+		ok(t, err)
+		equals(t, SlackAuth{UserId: "UBOT", User: "aci-bot", BotId: "BBOT", TeamId: "T1"}, a)
+	})
+
+	t.Run("User Info", func(t *testing.T) {
+		u, err := client.GetUserInfo("U123")
+		ok(t, err)
+		equals(t, "John Doe", u.RealName)
+	})
+
+	t.Run("Conversation Info", func(t *testing.T) {
+		c, err := client.GetConversationInfo("C123")
+		ok(t, err)
+		equals(t, "aci-ops", c.Name)
+	})
+}
+
+// Test the three steps file upload
+func TestUploadFile(t *testing.T) {
+	var uploaded []byte
+	var completed map[string]interface{}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/files.getUploadURLExternal":
+			equals(t, "faults.csv", req.URL.Query().Get("filename"))
+			equals(t, "7", req.URL.Query().Get("length"))
+			rw.Write([]byte(`{"ok": true, "upload_url": "` + server.URL + `/upload/F1", "file_id": "F1"}`))
+		case "/upload/F1":
+			uploaded, _ = ioutil.ReadAll(req.Body)
+			rw.Write([]byte("OK - 7"))
+		case "/files.completeUploadExternal":
+			body, _ := ioutil.ReadAll(req.Body)
+			json.Unmarshal(body, &completed)
+			rw.Write([]byte(`{"ok": true}`))
+		}
+	}))
+	defer server.Close()
+	client := newTestClient(server)
+
+	err := client.UploadFile(SlackFile{Name: "faults.csv", Content: []byte("a,b\n1,2"), Channel: "C123", ThreadTs: "1.2", Comment: "Faults"})
+	ok(t, err)
+	equals(t, "a,b\n1,2", string(uploaded))
+	equals(t, "C123", completed["channel_id"])
+	equals(t, "1.2", completed["thread_ts"])
+	equals(t, "Faults", completed["initial_comment"])
+}
+
+// Test the verification of signed requests
+func TestVerifySignature(t *testing.T) {
+	secret := "8f742231b10e8888abcd99yyyzzz85a5"
+	body := []byte(`{"type":"url_verification","challenge":"abc"}`)
+	now := time.Unix(1600000000, 0)
+	sign := func(ts string, b []byte) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte("v0:" + ts + ":"))
+		mac.Write(b)
+		return "v0=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	header := func(ts, sig string) http.Header {
+		h := http.Header{}
+		h.Set("X-Slack-Request-Timestamp", ts)
+		h.Set("X-Slack-Signature", sig)
+		return h
+	}
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	t.Run("Valid Signature", func(t *testing.T) {
+		ok(t, VerifySignature(secret, header(ts, sign(ts, body)), body, now))
+	})
+
+	t.Run("Tampered Body", func(t *testing.T) {
+		notOk(t, VerifySignature(secret, header(ts, sign(ts, body)), []byte(`{"type":"event_callback"}`), now))
+	})
+
+	t.Run("Wrong Secret", func(t *testing.T) {
+		notOk(t, VerifySignature("another-secret", header(ts, sign(ts, body)), body, now))
+	})
+
+	t.Run("Replayed Request", func(t *testing.T) {
+		notOk(t, VerifySignature(secret, header(ts, sign(ts, body)), body, now.Add(6*time.Minute)))
+	})
+
+	t.Run("Missing Headers", func(t *testing.T) {
+		notOk(t, VerifySignature(secret, http.Header{}, body, now))
+	})
+}
@@ -0,0 +1,337 @@
+package teams
+
+// Package teams uses httptest to execute unit tests
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	botFrameworkIssuer = "https://api.botframework.com"
+	clockSkew          = 5 * time.Minute // Tolerated difference between the clocks of the Bot Framework and the bot
+	keysTTL            = 24 * time.Hour  // The signing keys are fetched again afterwards
+	keysRefreshDelay   = 5 * time.Minute // Minimum delay between two fetches of the signing keys for unknown keys
+	tokenMargin        = 5 * time.Minute // The OAuth token is renewed this long before it expires
+)
+
+// Teams interface. Implemented by TeamsClient and TeamsClientMocks
+type TeamsInterface interface {
+	SendActivity(serviceUrl, conversationId string, a TeamsActivity) (string, error)
+	UpdateActivity(serviceUrl, conversationId, activityId string, a TeamsActivity) error
+	ValidateToken(authHeader string, a TeamsActivity) error
+}
+
+// Teams Client struct. Talks to the Bot Framework Connector
+type TeamsClient struct {
+	httpClient  *http.Client
+	appId       string
+	appPassword string
+	tokenURL    string
+	openIdURL   string
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+	keys        map[string]jsonWebKey
+	keysFetched time.Time
+}
+
+// Create a new Teams Client with the credentials of the Azure Bot registration
+func NewTeamsClient(appId, appPassword string) *TeamsClient {
+	return &TeamsClient{
+		appId:       appId,
+		appPassword: appPassword,
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		tokenURL:  "https://login.microsoftonline.com/botframework.com/oauth2/v2.0/token",
+		openIdURL: "https://login.botframework.com/v1/.well-known/openidconfiguration",
+	}
+}
+
+// Send an activity to a conversation and return its ID. The ID is required to update it afterwards
+// The activity is sent as a reply if ReplyToId is set
+func (tm *TeamsClient) SendActivity(serviceUrl, conversationId string, a TeamsActivity) (string, error) {
+	var result TeamsResourceResponse
+
+	u := fmt.Sprintf("%s/v3/conversations/%s/activities", strings.TrimSuffix(serviceUrl, "/"), url.PathEscape(conversationId))
+	if a.ReplyToId != "" {
+		u += "/" + url.PathEscape(a.ReplyToId)
+	}
+	err := tm.processMessage(http.MethodPost, u, a, &result)
+	if err != nil {
+		return "", err
+	}
+
+	return result.Id, nil
+}
+
+// Replace an activity previously sent by the bot
+func (tm *TeamsClient) UpdateActivity(serviceUrl, conversationId, activityId string, a TeamsActivity) error {
+
+	u := fmt.Sprintf("%s/v3/conversations/%s/activities/%s", strings.TrimSuffix(serviceUrl, "/"), url.PathEscape(conversationId), url.PathEscape(activityId))
+	err := tm.processMessage(http.MethodPut, u, a, nil)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Validate the JWT sent by the Bot Framework in the Authorization header of an activity
+// See https://learn.microsoft.com/azure/bot-service/rest-api/bot-framework-rest-connector-authentication
+func (tm *TeamsClient) ValidateToken(authHeader string, a TeamsActivity) error {
+
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return errors.New("missing bearer token")
+	}
+	parts := strings.Split(strings.TrimPrefix(authHeader, "Bearer "), ".")
+	if len(parts) != 3 {
+		return errors.New("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return err
+	}
+	if header.Alg != "RS256" {
+		return fmt.Errorf("unsupported token algorithm %s", header.Alg)
+	}
+	key, err := tm.signingKey(header.Kid)
+	if err != nil {
+		return err
+	}
+	if !endorsed(key, a.ChannelId) {
+		return fmt.Errorf("signing key %s is not endorsed for channel %s", header.Kid, a.ChannelId)
+	}
+	pub, err := rsaPublicKey(key)
+	if err != nil {
+		return err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, hash[:], sig); err != nil {
+		return errors.New("invalid token signature")
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return err
+	}
+	now := time.Now()
+	if claims.Iss != botFrameworkIssuer {
+		return fmt.Errorf("invalid token issuer %s", claims.Iss)
+	}
+	if !audienceContains(claims.Aud, tm.appId) {
+		return errors.New("token not issued for this bot")
+	}
+	if now.After(time.Unix(claims.Exp, 0).Add(clockSkew)) {
+		return errors.New("token expired")
+	}
+	if claims.Nbf != 0 && now.Before(time.Unix(claims.Nbf, 0).Add(-clockSkew)) {
+		return errors.New("token not valid yet")
+	}
+	// The service URL is where the replies are sent. It must not be forged
+	if claims.ServiceUrl != a.ServiceUrl {
+		return fmt.Errorf("token issued for service URL %s", claims.ServiceUrl)
+	}
+	return nil
+}
+
+// Get a signing key of the Bot Framework by ID
+// The keys are cached and fetched again once a day or when an unknown key is used
+// Unknown keys trigger at most one fetch every keysRefreshDelay, so that forged tokens can not flood the Bot Framework
+func (tm *TeamsClient) signingKey(kid string) (jsonWebKey, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	k, ok := tm.keys[kid]
+	fresh := time.Since(tm.keysFetched) < keysTTL
+	if ok && fresh {
+		return k, nil
+	}
+	if fresh && time.Since(tm.keysFetched) < keysRefreshDelay {
+		return k, fmt.Errorf("unknown signing key %s", kid)
+	}
+	var oid openIdConfiguration
+	if err := tm.getJson(tm.openIdURL, &oid); err != nil {
+		return jsonWebKey{}, err
+	}
+	var set jsonWebKeySet
+	if err := tm.getJson(oid.JwksUri, &set); err != nil {
+		return jsonWebKey{}, err
+	}
+	tm.keys = make(map[string]jsonWebKey)
+	for _, k := range set.Keys {
+		tm.keys[k.Kid] = k
+	}
+	tm.keysFetched = time.Now()
+
+	k, ok = tm.keys[kid]
+	if !ok {
+		return k, fmt.Errorf("unknown signing key %s", kid)
+	}
+	return k, nil
+}
+
+// Get an OAuth token to call the Bot Connector. The token is cached until it expires
+func (tm *TeamsClient) accessToken() (string, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if tm.token != "" && time.Now().Before(tm.tokenExpiry) {
+		return tm.token, nil
+	}
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {tm.appId},
+		"client_secret": {tm.appPassword},
+		"scope":         {botFrameworkIssuer + "/.default"},
+	}
+	resp, err := tm.httpClient.PostForm(tm.tokenURL, form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("could not get a Bot Framework token\n API message %s", body)
+	}
+	var t teamsToken
+	if err = json.Unmarshal(body, &t); err != nil {
+		return "", err
+	}
+	tm.token = t.AccessToken
+	tm.tokenExpiry = time.Now().Add(time.Duration(t.ExpiresIn)*time.Second - tokenMargin)
+	return tm.token, nil
+}
+
+// Create and execute an HTTP request to the Bot Connector
+func (tm *TeamsClient) processMessage(method, url string, payload interface{}, response interface{}) error {
+
+	tkn, err := tm.accessToken()
+	if err != nil {
+		return err
+	}
+	jp, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(method, url, bytes.NewBuffer(jp))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Authorization", "Bearer "+tkn)
+
+	resp, err := tm.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("error processing this request %s\n API message %s", req.URL, body)
+	}
+	if response != nil && len(body) > 0 {
+		err = json.Unmarshal(body, response)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Execute an unauthenticated GET request and decode its JSON reply
+func (tm *TeamsClient) getJson(url string, res interface{}) error {
+	resp, err := tm.httpClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error processing this request %s\n API message %s", url, body)
+	}
+	return json.Unmarshal(body, res)
+}
+
+// Decode a base64url JWT segment
+func decodeSegment(s string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// Build the RSA public key of a JSON Web Key
+func rsaPublicKey(k jsonWebKey) (*rsa.PublicKey, error) {
+	if k.Kty != "RSA" {
+		return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+	}
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+}
+
+// Keys without endorsements are valid for every channel
+func endorsed(k jsonWebKey, channel string) bool {
+	if len(k.Endorsements) == 0 {
+		return true
+	}
+	for _, e := range k.Endorsements {
+		if e == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// The audience claim is either a string or a list of strings
+func audienceContains(aud interface{}, id string) bool {
+	switch a := aud.(type) {
+	case string:
+		return a == id
+	case []interface{}:
+		for _, v := range a {
+			if v == id {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+//go:build !teams
+// +build !teams
+
+package teams
+
+import (
+	"errors"
+	"log"
+)
+
+var errInvalidMockToken = errors.New("invalid token")
+
+type TeamsClientMocks struct {
+	LastActivitySent TeamsActivity
+	LastServiceUrl   string
+	LastUpdatedId    string
+	SendActivityF    func(serviceUrl, conversationId string, a TeamsActivity) (string, error)
+	UpdateActivityF  func(serviceUrl, conversationId, activityId string, a TeamsActivity) error
+	ValidateTokenF   func(authHeader string, a TeamsActivity) error
+}
+
+var (
+	TeamsMockClient TeamsClientMocks
+)
+
+// Mock functions default values
+func (tm *TeamsClientMocks) SetDefaultFunctions() {
+	tm.LastActivitySent = TeamsActivity{}
+	tm.LastServiceUrl = ""
+	tm.LastUpdatedId = ""
+
+	tm.SendActivityF = func(serviceUrl, conversationId string, a TeamsActivity) (string, error) {
+		log.Printf("Mock: Sending Activity to Teams Conversation %s\n%s\n", conversationId, a.Text)
+		tm.LastActivitySent = a
+		tm.LastServiceUrl = serviceUrl
+		return "MockActivityId", nil
+	}
+
+	tm.UpdateActivityF = func(serviceUrl, conversationId, activityId string, a TeamsActivity) error {
+		log.Printf("Mock: Updating Activity %s in Teams Conversation %s\n%s\n", activityId, conversationId, a.Text)
+		tm.LastActivitySent = a
+		tm.LastUpdatedId = activityId
+		return nil
+	}
+
+	// Only the mock token is accepted
+	tm.ValidateTokenF = func(authHeader string, a TeamsActivity) error {
+		if authHeader != "Bearer MockToken" {
+			return errInvalidMockToken
+		}
+		return nil
+	}
+}
+
+func (tm *TeamsClientMocks) SendActivity(serviceUrl, conversationId string, a TeamsActivity) (string, error) {
+	return tm.SendActivityF(serviceUrl, conversationId, a)
+}
+
+func (tm *TeamsClientMocks) UpdateActivity(serviceUrl, conversationId, activityId string, a TeamsActivity) error {
+	return tm.UpdateActivityF(serviceUrl, conversationId, activityId, a)
+}
+
+func (tm *TeamsClientMocks) ValidateToken(authHeader string, a TeamsActivity) error {
+	return tm.ValidateTokenF(authHeader, a)
+}
@@ -0,0 +1,92 @@
+package teams
+
+// Bot Framework activity. Messages, card submissions and conversation updates are activities
+// See https://learn.microsoft.com/azure/bot-service/rest-api/bot-framework-rest-connector-api-reference
+type TeamsActivity struct {
+	Type         string                 `json:"type"`
+	Id           string                 `json:"id,omitempty"`
+	Timestamp    string                 `json:"timestamp,omitempty"`
+	ServiceUrl   string                 `json:"serviceUrl,omitempty"`
+	ChannelId    string                 `json:"channelId,omitempty"`
+	From         *TeamsAccount          `json:"from,omitempty"`
+	Conversation *TeamsConversation     `json:"conversation,omitempty"`
+	Recipient    *TeamsAccount          `json:"recipient,omitempty"`
+	Text         string                 `json:"text,omitempty"`
+	TextFormat   string                 `json:"textFormat,omitempty"`
+	Summary      string                 `json:"summary,omitempty"`
+	ReplyToId    string                 `json:"replyToId,omitempty"`
+	Value        map[string]interface{} `json:"value,omitempty"`
+	Attachments  []TeamsAttachment      `json:"attachments,omitempty"`
+	Entities     []TeamsEntity          `json:"entities,omitempty"`
+}
+
+type TeamsAccount struct {
+	Id          string `json:"id,omitempty"`
+	Name        string `json:"name,omitempty"`
+	AadObjectId string `json:"aadObjectId,omitempty"`
+}
+
+type TeamsConversation struct {
+	Id               string `json:"id,omitempty"`
+	Name             string `json:"name,omitempty"`
+	ConversationType string `json:"conversationType,omitempty"`
+	IsGroup          bool   `json:"isGroup,omitempty"`
+	TenantId         string `json:"tenantId,omitempty"`
+}
+
+type TeamsAttachment struct {
+	ContentType string      `json:"contentType"`
+	Content     interface{} `json:"content,omitempty"`
+	ContentUrl  string      `json:"contentUrl,omitempty"`
+	Name        string      `json:"name,omitempty"`
+}
+
+// Entity attached to an activity. Mentions of the bot are entities of type mention
+type TeamsEntity struct {
+	Type      string        `json:"type"`
+	Mentioned *TeamsAccount `json:"mentioned,omitempty"`
+	Text      string        `json:"text,omitempty"`
+}
+
+// Reply of the Bot Connector when an activity is sent
+type TeamsResourceResponse struct {
+	Id string `json:"id"`
+}
+
+// OAuth token used to call the Bot Connector
+type teamsToken struct {
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	AccessToken string `json:"access_token"`
+}
+
+// OpenID metadata of the Bot Framework. Points to the keys signing the incoming requests
+type openIdConfiguration struct {
+	Issuer  string `json:"issuer"`
+	JwksUri string `json:"jwks_uri"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+type jsonWebKey struct {
+	Kty          string   `json:"kty"`
+	Kid          string   `json:"kid"`
+	N            string   `json:"n"`
+	E            string   `json:"e"`
+	Endorsements []string `json:"endorsements"`
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Iss        string      `json:"iss"`
+	Aud        interface{} `json:"aud"`
+	Exp        int64       `json:"exp"`
+	Nbf        int64       `json:"nbf"`
+	ServiceUrl string      `json:"serviceurl"`
+}
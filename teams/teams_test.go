@@ -0,0 +1,196 @@
+package teams
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func notOk(tb testing.TB, err error) {
+	if err == nil {
+		_, file, line, _ := runtime.Caller(1)
+		fmt.Printf("\033[31m%s:%d: expected an error\033[39m\n\n", filepath.Base(file), line)
+		tb.FailNow()
+	}
+}
+
+func ok(tb testing.TB, err error) {
+	if err != nil {
+		_, file, line, _ := runtime.Caller(1)
+		fmt.Printf("\033[31m%s:%d: unexpected error: %s\033[39m\n\n", filepath.Base(file), line, err.Error())
+		tb.FailNow()
+	}
+}
+
+func equals(tb testing.TB, exp, act interface{}) {
+	if !reflect.DeepEqual(exp, act) {
+		_, file, line, _ := runtime.Caller(1)
+		fmt.Printf("\033[31m%s:%d:\n\n\texp: %#v\n\n\tgot: %#v\033[39m\n\n", filepath.Base(file), line, exp, act)
+		tb.FailNow()
+	}
+}
+
+// Test sending and updating activities through the Bot Connector
+func TestActivities(t *testing.T) {
+	tokens := 0
+	var sent TeamsActivity
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/token" {
+			tokens++
+			req.ParseForm()
+			equals(t, "client_credentials", req.Form.Get("grant_type"))
+			equals(t, "APPID", req.Form.Get("client_id"))
+			rw.Write([]byte(`{"token_type": "Bearer", "expires_in": 3600, "access_token": "BFTOKEN"}`))
+			return
+		}
+		equals(t, "Bearer BFTOKEN", req.Header.Get("Authorization"))
+		path = req.URL.EscapedPath()
+		body, _ := ioutil.ReadAll(req.Body)
+		json.Unmarshal(body, &sent)
+		rw.Write([]byte(`{"id": "1632474074231"}`))
+	}))
+	defer server.Close()
+	client := NewTeamsClient("APPID", "SECRET")
+	client.httpClient = server.Client()
+	client.tokenURL = server.URL + "/token"
+
+	t.Run("Send Activity", func(t *testing.T) {
+		id, err := client.SendActivity(server.URL+"/", "19:abc@thread.tacv2", TeamsActivity{Type: "message", Text: "Hello"})
+		ok(t, err)
+		equals(t, "1632474074231", id)
+		equals(t, "/v3/conversations/19:abc@thread.tacv2/activities", path)
+		equals(t, "Hello", sent.Text)
+	})
+
+	t.Run("Reply to Activity", func(t *testing.T) {
+		_, err := client.SendActivity(server.URL, "a:1/2", TeamsActivity{Type: "message", Text: "Hi", ReplyToId: "1632"})
+		ok(t, err)
+		equals(t, "/v3/conversations/a:1%2F2/activities/1632", path)
+	})
+
+	t.Run("Update Activity", func(t *testing.T) {
+		err := client.UpdateActivity(server.URL, "19:abc@thread.tacv2", "1632", TeamsActivity{Type: "message", Text: "Done"})
+		ok(t, err)
+		equals(t, "/v3/conversations/19:abc@thread.tacv2/activities/1632", path)
+		equals(t, "Done", sent.Text)
+	})
+
+	t.Run("Token is cached", func(t *testing.T) {
+		equals(t, 1, tokens)
+	})
+}
+
+// Test the validation of the JWT sent by the Bot Framework
+func TestValidateToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	ok(t, err)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	ok(t, err)
+
+	var server *httptest.Server
+	fetches := 0
+	server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/openid":
+			fetches++
+			rw.Write([]byte(`{"issuer": "https://api.botframework.com", "jwks_uri": "` + server.URL + `/keys"}`))
+		case "/keys":
+			json.NewEncoder(rw).Encode(jsonWebKeySet{Keys: []jsonWebKey{{
+				Kty:          "RSA",
+				Kid:          "KEY1",
+				N:            base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:            base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+				Endorsements: []string{"msteams"},
+			}}})
+		}
+	}))
+	defer server.Close()
+	client := NewTeamsClient("APPID", "SECRET")
+	client.httpClient = server.Client()
+	client.openIdURL = server.URL + "/openid"
+
+	sign := func(k *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+		h, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"})
+		c, _ := json.Marshal(claims)
+		unsigned := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+		hash := sha256.Sum256([]byte(unsigned))
+		sig, _ := rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, hash[:])
+		return "Bearer " + unsigned + "." + base64.RawURLEncoding.EncodeToString(sig)
+	}
+	claims := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":        "https://api.botframework.com",
+			"aud":        "APPID",
+			"exp":        time.Now().Add(time.Hour).Unix(),
+			"nbf":        time.Now().Add(-time.Minute).Unix(),
+			"serviceurl": "https://smba.trafficmanager.net/emea/",
+		}
+	}
+	activity := TeamsActivity{Type: "message", ChannelId: "msteams", ServiceUrl: "https://smba.trafficmanager.net/emea/"}
+
+	t.Run("Valid Token", func(t *testing.T) {
+		ok(t, client.ValidateToken(sign(key, "KEY1", claims()), activity))
+	})
+
+	t.Run("Missing Token", func(t *testing.T) {
+		notOk(t, client.ValidateToken("", activity))
+	})
+
+	t.Run("Signed by another key", func(t *testing.T) {
+		notOk(t, client.ValidateToken(sign(other, "KEY1", claims()), activity))
+	})
+
+	t.Run("Unknown key", func(t *testing.T) {
+		notOk(t, client.ValidateToken(sign(key, "KEY2", claims()), activity))
+	})
+
+	t.Run("Unknown keys do not refetch the keys", func(t *testing.T) {
+		fetches = 0
+		for i := 0; i < 3; i++ {
+			notOk(t, client.ValidateToken(sign(key, "KEY3", claims()), activity))
+		}
+		equals(t, 0, fetches)
+		client.keysFetched = time.Now().Add(-keysRefreshDelay)
+		notOk(t, client.ValidateToken(sign(key, "KEY3", claims()), activity))
+		equals(t, 1, fetches)
+		ok(t, client.ValidateToken(sign(key, "KEY1", claims()), activity))
+	})
+
+	t.Run("Wrong audience", func(t *testing.T) {
+		c := claims()
+		c["aud"] = "ANOTHERAPP"
+		notOk(t, client.ValidateToken(sign(key, "KEY1", c), activity))
+	})
+
+	t.Run("Expired Token", func(t *testing.T) {
+		c := claims()
+		c["exp"] = time.Now().Add(-time.Hour).Unix()
+		notOk(t, client.ValidateToken(sign(key, "KEY1", c), activity))
+	})
+
+	t.Run("Forged service URL", func(t *testing.T) {
+		a := activity
+		a.ServiceUrl = "https://attacker.example.com/"
+		notOk(t, client.ValidateToken(sign(key, "KEY1", claims()), a))
+	})
+
+	t.Run("Key not endorsed for the channel", func(t *testing.T) {
+		a := activity
+		a.ChannelId = "webchat"
+		notOk(t, client.ValidateToken(sign(key, "KEY1", claims()), a))
+	})
+}
@@ -0,0 +1,24 @@
+package webex
+
+import (
+	"aci-chatbot/metrics"
+	"net/url"
+	"strings"
+)
+
+// Metrics of the calls to the Webex API. See metrics.Handler()
+var (
+	apiErrors   = metrics.NewCounter("aci_chatbot_webex_api_errors_total", "Webex API calls failed, by resource", "resource")
+	rateLimited = metrics.NewCounter("aci_chatbot_webex_rate_limited_total", "Webex API calls rejected with 429 Too Many Requests, by resource", "resource")
+)
+
+// Resource of a request used as metric label, e.g. messages for /v1/messages/{id}
+func apiResource(u *url.URL) string {
+	w := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := range w {
+		if w[i] == "v1" && i+1 < len(w) {
+			return w[i+1]
+		}
+	}
+	return w[len(w)-1]
+}
@@ -2,45 +2,84 @@ package webex
 
 // Package webex uses httptest to execute unit tests
 import (
+	"aci-chatbot/proxy"
 	"bytes"
-	"crypto/tls"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
+	"strings"
 	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 // Webex interface. Implemented by WebexClient and WebexClientMocks
 type WebexInterface interface {
-	SendMessageToRoom(m string, roomId string) error
+	SendMessageToRoom(m string, roomId string, parentId ...string) error
+	SendFileToRoom(m string, f WebexFile, roomId string, parentId ...string) error
+	SendCardToRoom(m string, c AdaptiveCard, roomId string, parentId ...string) error
+	SendMessage(msg WebexMessage) (WebexMessage, error)
+	EditMessage(id, m, roomId string) error
 	GetBotDetails() (WebexPeople, error)
 	GetWebHooks() ([]WebexWebhook, error)
 	DeleteWebhook(id string) error
 	CreateWebhook(name, url, resource, event string) error
 	GetPersonInformation(id string) (WebexPeople, error)
 	GetMessageById(id string) (WebexMessage, error)
+	GetAttachmentActionById(id string) (WebexAttachmentAction, error)
 	GetRoomById(roomId string) (WebexRoom, error)
+	RegisterDevice(name string) (WebexDevice, error)
+	ListenEvents(ctx context.Context, d WebexDevice, events chan<- WebexEvent) error
 }
 
+// Type to define the Webex client configuration option
+type Option func(*WebexClient)
+
 // Webex Client struct
 type WebexClient struct {
 	httpClient *http.Client // Webex Client expect an HttpClient interface type
+	dialer     *websocket.Dialer
 	tkn        string
 	baseURL    string
+	deviceURL  string
+}
+
+// The proxy of the requests to Webex, used instead of the HTTP_PROXY, HTTPS_PROXY and NO_PROXY variables
+// The REST API and the WebSocket of the device mode are sent through the proxy. See proxy.New()
+func SetProxy(p proxy.Func) Option {
+	return func(wbx *WebexClient) {
+		if p == nil {
+			return
+		}
+		if t, ok := wbx.httpClient.Transport.(*http.Transport); ok {
+			t.Proxy = p
+		}
+		d := *wbx.dialer
+		d.Proxy = p
+		wbx.dialer = &d
+	}
 }
 
 // Create a new Webex Client
-func NewWebexClient(tkn string) WebexClient {
+func NewWebexClient(tkn string, options ...Option) WebexClient {
 	wbx := WebexClient{
 		tkn: tkn,
 		httpClient: &http.Client{
-			Timeout: 5 * time.Second,
+			Timeout:   5 * time.Second,
+			Transport: http.DefaultTransport.(*http.Transport).Clone(),
 		},
-		baseURL: "https://webexapis.com",
+		baseURL:   "https://webexapis.com",
+		deviceURL: "https://wdm-a.wbx2.com/wdm/api/v1/devices",
+		dialer:    websocket.DefaultDialer,
+	}
+	for _, opt := range options {
+		opt(&wbx)
 	}
-	http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
 	return wbx
 }
 
@@ -137,10 +176,75 @@ func (wbx *WebexClient) GetMessageById(id string) (WebexMessage, error) {
 	return result, nil
 }
 
+// Get the action submitted from a card by ID
+func (wbx *WebexClient) GetAttachmentActionById(id string) (WebexAttachmentAction, error) {
+	var result WebexAttachmentAction
+
+	err := wbx.processMessage(http.MethodGet, fmt.Sprintf("/v1/attachment/actions/%s", id), nil, &result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
 // Send a markdown message to a Webex Room
-func (wbx *WebexClient) SendMessageToRoom(m, roomId string) error {
+// The message is threaded under the parent message, if any
+func (wbx *WebexClient) SendMessageToRoom(m, roomId string, parentId ...string) error {
 
-	err := wbx.processMessage(http.MethodPost, "/v1/messages", WebexMessage{RoomId: roomId, Markdown: m}, nil)
+	_, err := wbx.SendMessage(WebexMessage{RoomId: roomId, ParentId: firstOrEmpty(parentId), Markdown: m})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Send an Adaptive Card to a Webex Room
+// The markdown message is displayed by clients which do not support cards
+func (wbx *WebexClient) SendCardToRoom(m string, c AdaptiveCard, roomId string, parentId ...string) error {
+
+	msg := WebexMessage{RoomId: roomId, ParentId: firstOrEmpty(parentId), Markdown: m, Attachments: []WebexAttachment{{ContentType: AdaptiveCardContentType, Content: c}}}
+	_, err := wbx.SendMessage(msg)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Send a markdown message with a file attachment to a Webex Room
+func (wbx *WebexClient) SendFileToRoom(m string, f WebexFile, roomId string, parentId ...string) error {
+
+	fields := map[string]string{"roomId": roomId, "markdown": m}
+	if p := firstOrEmpty(parentId); p != "" {
+		fields["parentId"] = p
+	}
+	req, err := wbx.makeMultipartCall(http.MethodPost, "/v1/messages", fields, f)
+	if err != nil {
+		return err
+	}
+	err = wbx.doCall(req, nil)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Send a message and return the message created by Webex. Its ID is required to edit it afterwards
+func (wbx *WebexClient) SendMessage(msg WebexMessage) (WebexMessage, error) {
+	var result WebexMessage
+
+	err := wbx.processMessage(http.MethodPost, "/v1/messages", msg, &result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// Replace the markdown text of an existing message
+func (wbx *WebexClient) EditMessage(id, m, roomId string) error {
+
+	err := wbx.processMessage(http.MethodPut, fmt.Sprintf("/v1/messages/%s", id), WebexMessage{RoomId: roomId, Markdown: m}, nil)
 	if err != nil {
 		return err
 	}
@@ -195,7 +299,11 @@ func (wbx *WebexClient) makeCall(m, url string, p interface{}) (*http.Request, e
 		return nil, err
 	}
 
-	req, err := http.NewRequest(m, wbx.baseURL+url, bytes.NewBuffer(jp))
+	// Relative URLs are resolved against the Webex API
+	if !strings.HasPrefix(url, "http") {
+		url = wbx.baseURL + url
+	}
+	req, err := http.NewRequest(m, url, bytes.NewBuffer(jp))
 	if err != nil {
 		return nil, err
 	}
@@ -207,11 +315,49 @@ func (wbx *WebexClient) makeCall(m, url string, p interface{}) (*http.Request, e
 	return req, nil
 }
 
+// Create a multipart/form-data HTTP request. Used to upload files
+func (wbx *WebexClient) makeMultipartCall(m, url string, fields map[string]string, f WebexFile) (*http.Request, error) {
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for k, v := range fields {
+		if err := writer.WriteField(k, v); err != nil {
+			return nil, err
+		}
+	}
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="files"; filename="%s"`, f.Name))
+	h.Set("Content-Type", f.ContentType)
+	part, err := writer.CreatePart(h)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = part.Write(f.Content); err != nil {
+		return nil, err
+	}
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(m, wbx.baseURL+url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	req.Header.Add("Authorization", "Bearer "+wbx.tkn)
+
+	return req, nil
+}
+
 // Execute a HTTP request
 func (wbx *WebexClient) doCall(req *http.Request, res interface{}) error {
 
 	resp, err := wbx.httpClient.Do(req)
 	if err != nil {
+		apiErrors.Inc(apiResource(req.URL))
 		return err
 	}
 	defer resp.Body.Close()
@@ -221,6 +367,10 @@ func (wbx *WebexClient) doCall(req *http.Request, res interface{}) error {
 	}
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		apiErrors.Inc(apiResource(req.URL))
+		if resp.StatusCode == http.StatusTooManyRequests {
+			rateLimited.Inc(apiResource(req.URL))
+		}
 		return fmt.Errorf("error processing this request %s\n API message %s", req.URL, body)
 
 	}
@@ -233,3 +383,11 @@ func (wbx *WebexClient) doCall(req *http.Request, res interface{}) error {
 
 	return nil
 }
+
+// Return the first optional argument, if any
+func firstOrEmpty(s []string) string {
+	if len(s) > 0 {
+		return s[0]
+	}
+	return ""
+}
@@ -0,0 +1,93 @@
+package webex
+
+// Adaptive Cards attached to Webex messages
+// See https://developer.webex.com/docs/api/guides/cards
+const (
+	AdaptiveCardContentType = "application/vnd.microsoft.card.adaptive"
+	adaptiveCardSchema      = "http://adaptivecards.io/schemas/adaptive-card.json"
+	adaptiveCardVersion     = "1.2"
+)
+
+type AdaptiveCard struct {
+	Type    string        `json:"type"`
+	Schema  string        `json:"$schema"`
+	Version string        `json:"version"`
+	Body    []CardElement `json:"body"`
+}
+
+// Generic card element. Only the fields used by the element type are set
+// Supported types: TextBlock, FactSet, Container, ColumnSet, Column and ActionSet
+type CardElement struct {
+	Type      string        `json:"type"`
+	Text      string        `json:"text,omitempty"`
+	Size      string        `json:"size,omitempty"`
+	Weight    string        `json:"weight,omitempty"`
+	Color     string        `json:"color,omitempty"`
+	FontType  string        `json:"fontType,omitempty"`
+	IsSubtle  bool          `json:"isSubtle,omitempty"`
+	Wrap      bool          `json:"wrap,omitempty"`
+	Separator bool          `json:"separator,omitempty"`
+	Spacing   string        `json:"spacing,omitempty"`
+	Width     string        `json:"width,omitempty"`
+	Facts     []CardFact    `json:"facts,omitempty"`
+	Items     []CardElement `json:"items,omitempty"`
+	Columns   []CardElement `json:"columns,omitempty"`
+	Actions   []CardAction  `json:"actions,omitempty"`
+}
+
+// Button of a card. The data is sent back in an attachmentActions webhook
+type CardAction struct {
+	Type  string            `json:"type"`
+	Title string            `json:"title"`
+	Data  map[string]string `json:"data,omitempty"`
+}
+
+type CardFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+type WebexAttachment struct {
+	ContentType string      `json:"contentType"`
+	Content     interface{} `json:"content"`
+}
+
+// Create a new Adaptive Card
+func NewAdaptiveCard(body ...CardElement) AdaptiveCard {
+	return AdaptiveCard{
+		Type:    "AdaptiveCard",
+		Schema:  adaptiveCardSchema,
+		Version: adaptiveCardVersion,
+		Body:    body,
+	}
+}
+
+// Card element with a wrapped text
+func CardText(t string) CardElement {
+	return CardElement{Type: "TextBlock", Text: t, Wrap: true}
+}
+
+// Card element with a bold text
+func CardTitle(t string) CardElement {
+	return CardElement{Type: "TextBlock", Text: t, Size: "Medium", Weight: "Bolder", Wrap: true}
+}
+
+// Card element with a list of title/value pairs
+func CardFacts(f ...CardFact) CardElement {
+	return CardElement{Type: "FactSet", Facts: f}
+}
+
+// Card element with a list of buttons
+func CardActions(a ...CardAction) CardElement {
+	return CardElement{Type: "ActionSet", Actions: a}
+}
+
+// Button submitting its data back to the bot
+func CardSubmit(title string, data map[string]string) CardAction {
+	return CardAction{Type: "Action.Submit", Title: title, Data: data}
+}
+
+// Card element grouping other elements, separated from the previous one
+func CardContainer(items ...CardElement) CardElement {
+	return CardElement{Type: "Container", Items: items, Separator: true}
+}
@@ -0,0 +1,102 @@
+package webex
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// Register the bot as a Webex device. An existing device with the same name is reused
+// The device provides the URL of the Webex WebSocket, used when the bot is not reachable from the internet
+func (wbx *WebexClient) RegisterDevice(name string) (WebexDevice, error) {
+
+	var devices WebexDevicesReply
+	if err := wbx.processMessage(http.MethodGet, wbx.deviceURL, nil, &devices); err != nil {
+		return WebexDevice{}, err
+	}
+	for _, d := range devices.Devices {
+		if d.Name == name {
+			return d, nil
+		}
+	}
+
+	var result WebexDevice
+	device := WebexDevice{
+		DeviceName:     name,
+		DeviceType:     "DESKTOP",
+		LocalizedModel: "go",
+		Model:          "go",
+		Name:           name,
+		SystemName:     name,
+		SystemVersion:  "1.0",
+	}
+	if err := wbx.processMessage(http.MethodPost, wbx.deviceURL, device, &result); err != nil {
+		return WebexDevice{}, err
+	}
+	return result, nil
+}
+
+// Listen to the messages and card actions sent to the bot over the Webex WebSocket
+// Blocks until the context is cancelled or the connection is closed
+func (wbx *WebexClient) ListenEvents(ctx context.Context, d WebexDevice, events chan<- WebexEvent) error {
+
+	ws, _, err := wbx.dialer.DialContext(ctx, d.WebSocketUrl, nil)
+	if err != nil {
+		log.Printf("Error setting up the Webex websocket connection. Error %s", err)
+		return err
+	}
+	defer ws.Close()
+	// Unblock the reader once the context is cancelled
+	go func() {
+		<-ctx.Done()
+		ws.Close()
+	}()
+
+	auth := map[string]interface{}{
+		"id":   d.Name,
+		"type": "authorization",
+		"data": map[string]string{"token": "Bearer " + wbx.tkn},
+	}
+	if err = ws.WriteJSON(auth); err != nil {
+		return err
+	}
+
+	for {
+		var msg mercuryMessage
+		if err = ws.ReadJSON(&msg); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Printf("Error reading the Webex websocket data. Error %s", err)
+			return err
+		}
+		// Every message must be acknowledged, otherwise Webex sends it again
+		if err = ws.WriteJSON(map[string]string{"type": "ack", "messageId": msg.Id}); err != nil {
+			return err
+		}
+		if msg.Data.EventType != "conversation.activity" {
+			continue
+		}
+		var ev WebexEvent
+		switch msg.Data.Activity.Verb {
+		case "post", "share":
+			ev = WebexEvent{Resource: "messages", Id: activityToApiId("MESSAGE", msg.Data.Activity.Id)}
+		case "cardAction":
+			ev = WebexEvent{Resource: "attachmentActions", Id: activityToApiId("ATTACHMENT_ACTION", msg.Data.Activity.Id)}
+		default:
+			continue
+		}
+		select {
+		case events <- ev:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Convert the UUID of a WebSocket activity into the ID of the resource in the Webex API
+func activityToApiId(resource, uuid string) string {
+	return base64.RawStdEncoding.EncodeToString([]byte(fmt.Sprintf("ciscospark://us/%s/%s", resource, uuid)))
+}
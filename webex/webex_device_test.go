@@ -0,0 +1,118 @@
+package webex
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Test the device registration on the devices URI
+func TestRegisterDevice(t *testing.T) {
+
+	var created WebexDevice
+	devices := `{"devices": [{"name": "Other-Bot", "url": "https://wdm/1", "webSocketUrl": "wss://mercury/1"}]}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+			rw.Write([]byte(devices))
+		case http.MethodPost:
+			body, _ := ioutil.ReadAll(req.Body)
+			json.Unmarshal(body, &created)
+			rw.Write([]byte(`{"name": "Test-Bot", "url": "https://wdm/2", "webSocketUrl": "wss://mercury/2"}`))
+		}
+	}))
+	defer server.Close()
+	client := NewWebexClient("FAKETOKEN")
+	client.httpClient = server.Client()
+	client.deviceURL = server.URL + "/wdm/api/v1/devices"
+
+	t.Run("Reuse existing device", func(t *testing.T) {
+		d, err := client.RegisterDevice("Other-Bot")
+		ok(t, err)
+		equals(t, "wss://mercury/1", d.WebSocketUrl)
+		equals(t, "", created.Name)
+	})
+	t.Run("Create new device", func(t *testing.T) {
+		d, err := client.RegisterDevice("Test-Bot")
+		ok(t, err)
+		equals(t, "wss://mercury/2", d.WebSocketUrl)
+		equals(t, "Test-Bot", created.Name)
+		equals(t, "DESKTOP", created.DeviceType)
+	})
+}
+
+// Test the events received over the Webex WebSocket
+func TestListenEvents(t *testing.T) {
+
+	acks := make(chan string, 10)
+	var auth map[string]interface{}
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		ws, err := upgrader.Upgrade(rw, req, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		ws.ReadJSON(&auth)
+		for _, m := range []string{
+			`{"id": "1", "data": {"eventType": "conversation.activity", "activity": {"id": "aaaa-1111", "verb": "post"}}}`,
+			`{"id": "2", "data": {"eventType": "status.start_typing"}}`,
+			`{"id": "3", "data": {"eventType": "conversation.activity", "activity": {"id": "bbbb-2222", "verb": "acknowledge"}}}`,
+			`{"id": "4", "data": {"eventType": "conversation.activity", "activity": {"id": "cccc-3333", "verb": "cardAction"}}}`,
+		} {
+			ws.WriteMessage(websocket.TextMessage, []byte(m))
+			var ack map[string]string
+			ws.ReadJSON(&ack)
+			acks <- ack["messageId"]
+		}
+		// Keep the connection open until the client leaves
+		ws.ReadMessage()
+	}))
+	defer server.Close()
+	client := NewWebexClient("FAKETOKEN")
+	device := WebexDevice{Name: "Test-Bot", WebSocketUrl: strings.Replace(server.URL, "http", "ws", 1)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan WebexEvent)
+	errs := make(chan error)
+	go func() {
+		errs <- client.ListenEvents(ctx, device, events)
+	}()
+
+	t.Run("Message event", func(t *testing.T) {
+		ev := <-events
+		equals(t, "messages", ev.Resource)
+		equals(t, activityToApiId("MESSAGE", "aaaa-1111"), ev.Id)
+		equals(t, "Bearer FAKETOKEN", auth["data"].(map[string]interface{})["token"])
+	})
+	t.Run("Card action event", func(t *testing.T) {
+		ev := <-events
+		equals(t, "attachmentActions", ev.Resource)
+		equals(t, activityToApiId("ATTACHMENT_ACTION", "cccc-3333"), ev.Id)
+	})
+	t.Run("Every message is acknowledged", func(t *testing.T) {
+		for _, id := range []string{"1", "2", "3", "4"} {
+			select {
+			case ack := <-acks:
+				equals(t, id, ack)
+			case <-time.After(time.Second):
+				t.Fatal("message not acknowledged")
+			}
+		}
+	})
+	t.Run("Stop listening", func(t *testing.T) {
+		cancel()
+		equals(t, context.Canceled, <-errs)
+	})
+	t.Run("Webex API ID", func(t *testing.T) {
+		equals(t, "Y2lzY29zcGFyazovL3VzL01FU1NBR0UvOTJkYjNiZTAtNDNiZC0xMWU2LThhZTktZGQ1YjNkZmM1NjVk", activityToApiId("MESSAGE", "92db3be0-43bd-11e6-8ae9-dd5b3dfc565d"))
+	})
+}
@@ -4,19 +4,31 @@
 package webex
 
 import (
+	"context"
 	"log"
 )
 
 type WebexClientMocks struct {
-	LastMsgSent           string
-	CreateWebhookF        func(name, url, resource, event string) error
-	GetBotDetailsF        func() (WebexPeople, error)
-	GetWebHooksF          func() ([]WebexWebhook, error)
-	DeleteWebhookF        func(id string) error
-	SendMessageToRoomF    func(m string, roomId string) error
-	GetPersonInformationF func(id string) (WebexPeople, error)
-	GetMessageByIdF       func(id string) (WebexMessage, error)
-	GetRoomByIdF          func(roomId string) (WebexRoom, error)
+	LastMsgSent              string
+	LastParentId             string
+	LastEditedId             string
+	LastFileSent             WebexFile
+	LastCardSent             *AdaptiveCard
+	CreateWebhookF           func(name, url, resource, event string) error
+	GetBotDetailsF           func() (WebexPeople, error)
+	GetWebHooksF             func() ([]WebexWebhook, error)
+	DeleteWebhookF           func(id string) error
+	SendMessageToRoomF       func(m string, roomId string, parentId ...string) error
+	SendFileToRoomF          func(m string, f WebexFile, roomId string, parentId ...string) error
+	SendCardToRoomF          func(m string, c AdaptiveCard, roomId string, parentId ...string) error
+	SendMessageF             func(msg WebexMessage) (WebexMessage, error)
+	EditMessageF             func(id, m, roomId string) error
+	GetPersonInformationF    func(id string) (WebexPeople, error)
+	GetMessageByIdF          func(id string) (WebexMessage, error)
+	GetAttachmentActionByIdF func(id string) (WebexAttachmentAction, error)
+	GetRoomByIdF             func(roomId string) (WebexRoom, error)
+	RegisterDeviceF          func(name string) (WebexDevice, error)
+	ListenEventsF            func(ctx context.Context, d WebexDevice, events chan<- WebexEvent) error
 }
 
 var (
@@ -26,6 +38,10 @@ var (
 // Mock functions default values
 func (wbx *WebexClientMocks) SetDefaultFunctions() {
 	wbx.LastMsgSent = ""
+	wbx.LastParentId = ""
+	wbx.LastEditedId = ""
+	wbx.LastFileSent = WebexFile{}
+	wbx.LastCardSent = nil
 	wbx.GetBotDetailsF = func() (WebexPeople, error) {
 		return WebexPeople{
 			Id:          "ABC123",
@@ -55,9 +71,41 @@ func (wbx *WebexClientMocks) SetDefaultFunctions() {
 		return nil
 	}
 
-	wbx.SendMessageToRoomF = func(m, roomId string) error {
+	wbx.SendMessageToRoomF = func(m, roomId string, parentId ...string) error {
 		log.Printf("Mock: Sending Message to Webex Room %s\n%s\n", roomId, m)
 		wbx.LastMsgSent = m
+		wbx.LastParentId = firstOrEmpty(parentId)
+		return nil
+	}
+
+	wbx.SendFileToRoomF = func(m string, f WebexFile, roomId string, parentId ...string) error {
+		log.Printf("Mock: Sending File %s to Webex Room %s\n%s\n", f.Name, roomId, m)
+		wbx.LastMsgSent = m
+		wbx.LastFileSent = f
+		wbx.LastParentId = firstOrEmpty(parentId)
+		return nil
+	}
+
+	wbx.SendCardToRoomF = func(m string, c AdaptiveCard, roomId string, parentId ...string) error {
+		log.Printf("Mock: Sending Card to Webex Room %s\n%s\n", roomId, m)
+		wbx.LastMsgSent = m
+		wbx.LastCardSent = &c
+		wbx.LastParentId = firstOrEmpty(parentId)
+		return nil
+	}
+
+	wbx.SendMessageF = func(msg WebexMessage) (WebexMessage, error) {
+		log.Printf("Mock: Sending Message to Webex Room %s\n%s\n", msg.RoomId, msg.Markdown)
+		wbx.LastMsgSent = msg.Markdown
+		wbx.LastParentId = msg.ParentId
+		msg.Id = "MockMessageId"
+		return msg, nil
+	}
+
+	wbx.EditMessageF = func(id, m, roomId string) error {
+		log.Printf("Mock: Editing Message %s in Webex Room %s\n%s\n", id, roomId, m)
+		wbx.LastMsgSent = m
+		wbx.LastEditedId = id
 		return nil
 	}
 
@@ -68,14 +116,45 @@ func (wbx *WebexClientMocks) SetDefaultFunctions() {
 	wbx.GetMessageByIdF = func(id string) (WebexMessage, error) {
 		return WebexMessage{Text: "This is a mocked webex test", PersonId: "ARandomId"}, nil
 	}
+
+	wbx.GetAttachmentActionByIdF = func(id string) (WebexAttachmentAction, error) {
+		return WebexAttachmentAction{Id: id, Type: "submit", PersonId: "ARandomId", Inputs: map[string]interface{}{"command": "/help"}}, nil
+	}
+
+	wbx.RegisterDeviceF = func(name string) (WebexDevice, error) {
+		log.Println("Mock: Registering Device")
+		return WebexDevice{Name: name, Url: "https://wdm.mock/devices/1", WebSocketUrl: "wss://mercury.mock/v1/events"}, nil
+	}
+
+	wbx.ListenEventsF = func(ctx context.Context, d WebexDevice, events chan<- WebexEvent) error {
+		log.Println("Mock: Listening to Events")
+		<-ctx.Done()
+		return ctx.Err()
+	}
 }
 
 func (wbx *WebexClientMocks) GetBotDetails() (WebexPeople, error) {
 	return wbx.GetBotDetailsF()
 }
 
-func (wbx *WebexClientMocks) SendMessageToRoom(m string, roomId string) error {
-	return wbx.SendMessageToRoomF(m, roomId)
+func (wbx *WebexClientMocks) SendMessageToRoom(m string, roomId string, parentId ...string) error {
+	return wbx.SendMessageToRoomF(m, roomId, parentId...)
+}
+
+func (wbx *WebexClientMocks) SendFileToRoom(m string, f WebexFile, roomId string, parentId ...string) error {
+	return wbx.SendFileToRoomF(m, f, roomId, parentId...)
+}
+
+func (wbx *WebexClientMocks) SendCardToRoom(m string, c AdaptiveCard, roomId string, parentId ...string) error {
+	return wbx.SendCardToRoomF(m, c, roomId, parentId...)
+}
+
+func (wbx *WebexClientMocks) SendMessage(msg WebexMessage) (WebexMessage, error) {
+	return wbx.SendMessageF(msg)
+}
+
+func (wbx *WebexClientMocks) EditMessage(id, m, roomId string) error {
+	return wbx.EditMessageF(id, m, roomId)
 }
 
 func (wbx *WebexClientMocks) DeleteWebhook(id string) error {
@@ -98,6 +177,18 @@ func (wbx *WebexClientMocks) GetMessageById(id string) (WebexMessage, error) {
 	return wbx.GetMessageByIdF(id)
 }
 
+func (wbx *WebexClientMocks) GetAttachmentActionById(id string) (WebexAttachmentAction, error) {
+	return wbx.GetAttachmentActionByIdF(id)
+}
+
 func (wbx *WebexClientMocks) GetRoomById(roomId string) (WebexRoom, error) {
 	return wbx.GetRoomByIdF(roomId)
 }
+
+func (wbx *WebexClientMocks) RegisterDevice(name string) (WebexDevice, error) {
+	return wbx.RegisterDeviceF(name)
+}
+
+func (wbx *WebexClientMocks) ListenEvents(ctx context.Context, d WebexDevice, events chan<- WebexEvent) error {
+	return wbx.ListenEventsF(ctx, d, events)
+}
@@ -37,14 +37,26 @@ type WebexMessagesReply struct {
 }
 
 type WebexMessage struct {
-	Id          string `json:"id,omitempty"`
-	RoomId      string `json:"roomId,omitempty"`
-	RoomType    string `json:"roomType,omitempty"`
-	Text        string `json:"text,omitempty"`
-	PersonId    string `json:"personId,omitempty"`
-	PersonEmail string `json:"personEmail,omitempty"`
-	Created     string `json:"created,omitempty"`
-	Markdown    string `json:"markdown,omitempty"`
+	Id              string            `json:"id,omitempty"`
+	RoomId          string            `json:"roomId,omitempty"`
+	RoomType        string            `json:"roomType,omitempty"`
+	ParentId        string            `json:"parentId,omitempty"`
+	Text            string            `json:"text,omitempty"`
+	PersonId        string            `json:"personId,omitempty"`
+	PersonEmail     string            `json:"personEmail,omitempty"`
+	Created         string            `json:"created,omitempty"`
+	Markdown        string            `json:"markdown,omitempty"`
+	Html            string            `json:"html,omitempty"`
+	MentionedPeople []string          `json:"mentionedPeople,omitempty"`
+	MentionedGroups []string          `json:"mentionedGroups,omitempty"`
+	Attachments     []WebexAttachment `json:"attachments,omitempty"`
+}
+
+// File attached to a message. Webex supports a single file per message
+type WebexFile struct {
+	Name        string
+	ContentType string
+	Content     []byte
 }
 
 // Webhook URI
@@ -75,5 +87,53 @@ type WebexWebhookData struct {
 	PersonId        string   `json:"personId,omitempty"`
 	PersonEmail     string   `json:"personEmail,omitempty"`
 	MentionedPeople []string `json:"mentionedPeople"`
+	MentionedGroups []string `json:"mentionedGroups,omitempty"`
 	Created         string   `json:"created,omitempty"`
 }
+
+// Attachment Actions URI
+type WebexAttachmentAction struct {
+	Id        string                 `json:"id,omitempty"`
+	Type      string                 `json:"type,omitempty"`
+	MessageId string                 `json:"messageId,omitempty"`
+	Inputs    map[string]interface{} `json:"inputs,omitempty"`
+	PersonId  string                 `json:"personId,omitempty"`
+	RoomId    string                 `json:"roomId,omitempty"`
+	Created   string                 `json:"created,omitempty"`
+}
+
+// Devices URI. A device is required to receive events over the Webex WebSocket
+type WebexDevicesReply struct {
+	Devices []WebexDevice `json:"devices"`
+}
+
+type WebexDevice struct {
+	Url            string `json:"url,omitempty"`
+	WebSocketUrl   string `json:"webSocketUrl,omitempty"`
+	DeviceName     string `json:"deviceName,omitempty"`
+	DeviceType     string `json:"deviceType,omitempty"`
+	LocalizedModel string `json:"localizedModel,omitempty"`
+	Model          string `json:"model,omitempty"`
+	Name           string `json:"name,omitempty"`
+	SystemName     string `json:"systemName,omitempty"`
+	SystemVersion  string `json:"systemVersion,omitempty"`
+}
+
+// Event received over the Webex WebSocket
+// Resource is either messages or attachmentActions, like the webhooks. Id is the ID of the resource in the Webex API
+type WebexEvent struct {
+	Resource string
+	Id       string
+}
+
+// Message pushed by the Webex WebSocket (Mercury)
+type mercuryMessage struct {
+	Id   string `json:"id"`
+	Data struct {
+		EventType string `json:"eventType"`
+		Activity  struct {
+			Id   string `json:"id"`
+			Verb string `json:"verb"`
+		} `json:"activity"`
+	} `json:"data"`
+}
@@ -1,14 +1,22 @@
 package webex
 
 import (
+	"aci-chatbot/metrics"
+	"aci-chatbot/proxy"
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"path/filepath"
 	"reflect"
 	"runtime"
 	"strings"
 	"testing"
+
+	"github.com/gorilla/websocket"
 )
 
 func notOk(tb testing.TB, err error) {
@@ -161,6 +169,161 @@ func TestMessageUriOk(t *testing.T) {
 
 }
 
+// Test threaded replies and message edition on the /message URI
+func TestMessageThreadAndEdit(t *testing.T) {
+
+	var msg WebexMessage
+	var method, uri string
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		msg = WebexMessage{}
+		json.Unmarshal(body, &msg)
+		method, uri = req.Method, req.URL.String()
+		rw.Write([]byte(`{"id": "A1B2C3", "roomId": "ABCD", "parentId": "P1"}`))
+	}))
+	defer server.Close()
+	client := NewWebexClient("FAKETOKEN")
+	client.httpClient = server.Client()
+	client.baseURL = server.URL
+
+	t.Run("Threaded reply", func(t *testing.T) {
+		err := client.SendMessageToRoom("Reply", "ABCD", "P1")
+		ok(t, err)
+		equals(t, http.MethodPost, method)
+		equals(t, "P1", msg.ParentId)
+		equals(t, "Reply", msg.Markdown)
+	})
+	t.Run("Not threaded message", func(t *testing.T) {
+		err := client.SendMessageToRoom("Reply", "ABCD")
+		ok(t, err)
+		equals(t, "", msg.ParentId)
+	})
+	t.Run("Send message and get its ID", func(t *testing.T) {
+		res, err := client.SendMessage(WebexMessage{RoomId: "ABCD", ParentId: "P1", Markdown: "Querying APIC…"})
+		ok(t, err)
+		equals(t, "A1B2C3", res.Id)
+		equals(t, "Querying APIC…", msg.Markdown)
+	})
+	t.Run("Edit message", func(t *testing.T) {
+		err := client.EditMessage("A1B2C3", "Result", "ABCD")
+		ok(t, err)
+		equals(t, http.MethodPut, method)
+		equals(t, "/v1/messages/A1B2C3", uri)
+		equals(t, "ABCD", msg.RoomId)
+		equals(t, "Result", msg.Markdown)
+	})
+}
+
+// Test the Adaptive Card attachments to the /message URI
+func TestMessageCard(t *testing.T) {
+
+	var msg WebexMessage
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		json.Unmarshal(body, &msg)
+		rw.Write([]byte(`{"id": "A1B2C3"}`))
+	}))
+	defer server.Close()
+	client := NewWebexClient("FAKETOKEN")
+	client.httpClient = server.Client()
+	client.baseURL = server.URL
+
+	t.Run("Send Card", func(t *testing.T) {
+		err := client.SendCardToRoom("Fallback", NewAdaptiveCard(CardTitle("Title"), CardFacts(CardFact{Title: "Health", Value: "95"})), "ABCD")
+		ok(t, err)
+		equals(t, "ABCD", msg.RoomId)
+		equals(t, "Fallback", msg.Markdown)
+		equals(t, 1, len(msg.Attachments))
+		equals(t, AdaptiveCardContentType, msg.Attachments[0].ContentType)
+		card := msg.Attachments[0].Content.(map[string]interface{})
+		equals(t, "AdaptiveCard", card["type"])
+		equals(t, 2, len(card["body"].([]interface{})))
+	})
+}
+
+// Test the file upload to the /message URI
+func TestMessageFileUpload(t *testing.T) {
+
+	var fields map[string]string
+	var fileName, fileType, fileContent string
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		fields = make(map[string]string)
+		if err := req.ParseMultipartForm(1 << 20); err != nil {
+			rw.WriteHeader(400)
+			return
+		}
+		for k, v := range req.MultipartForm.Value {
+			fields[k] = v[0]
+		}
+		f, h, err := req.FormFile("files")
+		if err != nil {
+			rw.WriteHeader(400)
+			return
+		}
+		defer f.Close()
+		content, _ := ioutil.ReadAll(f)
+		fileName, fileType, fileContent = h.Filename, h.Header.Get("Content-Type"), string(content)
+		rw.Write([]byte(`{"id": "A1B2C3"}`))
+	}))
+	defer server.Close()
+	client := NewWebexClient("FAKETOKEN")
+	client.httpClient = server.Client()
+	client.baseURL = server.URL
+
+	t.Run("Send CSV File", func(t *testing.T) {
+		err := client.SendFileToRoom("Export", WebexFile{Name: "faults.csv", ContentType: "text/csv", Content: []byte("code,dn\nF1451,topology")}, "ABCD")
+		ok(t, err)
+		equals(t, map[string]string{"roomId": "ABCD", "markdown": "Export"}, fields)
+		equals(t, "faults.csv", fileName)
+		equals(t, "text/csv", fileType)
+		equals(t, "code,dn\nF1451,topology", fileContent)
+	})
+}
+
+// Test the functions talking to the /attachment/actions URI
+func TestAttachmentActionsUri(t *testing.T) {
+
+	actionTest := `{
+		"id": "ACT1",
+		"type": "submit",
+		"messageId": "MSG1",
+		"inputs": {
+			"command": "/faults ack uni/tn-a/fault-F1"
+		},
+		"personId": "CCDD",
+		"roomId": "AABB"
+	}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.String() {
+		case "/v1/attachment/actions/ACT1":
+			rw.Write([]byte(actionTest))
+		default:
+			rw.WriteHeader(404)
+		}
+	}))
+	defer server.Close()
+	client := NewWebexClient("FAKETOKEN")
+	client.httpClient = server.Client()
+	client.baseURL = server.URL
+
+	t.Run("Get Attachment Action by ID", func(t *testing.T) {
+		action, err := client.GetAttachmentActionById("ACT1")
+		ok(t, err)
+		equals(t, "submit", action.Type)
+		equals(t, "CCDD", action.PersonId)
+		equals(t, "AABB", action.RoomId)
+		equals(t, "/faults ack uni/tn-a/fault-F1", action.Inputs["command"])
+	})
+	t.Run("Get unknown Attachment Action", func(t *testing.T) {
+		_, err := client.GetAttachmentActionById("ACT2")
+		notOk(t, err)
+	})
+}
+
 // Test the functions talking to the /Webhook URI
 func TestWebhookUriOk(t *testing.T) {
 
@@ -326,6 +489,18 @@ func TestNotOkUri(t *testing.T) {
 		equals(t, strings.Contains(err.Error(), "error processing this request"), true)
 	})
 
+	t.Run("Edit Message Error", func(t *testing.T) {
+		err := client.EditMessage("A1B2C3", "A Text", "AAA")
+		notOk(t, err)
+		equals(t, strings.Contains(err.Error(), "error processing this request"), true)
+	})
+
+	t.Run("Send File Error", func(t *testing.T) {
+		err := client.SendFileToRoom("A Text", WebexFile{Name: "a.json", ContentType: "application/json", Content: []byte("{}")}, "AAA")
+		notOk(t, err)
+		equals(t, strings.Contains(err.Error(), "error processing this request"), true)
+	})
+
 	t.Run("Get Message by Id Error", func(t *testing.T) {
 		_, err := client.GetMessageById("AAA")
 		notOk(t, err)
@@ -380,3 +555,70 @@ func TestNotOkUri(t *testing.T) {
 		equals(t, strings.Contains(err.Error(), "error processing this request"), true)
 	})
 }
+
+func TestProxy(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if user, psw, _ := parseProxyAuth(req.Header.Get("Proxy-Authorization")); user != "user" || psw != "password" {
+			rw.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		requests = append(requests, req.URL.String())
+		rw.Write([]byte(`{"id": "AAAA", "displayName": "Test-Bot"}`))
+	}))
+	defer server.Close()
+	p, err := proxy.New(fmt.Sprintf("http://user:password@%s", server.Listener.Addr()), "")
+	ok(t, err)
+	client := NewWebexClient("FAKETOKEN", SetProxy(p))
+	client.baseURL = "http://webexapis.example.com"
+
+	t.Run("Requests sent through the proxy", func(t *testing.T) {
+		b, err := client.GetBotDetails()
+		ok(t, err)
+		equals(t, "Test-Bot", b.DisplayName)
+		equals(t, []string{"http://webexapis.example.com/v1/people/me"}, requests)
+	})
+	t.Run("WebSocket sent through the proxy", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "https://mercury.example.com/", nil)
+		u, err := client.dialer.Proxy(req)
+		ok(t, err)
+		equals(t, server.Listener.Addr().String(), u.Host)
+		// The default dialer shared by the other clients is not modified
+		equals(t, true, client.dialer != websocket.DefaultDialer)
+	})
+}
+
+// Credentials of a Proxy-Authorization header
+func parseProxyAuth(h string) (string, string, bool) {
+	req := http.Request{Header: http.Header{"Authorization": []string{h}}}
+	return req.BasicAuth()
+}
+
+func TestMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	client := NewWebexClient("FAKETOKEN")
+	client.httpClient = server.Client()
+	client.baseURL = server.URL
+
+	t.Run("Rate limited requests", func(t *testing.T) {
+		notOk(t, client.SendMessageToRoom("Hello", "AAAA"))
+		var b bytes.Buffer
+		metrics.WriteTo(&b)
+		equals(t, true, strings.Contains(b.String(), "\naci_chatbot_webex_rate_limited_total{resource=\"messages\"} "))
+		equals(t, true, strings.Contains(b.String(), "\naci_chatbot_webex_api_errors_total{resource=\"messages\"} "))
+	})
+	t.Run("Resources", func(t *testing.T) {
+		for u, r := range map[string]string{
+			"https://webexapis.com/v1/messages/AAAA":       "messages",
+			"https://webexapis.com/v1/people/me":           "people",
+			"https://wdm-a.wbx2.com/wdm/api/v1/devices":    "devices",
+			"https://webexapis.com/v1/attachment/actions/": "attachment",
+		} {
+			parsed, _ := url.Parse(u)
+			equals(t, r, apiResource(parsed))
+		}
+	})
+}